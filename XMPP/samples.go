@@ -0,0 +1,36 @@
+// Package xmpp_samples contains XMPP notifications captured from Finesse server.
+//
+// Samples are used as templates for notifications sent by mock Finesse server in package finessetest.
+package xmpp_samples
+
+import (
+	"embed"
+	"fmt"
+	"strings"
+)
+
+const (
+	Login              = "login.xml"               // Login success login, agent is in NOT_READY state
+	Logout             = "logout.xml"              // Logout success logout
+	Ready              = "ready.xml"               // Ready success ready
+	NotReady           = "not-ready.xml"           // NotReady success not-ready with reason code
+	ErrorInvalidState  = "error-invalid-state.xml" // ErrorInvalidState state change not possible in actual state
+	ErrorInvalidDevice = "error-invalid-line.xml"  // ErrorInvalidDevice login with unknown line (device)
+)
+
+//go:embed *.xml
+var files embed.FS
+
+// Message get XMPP message from sample without leading comment
+func Message(name string) (string, error) {
+	data, err := files.ReadFile(name)
+	if err != nil {
+		return "", err
+	}
+	msg := string(data)
+	i := strings.Index(msg, "<message")
+	if i < 0 {
+		return "", fmt.Errorf("sample [%s] does not contain XMPP message", name)
+	}
+	return strings.TrimSpace(msg[i:]), nil
+}
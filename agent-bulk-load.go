@@ -0,0 +1,127 @@
+package finesse_api
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// bulkCsvColumns known CSV columns, name and password are required
+var bulkCsvColumns = []string{"name", "password", "line", "server", "notreadyreason", "logoutreason"}
+
+// bulkAgentsYaml YAML document with agent list
+type bulkAgentsYaml struct {
+	Agents []BulkAgent `yaml:"agents"`
+}
+
+// LoadBulkAgentsCsv read agents from CSV with header
+//
+// Columns (case-insensitive, any order): name, password, line, server, notReadyReason, logoutReason.
+// Empty lines and lines starting with # are ignored.
+func LoadBulkAgentsCsv(r io.Reader) ([]BulkAgent, error) {
+	c := csv.NewReader(r)
+	c.Comment = '#'
+	c.TrimLeadingSpace = true
+	header, err := c.Read()
+	if err != nil {
+		return nil, fmt.Errorf("problem read CSV header: %w", err)
+	}
+	index := make(map[string]int)
+	for i, h := range header {
+		h = strings.ToLower(strings.TrimSpace(h))
+		known := false
+		for _, k := range bulkCsvColumns {
+			known = known || k == h
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown CSV column [%s]", header[i])
+		}
+		index[h] = i
+	}
+	if _, ok := index["name"]; !ok {
+		return nil, errors.New("CSV column [name] is required")
+	}
+	if _, ok := index["password"]; !ok {
+		return nil, errors.New("CSV column [password] is required")
+	}
+	column := func(record []string, name string) string {
+		if i, ok := index[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	var agents []BulkAgent
+	for {
+		record, err := c.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("problem read CSV: %w", err)
+		}
+		agents = append(agents, BulkAgent{
+			Name:           column(record, "name"),
+			Password:       column(record, "password"),
+			Line:           column(record, "line"),
+			Server:         column(record, "server"),
+			NotReadyReason: column(record, "notreadyreason"),
+			LogoutReason:   column(record, "logoutreason"),
+		})
+	}
+	return agents, checkBulkAgents(agents)
+}
+
+// LoadBulkAgentsYaml read agents from YAML document with list "agents"
+//
+//	agents:
+//	  - name: agent1
+//	    password: secret
+//	    line: "1001"
+//	    server: finesse-b.example.com
+//	    notReadyReason: Lunch
+//	    logoutReason: End of shift
+func LoadBulkAgentsYaml(r io.Reader) ([]BulkAgent, error) {
+	var doc bulkAgentsYaml
+	d := yaml.NewDecoder(r)
+	d.KnownFields(true)
+	if err := d.Decode(&doc); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("problem read YAML: %w", err)
+	}
+	return doc.Agents, checkBulkAgents(doc.Agents)
+}
+
+// LoadBulkAgentsFile read agents from CSV (.csv) or YAML (.yaml, .yml) file
+func LoadBulkAgentsFile(file string) ([]BulkAgent, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".csv":
+		return LoadBulkAgentsCsv(f)
+	case ".yaml", ".yml":
+		return LoadBulkAgentsYaml(f)
+	}
+	return nil, fmt.Errorf("unknown format of agents file [%s]", file)
+}
+
+// checkBulkAgents verify required values and unique names
+func checkBulkAgents(agents []BulkAgent) error {
+	names := make(map[string]int)
+	for i, a := range agents {
+		if len(a.Name) == 0 || len(a.Password) == 0 {
+			return fmt.Errorf("agent [%d] has no name or password", i+1)
+		}
+		if j, ok := names[a.Name]; ok {
+			return fmt.Errorf("agent [%s] defined twice (agents %d and %d)", a.Name, j+1, i+1)
+		}
+		names[a.Name] = i
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package finesse_api_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	api "github.com/pokornyIt/finesse-api"
+)
+
+func TestLoadBulkAgentsCsv(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		data   string
+		exp    []api.BulkAgent
+		errMsg string // part of expected error message, empty for success
+	}{
+		{
+			name: "all columns",
+			data: "Name,Password,Line,Server,NotReadyReason,LogoutReason\n" +
+				"agent1,secret,1001,finesse-b:8445,Lunch,End of shift\n",
+			exp: []api.BulkAgent{{Name: "agent1", Password: "secret", Line: "1001", Server: "finesse-b:8445", NotReadyReason: "Lunch", LogoutReason: "End of shift"}},
+		},
+		{
+			name: "any order with comment and empty line",
+			data: "password, name, line\n# comment\n\nsecret, agent1, 1001\nsecret2, agent2,\n",
+			exp: []api.BulkAgent{
+				{Name: "agent1", Password: "secret", Line: "1001"},
+				{Name: "agent2", Password: "secret2"},
+			},
+		},
+		{name: "header only", data: "name,password\n"},
+		{name: "empty file", data: "", errMsg: "problem read CSV header"},
+		{name: "unknown column", data: "name,password,extension\nagent1,secret,1001\n", errMsg: "unknown CSV column [extension]"},
+		{name: "missing name column", data: "password,line\nsecret,1001\n", errMsg: "CSV column [name] is required"},
+		{name: "missing password column", data: "name,line\nagent1,1001\n", errMsg: "CSV column [password] is required"},
+		{name: "missing password value", data: "name,password\nagent1,secret\nagent2,\n", errMsg: "agent [2] has no name or password"},
+		{name: "wrong number of fields", data: "name,password\nagent1,secret,1001\n", errMsg: "problem read CSV"},
+		{name: "duplicate name", data: "name,password\nagent1,secret\nagent1,secret\n", errMsg: "agent [agent1] defined twice (agents 1 and 2)"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			agents, err := api.LoadBulkAgentsCsv(strings.NewReader(tc.data))
+			checkLoaded(t, agents, err, tc.exp, tc.errMsg)
+		})
+	}
+}
+
+func TestLoadBulkAgentsYaml(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		data   string
+		exp    []api.BulkAgent
+		errMsg string
+	}{
+		{
+			name: "all fields",
+			data: "agents:\n  - name: agent1\n    password: secret\n    line: \"1001\"\n    server: finesse-b\n" +
+				"    notReadyReason: Lunch\n    logoutReason: End of shift\n  - name: agent2\n    password: secret2\n",
+			exp: []api.BulkAgent{
+				{Name: "agent1", Password: "secret", Line: "1001", Server: "finesse-b", NotReadyReason: "Lunch", LogoutReason: "End of shift"},
+				{Name: "agent2", Password: "secret2"},
+			},
+		},
+		{name: "empty file", data: ""},
+		{name: "unknown field", data: "agents:\n  - name: agent1\n    password: secret\n    extension: \"1001\"\n", errMsg: "problem read YAML"},
+		{name: "missing password", data: "agents:\n  - name: agent1\n", errMsg: "agent [1] has no name or password"},
+		{name: "duplicate name", data: "agents:\n  - {name: agent1, password: a}\n  - {name: agent1, password: b}\n", errMsg: "defined twice"},
+		{name: "invalid document", data: "agents: agent1\n", errMsg: "problem read YAML"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			agents, err := api.LoadBulkAgentsYaml(strings.NewReader(tc.data))
+			checkLoaded(t, agents, err, tc.exp, tc.errMsg)
+		})
+	}
+}
+
+func TestLoadBulkAgentsFile(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"agents.csv":  "name,password\nagent1,secret\n",
+		"agents.YML":  "agents:\n  - {name: agent1, password: secret}\n",
+		"agents.json": `{"agents": []}`,
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o600); err != nil {
+			t.Fatalf("write %s: %s", name, err)
+		}
+	}
+	exp := []api.BulkAgent{{Name: "agent1", Password: "secret"}}
+	for _, name := range []string{"agents.csv", "agents.YML"} {
+		agents, err := api.LoadBulkAgentsFile(filepath.Join(dir, name))
+		checkLoaded(t, agents, err, exp, "")
+	}
+	_, err := api.LoadBulkAgentsFile(filepath.Join(dir, "agents.json"))
+	checkLoaded(t, nil, err, nil, "unknown format of agents file")
+	_, err = api.LoadBulkAgentsFile(filepath.Join(dir, "missing.csv"))
+	if !os.IsNotExist(err) {
+		t.Errorf("missing file: error is [%v]", err)
+	}
+}
+
+// checkLoaded loaded agents or error message
+func checkLoaded(t *testing.T, agents []api.BulkAgent, err error, exp []api.BulkAgent, errMsg string) {
+	t.Helper()
+	if len(errMsg) > 0 {
+		if err == nil || !strings.Contains(err.Error(), errMsg) {
+			t.Errorf("error is [%v], expected [%s]", err, errMsg)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if !reflect.DeepEqual(agents, exp) {
+		t.Errorf("agents are %+v, expected %+v", agents, exp)
+	}
+}
@@ -3,183 +3,484 @@ package finesse_api
 import (
 	"context"
 	"fmt"
-	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+	"net"
+	"strconv"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // massive parallel processing sets agents into the same state
 
+const (
+	DefaultGroupWorkers = 0 // DefaultGroupWorkers number of agents processed in parallel by AgentGroup operations, 0 is all agents at once
+)
+
 type AgentGroup struct {
 	Agents     []*Agent
+	members    map[*Agent]BulkAgent // members definition of agents (reason codes)
+	servers    map[string]*Server   // servers overridden per agent by name
+	workers    int                  // workers maximal number of agents processed in parallel, 0 is unlimited
+	rampUp     float64              // rampUp maximal number of agents started per second, 0 is unlimited
+	logger     atomic.Value         // logger group logger, inherited from server of first added agent
 	ctx        context.Context
 	cancelFunc context.CancelFunc
-	wg         sync.WaitGroup
 	mutex      sync.Mutex
 }
 
+// BulkAgent definition of agent for AgentGroup.AddBulkAgents
 type BulkAgent struct {
-	Name     string
-	Password string
-	Line     string
+	Name           string `yaml:"name"`           // Name agent login name
+	Password       string `yaml:"password"`       // Password agent password
+	Line           string `yaml:"line"`           // Line agent extension
+	Server         string `yaml:"server"`         // Server override of group server as "host" or "host:port", other settings are the same
+	NotReadyReason string `yaml:"notReadyReason"` // NotReadyReason reason code label, code or ID used for NotReady
+	LogoutReason   string `yaml:"logoutReason"`   // LogoutReason reason code label, code or ID used for Logout
 }
 
 func NewAgentGroup() *AgentGroup {
 	ctx, cancelFunc := context.WithCancel(context.Background())
 	return &AgentGroup{
 		Agents:     nil,
+		members:    make(map[*Agent]BulkAgent),
+		servers:    make(map[string]*Server),
+		workers:    DefaultGroupWorkers,
 		ctx:        ctx,
 		cancelFunc: cancelFunc,
-		wg:         sync.WaitGroup{},
 	}
 }
 
+// SetWorkers set maximal number of agents processed in parallel by group operations, 0 is one worker per agent
+func (group *AgentGroup) SetWorkers(workers int) {
+	group.mutex.Lock()
+	group.workers = workers
+	group.mutex.Unlock()
+}
+
+// SetRampUp set maximal number of agents started per second by group operations (e.g. login storm), 0 disable ramp-up
+func (group *AgentGroup) SetRampUp(agentsPerSecond float64) {
+	group.mutex.Lock()
+	group.rampUp = agentsPerSecond
+	group.mutex.Unlock()
+}
+
+// dispatch run job for items 0..n-1 with worker limit and ramp-up, items not started before context is done are passed to skip
+func (group *AgentGroup) dispatch(ctx context.Context, n int, job func(i int), skip func(i int)) {
+	group.mutex.Lock()
+	workers, rampUp := group.workers, group.rampUp
+	group.mutex.Unlock()
+	if workers <= 0 || workers > n {
+		workers = n
+	}
+	var interval time.Duration
+	if rampUp > 0 {
+		interval = time.Duration(float64(time.Second) / rampUp)
+	}
+	slots := make(chan struct{}, workers)
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		if interval > 0 && i > 0 {
+			t := time.NewTimer(time.Until(start.Add(time.Duration(i) * interval)))
+			select {
+			case <-ctx.Done():
+				t.Stop()
+			case <-t.C:
+			}
+		}
+		acquired := false
+		select {
+		case slots <- struct{}{}:
+			acquired = true
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			if acquired {
+				<-slots
+			}
+			skip(i)
+			continue
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			job(i)
+		}(i)
+	}
+	wg.Wait()
+}
+
 func (group *AgentGroup) AddAgentToGroup(name string, pwd string, line string, server *Server) error {
-	agent := NewAgentNotify(group.ctx, name, pwd, line, server)
-	err := agent.getId()
+	return group.AddAgentToGroupCtx(context.Background(), name, pwd, line, server)
+}
+
+// AddAgentToGroupCtx create agent and add it to group, context cancel request for agent ID
+//
+// Agent lives with group, its XMPP notification is stopped by CancelFunction. Agent with name of group member is rejected.
+func (group *AgentGroup) AddAgentToGroupCtx(ctx context.Context, name string, pwd string, line string, server *Server) error {
+	group.inheritLogger(server)
+	if err := group.checkMembers([]BulkAgent{{Name: name}}); err != nil {
+		return err
+	}
+	member := BulkAgent{Name: name, Password: pwd, Line: line}
+	agent, err := group.newAgent(ctx, member, server)
 	if err != nil {
-		log.WithFields(log.Fields{logProc: "AddAgentToGroup", logAgent: name}).Errorf("can't get actual agent state")
 		return err
 	}
+	return group.addMember(agent, member)
+}
+
+// newAgent create agent bound to group context and start its XMPP notification
+func (group *AgentGroup) newAgent(ctx context.Context, member BulkAgent, server *Server) (*Agent, error) {
+	agent := NewAgentNotify(group.ctx, member.Name, member.Password, member.Line, server)
+	err := agent.getId(ctx)
+	if err != nil {
+		group.withFields(Fields{logProc: "AddAgentToGroup", logAgent: member.Name}).Errorf("can't get actual agent state")
+		return nil, err
+	}
 	if err = agent.StartXmpp(); err != nil {
-		log.WithFields(log.Fields{logProc: "AddAgentToGroup", logId: agent.LoginId, logServer: server.name}).
+		group.withFields(Fields{logProc: "AddAgentToGroup", logId: agent.LoginId, logServer: server.name}).
 			Errorf("problem start XMPP for agent [%s] on server [%s]", agent.LoginName, server.name)
-		return err
+		return nil, err
 	}
-	log.WithFields(log.Fields{logProc: "AddAgentToGroup", logId: agent.LoginId, logServer: server.name}).
+	group.withFields(Fields{logProc: "AddAgentToGroup", logId: agent.LoginId, logServer: server.name}).
 		Tracef("start XMPP subroutine for agent [%s] on server [%s]", agent.LoginName, server.name)
+	return agent, nil
+}
+
+// addMember add created agent with its definition to group, agent added meanwhile with the same name is stopped
+func (group *AgentGroup) addMember(agent *Agent, member BulkAgent) error {
+	group.mutex.Lock()
+	if group.hasMember(agent.LoginName) {
+		group.mutex.Unlock()
+		agent.StopXmpp()
+		return fmt.Errorf("agent [%s] is already member of group", agent.LoginName)
+	}
 	group.Agents = append(group.Agents, agent)
+	group.members[agent] = member
+	group.mutex.Unlock()
 	return nil
 }
 
-func (group *AgentGroup) AddBulkAgents(agents []BulkAgent, server *Server) []OperationError {
-	log.WithFields(log.Fields{logProc: "AddBulkAgents"}).Tracef("start procees add bulk agents with it's status")
-	err := make(chan OperationError, len(agents))
-	for _, agent := range agents {
-		group.wg.Add(1)
-		go func(a BulkAgent, server *Server, wg *sync.WaitGroup, c chan OperationError) {
-			defer wg.Done()
+// checkMembers verify agents are not members of group
+func (group *AgentGroup) checkMembers(agents []BulkAgent) error {
+	group.mutex.Lock()
+	defer group.mutex.Unlock()
+	for _, a := range agents {
+		if group.hasMember(a.Name) {
+			return fmt.Errorf("agent [%s] is already member of group", a.Name)
+		}
+	}
+	return nil
+}
 
-			ag, e := server.CreateAgent(group.ctx, a.Name, a.Password, a.Line)
-			if e != nil {
-				c <- OperationError{
-					Type:  TypeErrorNoStatus,
-					Error: e,
-				}
-				return
-			}
-			e = ag.StartXmpp()
-			if e != nil {
-				c <- OperationError{
-					Type:  TypeErrorNoStatus,
-					Error: e,
-				}
-				return
-			}
-			group.mutex.Lock()
-			group.Agents = append(group.Agents, ag)
-			group.mutex.Unlock()
-			c <- OperationError{
-				Type:  TypeErrorNoError,
-				Error: nil,
+// hasMember group has agent with login name, must be called with locked mutex
+func (group *AgentGroup) hasMember(name string) bool {
+	for _, a := range group.Agents {
+		if a.LoginName == name {
+			return true
+		}
+	}
+	return false
+}
+
+// AddBulkAgents create agents in parallel and add them to group, result is keyed by agent name
+//
+// Agents must have password and unique name not used by group member, otherwise no agent is created and all results
+// have the error.
+// Agent with Server uses own server derived from group server, otherwise group server is used.
+func (group *AgentGroup) AddBulkAgents(agents []BulkAgent, server *Server) GroupResult {
+	return group.AddBulkAgentsCtx(context.Background(), agents, server)
+}
+
+// AddBulkAgentsCtx create agents in parallel and add them to group, after context cancel or CancelFunction
+// are not created remaining agents
+func (group *AgentGroup) AddBulkAgentsCtx(ctx context.Context, agents []BulkAgent, server *Server) GroupResult {
+	group.inheritLogger(server)
+	group.withFields(Fields{logProc: "AddBulkAgents"}).Tracef("start procees add bulk agents with it's status")
+	err := checkBulkAgents(agents)
+	if err == nil {
+		err = group.checkMembers(agents)
+	}
+	if err != nil {
+		group.withFields(Fields{logProc: "AddBulkAgents"}).Errorf("invalid bulk agents %s", err)
+		ret := make(GroupResult, len(agents))
+		for _, a := range agents {
+			ret[a.Name] = AgentResult{
+				LoginName: a.Name,
+				Operation: GroupOperationAdd,
+				Started:   time.Now(),
+				Error:     OperationError{Type: TypeErrorRequest, Error: err},
 			}
-		}(agent, server, &group.wg, err)
+		}
+		return ret
 	}
-	log.WithFields(log.Fields{logProc: "AddBulkAgents"}).Trace("wait for finish all in group")
-	group.wg.Wait()
-	var ret []OperationError
-	log.WithFields(log.Fields{logProc: "AddBulkAgents"}).Trace("wait for finish all in group")
-	for len(err) > 0 {
-		ret = append(ret, <-err)
+	ctx, span := startSpan(ctx, "group "+GroupOperationAdd, trace.SpanKindInternal, attrOperation.String(GroupOperationAdd), attrAgents.Int(len(agents)))
+	defer span.End()
+	ctx, cancel := group.operationContext(ctx)
+	defer cancel()
+	res := make(chan AgentResult, len(agents))
+	group.dispatch(ctx, len(agents), func(i int) {
+		a := agents[i]
+		r := AgentResult{LoginName: a.Name, Operation: GroupOperationAdd, Started: time.Now()}
+		ctx, agentSpan := startSpan(ctx, "group agent "+GroupOperationAdd, trace.SpanKindInternal, attrOperation.String(GroupOperationAdd), attrAgent.String(a.Name))
+		defer func() {
+			endSpan(agentSpan, r.Error)
+			r.Duration = time.Since(r.Started)
+			res <- r
+		}()
+
+		s, e := group.server(server, a.Server)
+		if e != nil {
+			r.Error = OperationError{Type: TypeErrorRequest, Error: e}
+			return
+		}
+		ag, e := group.newAgent(ctx, a, s)
+		if e != nil {
+			r.Error = OperationError{Type: TypeErrorNoStatus, Error: e}
+			return
+		}
+		if e = group.addMember(ag, a); e != nil {
+			r.Error = OperationError{Type: TypeErrorRequest, Error: e}
+			return
+		}
+		r.State = agentState(ag)
+	}, func(i int) {
+		res <- AgentResult{
+			LoginName: agents[i].Name,
+			Operation: GroupOperationAdd,
+			Started:   time.Now(),
+			Error: OperationError{
+				Type:  TypeErrorCanceled,
+				Error: fmt.Errorf("agent [%s] not created: %w", agents[i].Name, ctx.Err()),
+			},
+		}
+	})
+	ret := make(GroupResult, len(agents))
+	for len(res) > 0 {
+		r := <-res
+		ret[r.LoginName] = r
 	}
+	span.SetAttributes(attrFailed.Int(len(ret.Failed())))
 	if len(ret) != len(agents) {
-		log.WithFields(log.Fields{logProc: "AddBulkAgents"}).
-			Errorf("Agents in AgentGroup [%d] different from number of responses [%d]", len(agents), len(err))
+		group.withFields(Fields{logProc: "AddBulkAgents"}).
+			Errorf("Agents in AgentGroup [%d] different from number of responses [%d]", len(agents), len(ret))
 	} else {
-		log.WithFields(log.Fields{logProc: "AddBulkAgents"}).
+		group.withFields(Fields{logProc: "AddBulkAgents"}).
 			Trace("operation processed for all Agent in group")
 	}
 	return ret
 }
 
-func (group *AgentGroup) Login() []OperationError {
-	return group.doRequest(AgentStateLogin, false)
+// operationContext context of operation canceled also by group CancelFunction
+func (group *AgentGroup) operationContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	if group.ctx.Err() != nil {
+		cancel()
+		return ctx, cancel
+	}
+	go func() {
+		select {
+		case <-group.ctx.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// server group server or server with overridden host (and port), servers are shared by agents with the same override
+func (group *AgentGroup) server(server *Server, override string) (*Server, error) {
+	if len(override) == 0 {
+		return server, nil
+	}
+	group.mutex.Lock()
+	defer group.mutex.Unlock()
+	if s, ok := group.servers[override]; ok {
+		return s, nil
+	}
+	host, port := override, server.port
+	if h, p, err := net.SplitHostPort(override); err == nil {
+		if port, err = strconv.Atoi(p); err != nil {
+			return nil, fmt.Errorf("invalid port in server [%s]", override)
+		}
+		host = h
+	}
+	s := server.derive(host, port)
+	group.servers[override] = s
+	return s, nil
+}
+
+func (group *AgentGroup) Login() GroupResult {
+	return group.LoginCtx(context.Background())
 }
 
-func (group *AgentGroup) Logout(forceLogout ...bool) []OperationError {
+// LoginCtx login all agents, after context cancel or CancelFunction are not started requests for remaining agents
+func (group *AgentGroup) LoginCtx(ctx context.Context) GroupResult {
+	return group.doRequest(ctx, AgentStateLogin, false)
+}
+
+func (group *AgentGroup) Logout(forceLogout ...bool) GroupResult {
+	return group.LogoutCtx(context.Background(), forceLogout...)
+}
+
+// LogoutCtx logout all agents, after context cancel or CancelFunction are not started requests for remaining agents
+func (group *AgentGroup) LogoutCtx(ctx context.Context, forceLogout ...bool) GroupResult {
 	force := false
 	if len(forceLogout) > 0 {
 		force = forceLogout[0]
 	}
-	return group.doRequest(AgentStateLogout, force)
+	return group.doRequest(ctx, AgentStateLogout, force)
+}
+
+func (group *AgentGroup) Ready(forceLogout ...bool) GroupResult {
+	return group.ReadyCtx(context.Background(), forceLogout...)
 }
 
-func (group *AgentGroup) Ready(forceLogout ...bool) []OperationError {
+// ReadyCtx set all agents ready, after context cancel or CancelFunction are not started requests for remaining agents
+func (group *AgentGroup) ReadyCtx(ctx context.Context, forceReady ...bool) GroupResult {
 	force := false
-	if len(forceLogout) > 0 {
-		force = forceLogout[0]
+	if len(forceReady) > 0 {
+		force = forceReady[0]
 	}
-	return group.doRequest(AgentStateReady, force)
+	return group.doRequest(ctx, AgentStateReady, force)
+}
+
+func (group *AgentGroup) NotReady() GroupResult {
+	return group.NotReadyCtx(context.Background())
 }
 
-func (group *AgentGroup) NotReady() []OperationError {
-	return group.doRequest(AgentStateNotReady, false)
+// NotReadyCtx set all agents not-ready, after context cancel or CancelFunction are not started requests for remaining agents
+func (group *AgentGroup) NotReadyCtx(ctx context.Context) GroupResult {
+	return group.doRequest(ctx, AgentStateNotReady, false)
 }
 
 func (group *AgentGroup) CancelFunction() {
 	if group.cancelFunc != nil {
-		log.WithFields(log.Fields{logProc: "CancelFunction"}).
+		group.withFields(Fields{logProc: "CancelFunction"}).
 			Trace("call cancelFunc for all subroutines")
 		group.cancelFunc()
 	}
 }
 
-func (group *AgentGroup) doRequest(operation string, force bool) []OperationError {
+func (group *AgentGroup) doRequest(ctx context.Context, operation string, force bool) GroupResult {
 	lProc := "doRequest"
-	if group.Agents == nil || len(group.Agents) < 1 {
-		log.WithFields(log.Fields{logProc: lProc, logRequestType: operation}).
+	group.mutex.Lock()
+	agents := append([]*Agent(nil), group.Agents...)
+	group.mutex.Unlock()
+	if len(agents) < 1 {
+		group.withFields(Fields{logProc: lProc, logRequestType: operation}).
 			Warn("AgentGroup is empty")
 		return nil
 	}
-	err := make(chan OperationError, len(group.Agents))
-	for _, agent := range group.Agents {
-		group.wg.Add(1)
-		go agentOperation(operation, agent, &group.wg, err, force)
-	}
-	group.wg.Wait()
-	var ret []OperationError
-	for len(err) > 0 {
-		ret = append(ret, <-err)
+	ctx, span := startSpan(ctx, "group "+operation, trace.SpanKindInternal, attrOperation.String(operation), attrAgents.Int(len(agents)))
+	defer span.End()
+	ctx, cancel := group.operationContext(ctx)
+	defer cancel()
+	res := make(chan AgentResult, len(agents))
+	group.dispatch(ctx, len(agents), func(i int) {
+		group.agentOperation(ctx, operation, agents[i], res, force)
+	}, func(i int) {
+		res <- AgentResult{
+			LoginName: agents[i].LoginName,
+			Operation: operation,
+			Started:   time.Now(),
+			State:     agentState(agents[i]),
+			Error: OperationError{
+				Type:  TypeErrorCanceled,
+				Error: fmt.Errorf("operation [%s] for agent [%s] not started: %w", operation, agents[i].LoginName, ctx.Err()),
+			},
+		}
+	})
+	ret := make(GroupResult, len(agents))
+	for len(res) > 0 {
+		r := <-res
+		ret[r.LoginName] = r
 	}
-	if len(ret) != len(group.Agents) {
-		log.WithFields(log.Fields{logProc: lProc, logRequestType: operation}).
-			Errorf("Agents in AgentGroup [%d] different from number of responses [%d]", len(group.Agents), len(err))
+	span.SetAttributes(attrFailed.Int(len(ret.Failed())))
+	if len(ret) != len(agents) {
+		group.withFields(Fields{logProc: lProc, logRequestType: operation}).
+			Errorf("Agents in AgentGroup [%d] different from number of responses [%d]", len(agents), len(ret))
 	} else {
-		log.WithFields(log.Fields{logProc: lProc, logRequestType: operation}).
+		group.withFields(Fields{logProc: lProc, logRequestType: operation}).
 			Trace("operation processed for all Agent in group")
 	}
 	return ret
 }
 
-func agentOperation(operation string, a *Agent, wg *sync.WaitGroup, c chan OperationError, force bool) {
+func (group *AgentGroup) agentOperation(ctx context.Context, operation string, a *Agent, c chan AgentResult, force bool) {
 	lProc := "agentOperation"
-	log.WithFields(log.Fields{logProc: lProc, logRequestType: operation, logAgent: a.LoginName}).
+	group.withFields(Fields{logProc: lProc, logRequestType: operation, logAgent: a.LoginName}).
 		Tracef("process operation [%s] for agent [%s]", operation, a.LoginName)
-	defer wg.Done()
+	r := AgentResult{LoginName: a.LoginName, Operation: operation, Started: time.Now()}
+	ctx, span := startSpan(ctx, "group agent "+operation, trace.SpanKindInternal, attrOperation.String(operation), attrAgent.String(a.LoginName))
+	r.Error = group.operation(ctx, operation, a, force)
+	endSpan(span, r.Error)
+	r.Duration = time.Since(r.Started)
+	r.State = agentState(a)
+	c <- r
+}
+
+// operation run operation for agent, not-ready and logout use agent reason code when defined
+func (group *AgentGroup) operation(ctx context.Context, operation string, a *Agent, force bool) OperationError {
+	lProc := "agentOperation"
+	group.mutex.Lock()
+	member := group.members[a]
+	group.mutex.Unlock()
 	switch operation {
 	case AgentStateLogin:
-		c <- a.Login()
+		return a.LoginCtx(ctx)
 	case AgentStateLogout:
-		c <- a.Logout(force)
+		if len(member.LogoutReason) == 0 {
+			return a.LogoutCtx(ctx, force)
+		}
+		reason, err := a.getServer().ReasonCode(a, ReasonCategoryLogout, member.LogoutReason)
+		if err != nil {
+			return OperationError{Type: TypeErrorRequest, Error: err}
+		}
+		return a.LogoutWithReasonCtx(ctx, reason, force)
 	case AgentStateReady:
-		c <- a.Ready(force)
+		return a.ReadyCtx(ctx, force)
 	case AgentStateNotReady:
-		c <- a.NotReady()
-	default:
-		log.WithFields(log.Fields{logProc: lProc, logRequestType: operation, logAgent: a.LoginName}).
-			Errorf("unknown operation [%s] for agent [%s]", operation, a.LoginName)
-		c <- OperationError{
-			Type:  TypeErrorUnknownBulkCommand,
-			Error: fmt.Errorf("unknown AgentGroup operation [%s] for agent [%s]", operation, a.LoginName),
+		if len(member.NotReadyReason) == 0 {
+			return a.NotReadyCtx(ctx)
+		}
+		reason, err := a.getServer().ReasonCode(a, ReasonCategoryNotReady, member.NotReadyReason)
+		if err != nil {
+			return OperationError{Type: TypeErrorRequest, Error: err}
+		}
+		return a.NotReadyWithReasonCtx(ctx, reason)
+	}
+	group.withFields(Fields{logProc: lProc, logRequestType: operation, logAgent: a.LoginName}).
+		Errorf("unknown operation [%s] for agent [%s]", operation, a.LoginName)
+	return OperationError{
+		Type:  TypeErrorUnknownBulkCommand,
+		Error: fmt.Errorf("unknown AgentGroup operation [%s] for agent [%s]", operation, a.LoginName),
+	}
+}
+
+// agentState latest known agent state, empty when status was not collected
+func agentState(a *Agent) string {
+	if s := a.GetLastStatus(); s != nil {
+		return s.State
+	}
+	return ""
+}
+
+// States number of group agents in each latest known state, agents without collected status are in AgentStateUnknown
+func (group *AgentGroup) States() map[string]int {
+	group.mutex.Lock()
+	agents := append([]*Agent(nil), group.Agents...)
+	group.mutex.Unlock()
+	states := make(map[string]int)
+	for _, a := range agents {
+		state := agentState(a)
+		if len(state) == 0 {
+			state = AgentStateUnknown
 		}
+		states[state]++
 	}
+	return states
 }
@@ -0,0 +1,75 @@
+package finesse_api
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// dispatchJobs run dispatch with jobs sleeping for duration, returns maximal number of parallel jobs, started and skipped items
+func dispatchJobs(ctx context.Context, group *AgentGroup, n int, duration time.Duration) (parallel int, started []int, skipped []int) {
+	var mutex sync.Mutex
+	running := 0
+	group.dispatch(ctx, n, func(i int) {
+		mutex.Lock()
+		running++
+		if running > parallel {
+			parallel = running
+		}
+		started = append(started, i)
+		mutex.Unlock()
+		time.Sleep(duration)
+		mutex.Lock()
+		running--
+		mutex.Unlock()
+	}, func(i int) {
+		mutex.Lock()
+		skipped = append(skipped, i)
+		mutex.Unlock()
+	})
+	return parallel, started, skipped
+}
+
+func TestDispatchWorkers(t *testing.T) {
+	group := NewAgentGroup()
+	defer group.CancelFunction()
+	if group.workers != DefaultGroupWorkers || DefaultGroupWorkers != 0 {
+		t.Errorf("default workers is [%d], expected unlimited", group.workers)
+	}
+	if parallel, started, _ := dispatchJobs(context.Background(), group, 6, 50*time.Millisecond); parallel != 6 || len(started) != 6 {
+		t.Errorf("unlimited: [%d] parallel jobs from [%d], expected 6", parallel, len(started))
+	}
+
+	group.SetWorkers(2)
+	parallel, started, skipped := dispatchJobs(context.Background(), group, 6, 20*time.Millisecond)
+	if parallel != 2 {
+		t.Errorf("workers: [%d] parallel jobs, expected 2", parallel)
+	}
+	if len(started) != 6 || len(skipped) > 0 {
+		t.Errorf("workers: started %v, skipped %v", started, skipped)
+	}
+}
+
+func TestDispatchRampUp(t *testing.T) {
+	group := NewAgentGroup()
+	defer group.CancelFunction()
+	group.SetRampUp(20)
+	start := time.Now()
+	_, started, _ := dispatchJobs(context.Background(), group, 4, 0)
+	if elapsed := time.Since(start); elapsed < 140*time.Millisecond {
+		t.Errorf("4 jobs with ramp-up 20/s started in [%s], expected at least 150ms", elapsed)
+	}
+	if len(started) != 4 {
+		t.Errorf("started %v", started)
+	}
+
+	// items not started before context is done are skipped
+	ctx, cancel := context.WithTimeout(context.Background(), 75*time.Millisecond)
+	defer cancel()
+	group.SetRampUp(10)
+	_, started, skipped := dispatchJobs(ctx, group, 5, 0)
+	if len(started) != 1 || len(skipped) != 4 {
+		t.Errorf("canceled ramp-up: started %v, skipped %v", started, skipped)
+	}
+}
@@ -0,0 +1,65 @@
+package finesse_api_test
+
+import (
+	"testing"
+
+	api "github.com/pokornyIt/finesse-api"
+)
+
+func TestGroupDuplicateAgents(t *testing.T) {
+	mock := startMock(t)
+	mock.AddAgent("agent1", "1001", "password", "2001")
+	mock.AddAgent("agent2", "1002", "password", "2002")
+	server := mock.Finesse(true)
+	server.SetLogger(api.NewNopLogger())
+	group := api.NewAgentGroup()
+	defer group.CancelFunction()
+
+	if err := group.AddAgentToGroup("agent1", "password", "2001", server); err != nil {
+		t.Fatalf("add agent1: %s", err)
+	}
+	if err := group.AddAgentToGroup("agent1", "password", "2001", server); err == nil {
+		t.Errorf("agent1 added twice")
+	}
+	result := group.AddBulkAgents([]api.BulkAgent{
+		{Name: "agent2", Password: "password", Line: "2002"},
+		{Name: "agent1", Password: "password", Line: "2001"},
+	}, server)
+	for _, name := range []string{"agent1", "agent2"} {
+		if r := result[name]; r.Error.Type != api.TypeErrorRequest {
+			t.Errorf("%s: error type is [%d], expected [%d]", name, r.Error.Type, api.TypeErrorRequest)
+		}
+	}
+	if len(group.Agents) != 1 {
+		t.Errorf("group has [%d] agents, expected [1]", len(group.Agents))
+	}
+
+	result = group.AddBulkAgents([]api.BulkAgent{{Name: "agent2", Password: "password", Line: "2002"}}, server)
+	if failed := result.Failed(); len(failed) > 0 {
+		t.Fatalf("add agent2 failed %v", failed)
+	}
+	if states := group.States(); states[api.AgentStateLogout] != 2 {
+		t.Errorf("group states are %v, expected 2 agents in [%s]", states, api.AgentStateLogout)
+	}
+}
+
+func TestGroupCanceled(t *testing.T) {
+	mock := startMock(t)
+	mock.AddAgent("agent1", "1001", "password", "2001")
+	mock.AddAgent("agent2", "1002", "password", "2002")
+	group := mockGroup(t, mock,
+		api.BulkAgent{Name: "agent1", Line: "2001"},
+		api.BulkAgent{Name: "agent2", Line: "2002"},
+	)
+	group.CancelFunction()
+
+	result := group.Login()
+	for _, name := range []string{"agent1", "agent2"} {
+		if r := result[name]; r.Error.Type != api.TypeErrorCanceled {
+			t.Errorf("%s: error type is [%d], expected [%d]", name, r.Error.Type, api.TypeErrorCanceled)
+		}
+	}
+	if state := mock.AgentState("1001"); state != api.AgentStateLogout {
+		t.Errorf("mock state is [%s], expected [%s]", state, api.AgentStateLogout)
+	}
+}
@@ -0,0 +1,133 @@
+package finesse_api
+
+import (
+	"context"
+	"fmt"
+	"go.opentelemetry.io/otel/trace"
+	"time"
+)
+
+// notifyMatcher decide if XMPP notification confirm requested operation
+type notifyMatcher func(update *XmppUpdate) bool
+
+// notifyWaiter one request waiting for XMPP confirmation
+type notifyWaiter struct {
+	requestId string           // requestId ID sent in RequestId header
+	source    string           // source API path of request, used for errors without request ID
+	match     notifyMatcher    // match accept notification without request ID
+	result    chan *XmppUpdate // result accepted notification
+	done      bool             // done notification was delivered
+}
+
+// expectNotify register waiter for confirmation of request, must be called before request is sent
+func (a *Agent) expectNotify(requestId string, source string, match notifyMatcher) *notifyWaiter {
+	w := &notifyWaiter{
+		requestId: requestId,
+		source:    source,
+		match:     match,
+		result:    make(chan *XmppUpdate, 1),
+	}
+	a.waitMutex.Lock()
+	a.waiters = append(a.waiters, w)
+	a.waitMutex.Unlock()
+	return w
+}
+
+// dropNotify unregister waiter
+func (a *Agent) dropNotify(w *notifyWaiter) {
+	a.waitMutex.Lock()
+	defer a.waitMutex.Unlock()
+	for i, x := range a.waiters {
+		if x == w {
+			a.waiters = append(a.waiters[:i], a.waiters[i+1:]...)
+			return
+		}
+	}
+}
+
+// dispatchNotify deliver notification to waiting request
+//
+// Notification with request ID is delivered only to request with the same ID. Finesse does not fill request ID
+// for all notifications, notification without ID is delivered to the oldest waiter which accept it (errors by source).
+func (a *Agent) dispatchNotify(update *XmppUpdate) {
+	isError := update.Data.Error.ApiErrors != nil
+	a.waitMutex.Lock()
+	defer a.waitMutex.Unlock()
+	for _, w := range a.waiters {
+		if w.done {
+			continue
+		}
+		if len(update.RequestId) > 0 {
+			if w.requestId != update.RequestId {
+				continue
+			}
+			if isError || w.match(update) {
+				w.deliver(update)
+			}
+			return
+		}
+		if (isError && w.source == update.Source) || (!isError && w.match(update)) {
+			w.deliver(update)
+			return
+		}
+	}
+}
+
+func (w *notifyWaiter) deliver(update *XmppUpdate) {
+	w.done = true
+	w.result <- update
+}
+
+// awaitNotify wait for XMPP notification delivered to waiter
+//
+// Notification with API error ends wait with error, wait ends also when context is done.
+func (a *Agent) awaitNotify(ctx context.Context, w *notifyWaiter) (update *XmppUpdate, errOp OperationError) {
+	_, span := startSpan(ctx, "await notify", trace.SpanKindInternal,
+		attrAgent.String(a.LoginName), attrRequestId.String(w.requestId))
+	defer func() { endSpan(span, errOp) }()
+	select {
+	case <-ctx.Done():
+		a.withFields(Fields{logProc: "awaitNotify", logId: w.requestId, logAgent: a.LoginName}).Warnf("wait for notify canceled %s", ctx.Err())
+		return nil, OperationError{
+			Type:  TypeErrorCanceled,
+			Error: fmt.Errorf("wait for notify response for agent [%s] canceled: %w", a.LoginName, ctx.Err()),
+		}
+	case update = <-w.result:
+		if update.Data.Error.ApiErrors != nil {
+			a.withFields(Fields{logProc: "awaitNotify", logId: w.requestId, logAgent: a.LoginName}).
+				Warnf("request ends with error [%s]", update.Data.Error.ApiErrors[0].ErrorMessage)
+			return nil, OperationError{
+				Type:  TypeErrorAnalyzeResponse,
+				Error: newXmppError(update.Data.Error.ApiErrors[0], w.requestId),
+			}
+		}
+		a.withFields(Fields{logProc: "awaitNotify", logId: w.requestId, logAgent: a.LoginName}).Tracef("accept notification from [%s]", update.Source)
+		return update, OperationError{
+			Type:  TypeErrorNoError,
+			Error: nil,
+		}
+	case <-time.After(a.getServer().xmppTimeout):
+		a.withFields(Fields{logProc: "awaitNotify", logId: w.requestId, logAgent: a.LoginName}).Error("collect notify response form XMPP timeouts")
+		observeNotifyTimeout(a.getServer().name)
+		return nil, OperationError{
+			Type:  TypeErrorNotifyTimeout,
+			Error: fmt.Errorf("timeout collect notify response form XMPP for agnet [%s]: %w", a.LoginName, ErrNotifyTimeout),
+		}
+	}
+}
+
+// userStateMatcher accept user notification for user with login ID in requested state or pending state
+func userStateMatcher(loginId string, state string) notifyMatcher {
+	return func(update *XmppUpdate) bool {
+		u := update.Data.User
+		return len(u.URI) > 0 && u.LoginId == loginId && (u.State == state || u.PendingState == state)
+	}
+}
+
+// confirmedState agent state reported by Finesse after successful request for state, login ends in NOT_READY
+func confirmedState(requestState string) string {
+	if requestState == AgentStateLogin {
+		return AgentStateNotReady
+	}
+	return requestState
+}
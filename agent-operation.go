@@ -1,10 +1,10 @@
 package finesse_api
 
 import (
+	"context"
 	"encoding/xml"
 	"fmt"
-	log "github.com/sirupsen/logrus"
-	"time"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -17,6 +17,8 @@ const (
 	TypeErrorAnalyzeResponse    = 5
 	TypeErrorUnknownBulkCommand = 6
 	TypeErrorNoStatus           = 7
+	TypeErrorNotConnected       = 8
+	TypeErrorCanceled           = 9
 )
 
 type OperationError struct {
@@ -25,72 +27,174 @@ type OperationError struct {
 }
 
 func (a *Agent) Login() OperationError {
-	if a.lastStatus.State != AgentStateLogout {
-		return OperationError{
-			Type:  TypeErrorWrongState,
-			Error: fmt.Errorf("agent [%s] is in [%s] state", a.LoginName, a.lastStatus.State),
-		}
+	return a.LoginCtx(context.Background())
+}
+
+// LoginCtx login agent, context cancel request and wait for notification
+func (a *Agent) LoginCtx(ctx context.Context) OperationError {
+	if errOp := a.checkTransition(AgentStateLogin); errOp.Type != TypeErrorNoError {
+		return errOp
 	}
-	return a.doStateChange(AgentStateLogin)
+	return a.doStateChange(ctx, AgentStateLogin)
 }
 
 func (a *Agent) Logout(forceLogout ...bool) OperationError {
+	return a.LogoutCtx(context.Background(), forceLogout...)
+}
+
+// LogoutCtx logout agent, context cancel request and wait for notification
+func (a *Agent) LogoutCtx(ctx context.Context, forceLogout ...bool) OperationError {
 	force := false
 	if len(forceLogout) > 0 {
 		force = forceLogout[0]
 	}
-	if a.lastStatus.State == AgentStateReady && force {
-		errOp := a.NotReady()
-		if errOp.Type != TypeErrorNoError {
-			return errOp
-		}
-	}
-	if a.lastStatus.State != AgentStateNotReady {
+	return a.logout(ctx, force)
+}
+
+// LogoutWithReason logout agent with logout reason code
+func (a *Agent) LogoutWithReason(reason ReasonCode, forceLogout ...bool) OperationError {
+	return a.LogoutWithReasonCtx(context.Background(), reason, forceLogout...)
+}
+
+// LogoutWithReasonCtx logout agent with logout reason code, context cancel request and wait for notification
+func (a *Agent) LogoutWithReasonCtx(ctx context.Context, reason ReasonCode, forceLogout ...bool) OperationError {
+	if reason.Category != ReasonCategoryLogout {
 		return OperationError{
-			Type:  TypeErrorWrongState,
-			Error: fmt.Errorf("agent [%s] is in [%s] state and not possible logout", a.LoginName, a.lastStatus.State),
+			Type:  TypeErrorRequest,
+			Error: fmt.Errorf("reason code [%s] is in category [%s] and not usable for logout", reason.Label, reason.Category),
 		}
 	}
-	return a.doStateChange(AgentStateLogout)
+	force := false
+	if len(forceLogout) > 0 {
+		force = forceLogout[0]
+	}
+	return a.logout(ctx, force, reason.Id)
+}
+
+// logout logout agent, with force run all transitions planned by state machine (e.g. READY -> NOT_READY -> LOGOUT)
+func (a *Agent) logout(ctx context.Context, force bool, reason ...int) OperationError {
+	if force {
+		return a.moveTo(ctx, AgentStateLogout, reason...)
+	}
+	if errOp := a.checkTransition(AgentStateLogout); errOp.Type != TypeErrorNoError {
+		return errOp
+	}
+	return a.doStateChange(ctx, AgentStateLogout, reason...)
 }
 
 func (a *Agent) Ready(forceReady ...bool) OperationError {
+	return a.ReadyCtx(context.Background(), forceReady...)
+}
+
+// ReadyCtx set agent ready, context cancel request and wait for notification
+func (a *Agent) ReadyCtx(ctx context.Context, forceReady ...bool) OperationError {
 	force := false
 	if len(forceReady) > 0 {
 		force = forceReady[0]
 	}
-	_, ok := AgentReadyStates[a.lastStatus.State]
-	if ok {
+	if force {
+		return a.moveTo(ctx, AgentStateReady)
+	}
+	if errOp := a.checkTransition(AgentStateReady); errOp.Type != TypeErrorNoError {
+		return errOp
+	}
+	return a.doStateChange(ctx, AgentStateReady)
+}
+
+func (a *Agent) NotReady() OperationError {
+	return a.NotReadyCtx(context.Background())
+}
+
+// NotReadyCtx set agent not-ready, context cancel request and wait for notification
+func (a *Agent) NotReadyCtx(ctx context.Context) OperationError {
+	if a.lastStatus != nil && a.lastStatus.State == AgentStateNotReady {
 		return OperationError{
 			Type:  TypeErrorWrongState,
-			Error: fmt.Errorf("agent [%s] is in [%s] state and not possible switch to ready", a.LoginName, a.lastStatus.State),
+			Error: fmt.Errorf("agent [%s] is already in [%s] state, use reason code for change", a.LoginName, a.lastStatus.State),
 		}
 	}
+	if errOp := a.checkTransition(AgentStateNotReady); errOp.Type != TypeErrorNoError {
+		return errOp
+	}
+	return a.doStateChange(ctx, AgentStateNotReady)
+}
 
-	if a.lastStatus.State == AgentStateLogout && force {
-		errOp := a.Login()
-		if errOp.Type != TypeErrorNoError {
-			return errOp
+// NotReadyWithReason set agent into not-ready state with not-ready reason code, possible change reason for not-ready agent
+func (a *Agent) NotReadyWithReason(reason ReasonCode) OperationError {
+	return a.NotReadyWithReasonCtx(context.Background(), reason)
+}
+
+// NotReadyWithReasonCtx set agent into not-ready state with reason code, context cancel request and wait for notification
+func (a *Agent) NotReadyWithReasonCtx(ctx context.Context, reason ReasonCode) OperationError {
+	if reason.Category != ReasonCategoryNotReady {
+		return OperationError{
+			Type:  TypeErrorRequest,
+			Error: fmt.Errorf("reason code [%s] is in category [%s] and not usable for not-ready", reason.Label, reason.Category),
 		}
 	}
+	if errOp := a.checkTransition(AgentStateNotReady); errOp.Type != TypeErrorNoError {
+		return errOp
+	}
+	return a.doStateChange(ctx, AgentStateNotReady, reason.Id)
+}
 
-	return a.doStateChange(AgentStateReady)
+// StateMachine transitions allowed for agent changing own state, media type is from last known status
+func (a *Agent) StateMachine() *StateMachine {
+	mediaType := ""
+	if a.lastStatus != nil {
+		mediaType = a.lastStatus.MediaType
+	}
+	return NewStateMachine(mediaType, RoleAgent)
 }
 
-func (a *Agent) NotReady() OperationError {
-	_, ok := AgentReadyStates[a.lastStatus.State]
-	if !ok {
+// checkTransition verify agent can request state from last known state
+func (a *Agent) checkTransition(to string) OperationError {
+	from := AgentStateUnknown
+	if a.lastStatus != nil {
+		from = a.lastStatus.State
+	}
+	if _, err := a.StateMachine().Transition(from, to); err != nil {
+		return OperationError{
+			Type:  TypeErrorWrongState,
+			Error: fmt.Errorf("agent [%s]: %w", a.LoginName, err),
+		}
+	}
+	return OperationError{
+		Type:  TypeErrorNoError,
+		Error: nil,
+	}
+}
+
+// moveTo run all transitions planned by state machine from last known state, reason is used for the last transition
+func (a *Agent) moveTo(ctx context.Context, target string, reason ...int) OperationError {
+	path, err := a.StateMachine().PlanFor(a.lastStatus, target)
+	if err != nil {
 		return OperationError{
 			Type:  TypeErrorWrongState,
-			Error: fmt.Errorf("agent [%s] is in [%s] state and not possible switch to ready", a.LoginName, a.lastStatus.State),
+			Error: fmt.Errorf("agent [%s]: %w", a.LoginName, err),
+		}
+	}
+	for i, t := range path {
+		var r []int
+		if i == len(path)-1 {
+			r = reason
+		}
+		if errOp := a.doStateChange(ctx, t.To, r...); errOp.Type != TypeErrorNoError {
+			return errOp
 		}
 	}
-	return a.doStateChange(AgentStateNotReady)
+	return OperationError{
+		Type:  TypeErrorNoError,
+		Error: nil,
+	}
 }
 
-func (a *Agent) doStateChange(requestState string, reason ...int) OperationError {
+func (a *Agent) doStateChange(ctx context.Context, requestState string, reason ...int) (errOp OperationError) {
 	var err error
 	request := a.newAgentRequest()
+	ctx, span := startSpan(ctx, "state change", trace.SpanKindInternal, attrAgent.String(a.LoginName),
+		attrServer.String(request.server.name), attrRequestId.String(request.id), attrState.String(requestState))
+	defer func() { endSpan(span, errOp) }()
 	var requestBody []byte
 	if requestState == AgentStateNotReady && len(reason) > 0 {
 		state := userStateWithReasonRequest{
@@ -121,54 +225,52 @@ func (a *Agent) doStateChange(requestState string, reason ...int) OperationError
 	}
 
 	if err != nil {
-		log.WithFields(log.Fields{logProc: "doStateChange", logId: request.id, logAgent: a.LoginName, logNewState: requestState}).
+		a.withFields(Fields{logProc: "doStateChange", logId: request.id, logAgent: a.LoginName, logNewState: requestState}).
 			Errorf("change state to %s agent %s on line %s. Prolem is %s", requestState, a.LoginName, a.Line, err)
 		return OperationError{
 			Type:  TypeErrorRequest,
 			Error: err,
 		}
 	}
-	// clean queue https://stackoverflow.com/a/26143288/4074126
-	for len(a.response) > 0 {
-		data := <-a.response
-		log.WithFields(log.Fields{logProc: "doStateChange", logId: request.id, logAgent: a.LoginName, logNewState: requestState}).Debugf("remove data from channel [%s]", data)
+	if errOp := a.checkConnected(request.id); errOp.Type != TypeErrorNoError {
+		return errOp
 	}
+	w := a.expectNotify(request.id, request.server.apiPath("User", a.LoginId), userStateMatcher(a.LoginId, confirmedState(requestState)))
+	defer a.dropNotify(w)
 
-	response := request.doRequest("PUT", a.server.urlString(request.id, "User", a.LoginId), requestBody)
+	response := request.doRequestCtx(ctx, "PUT", request.server.urlString(request.id, "User", a.LoginId), requestBody)
+	defer response.close()
 	msg, err := response.responseError()
 	if err != nil {
-		response.close()
-		log.WithFields(log.Fields{logProc: "doStateChange", logId: response.id, logAgent: a.LoginName, logNewState: requestState}).Error(msg)
+		a.withFields(Fields{logProc: "doStateChange", logId: response.id, logAgent: a.LoginName, logNewState: requestState}).Error(msg)
 		return OperationError{
 			Type:  TypeErrorResponse,
 			Error: err,
 		}
 	}
-	log.WithFields(log.Fields{logProc: "doStateChange", logId: response.id, logAgent: a.LoginName, logNewState: requestState}).
+	a.withFields(Fields{logProc: "doStateChange", logId: response.id, logAgent: a.LoginName, logNewState: requestState}).
 		Tracef("agnet [%s] state change request", a.LoginName)
 
-	select {
-	case status := <-a.response:
-		log.WithFields(log.Fields{logProc: "doStateChange", logId: response.id, logAgent: a.LoginName, logNewState: requestState}).Tracef("get message from XMPP notification")
-		s, e := a.analyzeResponse(status)
-		if e != nil {
-			log.WithFields(log.Fields{logProc: "doStateChange", logId: response.id, logAgent: a.LoginName, logNewState: requestState}).Error(e)
-			return OperationError{
-				Type:  TypeErrorAnalyzeResponse,
-				Error: e,
-			}
-		} else {
-			a.lastStatus = s
-		}
-		return OperationError{
-			Type:  TypeErrorNoError,
-			Error: nil,
-		}
-	case <-time.After(time.Duration(XmppTimeout) * time.Second):
-		log.WithFields(log.Fields{logProc: "doStateChange", logId: response.id, logAgent: a.LoginName, logNewState: requestState}).Error("collect notify response form XMPP timeouts")
+	var update *XmppUpdate
+	update, errOp = a.awaitNotify(ctx, w)
+	if errOp.Type != TypeErrorNoError {
+		return errOp
+	}
+	a.lastStatus = &update.Data.User
+	return errOp
+}
+
+// checkConnected verify XMPP notification is connected, without notification is not possible confirm request
+func (a *Agent) checkConnected(requestId string) OperationError {
+	if !a.IsConnected() {
+		a.withFields(Fields{logProc: "checkConnected", logId: requestId, logAgent: a.LoginName}).Errorf("XMPP notification for agent [%s] is not connected", a.LoginName)
 		return OperationError{
-			Type:  TypeErrorNotifyTimeout,
-			Error: fmt.Errorf("timeout collect notify response form XMPP for agnet [%s]", a.LoginName),
+			Type:  TypeErrorNotConnected,
+			Error: fmt.Errorf("XMPP notification for agent [%s] is not connected: %w", a.LoginName, ErrNotConnected),
 		}
 	}
+	return OperationError{
+		Type:  TypeErrorNoError,
+		Error: nil,
+	}
 }
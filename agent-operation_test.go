@@ -0,0 +1,115 @@
+package finesse_api_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	api "github.com/pokornyIt/finesse-api"
+	"github.com/pokornyIt/finesse-api/finessetest"
+)
+
+// startMock start mock server stopped at the end of test
+func startMock(t *testing.T) *finessetest.Server {
+	t.Helper()
+	mock, err := finessetest.NewServer()
+	if err != nil {
+		t.Fatalf("start mock server: %s", err)
+	}
+	t.Cleanup(mock.Close)
+	return mock
+}
+
+// mockAgent create agent connected to mock over plain XMPP, agent must exist on mock with password "password"
+func mockAgent(t *testing.T, mock *finessetest.Server, name string, line string) *api.Agent {
+	t.Helper()
+	server := mock.Finesse(true)
+	server.SetLogger(api.NewNopLogger())
+	agent, err := server.CreateAgent(context.Background(), name, "password", line)
+	if err != nil {
+		t.Fatalf("create agent [%s]: %s", name, err)
+	}
+	t.Cleanup(agent.StopXmpp)
+	return agent
+}
+
+// checkOperation operation finished without error
+func checkOperation(t *testing.T, name string, errOp api.OperationError) {
+	t.Helper()
+	if errOp.Type != api.TypeErrorNoError {
+		t.Fatalf("%s: unexpected error type [%d]: %s", name, errOp.Type, errOp.Error)
+	}
+}
+
+// checkWrongState operation is rejected by state machine
+func checkWrongState(t *testing.T, name string, errOp api.OperationError) {
+	t.Helper()
+	if errOp.Type != api.TypeErrorWrongState {
+		t.Fatalf("%s: error type is [%d], expected [%d]: %s", name, errOp.Type, api.TypeErrorWrongState, errOp.Error)
+	}
+	if !errors.Is(errOp.Error, api.ErrInvalidState) {
+		t.Errorf("%s: error [%s] is not ErrInvalidState", name, errOp.Error)
+	}
+}
+
+func TestForceLogout(t *testing.T) {
+	mock := startMock(t)
+	mock.AddAgent("agent1", "1001", "password", "2001")
+	agent := mockAgent(t, mock, "agent1", "2001")
+	checkOperation(t, "login", agent.Login())
+	checkOperation(t, "ready", agent.Ready())
+
+	checkWrongState(t, "logout", agent.Logout())
+	if state := mock.AgentState("1001"); state != api.AgentStateReady {
+		t.Errorf("rejected logout: mock state is [%s], expected [%s]", state, api.AgentStateReady)
+	}
+	checkOperation(t, "force logout", agent.Logout(true))
+	if state := mock.AgentState("1001"); state != api.AgentStateLogout {
+		t.Errorf("force logout: mock state is [%s], expected [%s]", state, api.AgentStateLogout)
+	}
+	if state := agent.GetLastStatus().State; state != api.AgentStateLogout {
+		t.Errorf("force logout: agent state is [%s], expected [%s]", state, api.AgentStateLogout)
+	}
+}
+
+func TestForceReady(t *testing.T) {
+	mock := startMock(t)
+	mock.AddAgent("agent1", "1001", "password", "2001")
+	agent := mockAgent(t, mock, "agent1", "2001")
+
+	checkWrongState(t, "ready", agent.Ready())
+	if state := mock.AgentState("1001"); state != api.AgentStateLogout {
+		t.Errorf("rejected ready: mock state is [%s], expected [%s]", state, api.AgentStateLogout)
+	}
+	checkOperation(t, "force ready", agent.Ready(true))
+	if state := mock.AgentState("1001"); state != api.AgentStateReady {
+		t.Errorf("force ready: mock state is [%s], expected [%s]", state, api.AgentStateReady)
+	}
+	checkOperation(t, "force ready in ready state", agent.Ready(true))
+}
+
+func TestSupervisorSetAgentState(t *testing.T) {
+	mock := startMock(t)
+	mock.AddSupervisor("super", "1000", "password", "2000")
+	mock.AddAgent("agent1", "1001", "password", "2001")
+	supervisor, err := api.NewSupervisor(mockAgent(t, mock, "super", "2000"))
+	if err != nil {
+		t.Fatalf("create supervisor: %s", err)
+	}
+
+	checkWrongState(t, "ready of logged out agent", supervisor.SetAgentState("1001", api.AgentStateReady))
+	if state := mock.AgentState("1001"); state != api.AgentStateLogout {
+		t.Errorf("rejected ready: mock state is [%s], expected [%s]", state, api.AgentStateLogout)
+	}
+	if errOp := supervisor.SetAgentState("1001", api.AgentStateLogin); errOp.Type != api.TypeErrorRequest {
+		t.Errorf("login: error type is [%d], expected [%d]", errOp.Type, api.TypeErrorRequest)
+	}
+
+	if err = mock.SetAgentState("1001", api.AgentStateReady); err != nil {
+		t.Fatalf("set mock state: %s", err)
+	}
+	checkOperation(t, "sign out ready agent", supervisor.SignOutAgent("1001"))
+	if state := mock.AgentState("1001"); state != api.AgentStateLogout {
+		t.Errorf("sign out: mock state is [%s], expected [%s]", state, api.AgentStateLogout)
+	}
+}
@@ -0,0 +1,137 @@
+package finesse_api
+
+import (
+	"context"
+	"fmt"
+	"gosrc.io/xmpp"
+	"math/rand"
+	"time"
+)
+
+const (
+	XmppReconnectMin = 1  // XmppReconnectMin first reconnect delay in seconds
+	XmppReconnectMax = 60 // XmppReconnectMax maximal reconnect delay in seconds
+)
+
+// ConnectionEvent change of agent XMPP notification connection
+type ConnectionEvent struct {
+	Agent     *Agent    // Agent with changed connection
+	Connected bool      // Connected true when connection is up
+	Attempt   int       // Attempt reconnect attempt, 0 for first connection
+	Error     error     // Error reason of lost connection
+	Time      time.Time // Time of change
+}
+
+func (e ConnectionEvent) String() string {
+	if e.Connected {
+		return fmt.Sprintf("%s XMPP connected (attempt %d)", e.Agent.LoginName, e.Attempt)
+	}
+	return fmt.Sprintf("%s XMPP disconnected (%v)", e.Agent.LoginName, e.Error)
+}
+
+// SetConnectionHandler set callback called on every XMPP connection up/down change
+//
+// Callback is called from supervisor subroutine and must not block
+func (a *Agent) SetConnectionHandler(handler func(ConnectionEvent)) {
+	a.xmppMutex.Lock()
+	a.connHandler = handler
+	a.xmppMutex.Unlock()
+}
+
+// IsConnected XMPP notification is connected
+func (a *Agent) IsConnected() bool {
+	a.xmppMutex.Lock()
+	defer a.xmppMutex.Unlock()
+	return a.connected
+}
+
+// setConnected store connection state and inform connection handler about change
+func (a *Agent) setConnected(connected bool, attempt int, err error) {
+	a.xmppMutex.Lock()
+	changed := a.connected != connected
+	a.connected = connected
+	handler := a.connHandler
+	a.xmppMutex.Unlock()
+	if !changed {
+		return
+	}
+	observeXmppConnection(a.getServer().name, connected)
+	e := ConnectionEvent{
+		Agent:     a,
+		Connected: connected,
+		Attempt:   attempt,
+		Error:     err,
+		Time:      time.Now(),
+	}
+	a.events.publish(e)
+	if handler != nil {
+		handler(e)
+	}
+}
+
+// superviseXmpp wait for lost connection and reconnect it with exponential backoff until context is done
+func (a *Agent) superviseXmpp(ctx context.Context, router *xmpp.Router, lost <-chan error) {
+	for {
+		select {
+		case <-ctx.Done():
+			a.disconnectXmpp(ctx.Err())
+			return
+		case err := <-lost:
+			a.withFields(Fields{logProc: "superviseXmpp", logAgent: a.LoginName}).Warnf("agent [%s] XMPP connection lost %s", a.LoginName, err)
+			a.setConnected(false, 0, err)
+			lost = a.reconnectXmpp(ctx, router)
+			if lost == nil {
+				a.disconnectXmpp(ctx.Err())
+				return
+			}
+		}
+	}
+}
+
+// reconnectXmpp repeat connect until success, returns nil if context is done before
+func (a *Agent) reconnectXmpp(ctx context.Context, router *xmpp.Router) <-chan error {
+	for attempt := 1; ; attempt++ {
+		delay := reconnectDelay(attempt)
+		a.withFields(Fields{logProc: "reconnectXmpp", logAgent: a.LoginName}).Debugf("reconnect attempt [%d] for agent [%s] in %s", attempt, a.LoginName, delay)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+		lost, err := a.connectXmpp(router, attempt)
+		if err != nil {
+			a.withFields(Fields{logProc: "reconnectXmpp", logAgent: a.LoginName}).Warnf("reconnect attempt [%d] for agent [%s] fails %s", attempt, a.LoginName, err)
+			continue
+		}
+		// notifications during outage are lost, resync agent state
+		if _, err = a.GetStatus(); err != nil {
+			a.withFields(Fields{logProc: "reconnectXmpp", logAgent: a.LoginName}).Warnf("resync state for agent [%s] fails %s", a.LoginName, err)
+		}
+		a.withFields(Fields{logProc: "reconnectXmpp", logAgent: a.LoginName}).Infof("agent [%s] XMPP reconnected after [%d] attempts", a.LoginName, attempt)
+		return lost
+	}
+}
+
+// disconnectXmpp close actual XMPP client
+func (a *Agent) disconnectXmpp(reason error) {
+	a.xmppMutex.Lock()
+	client := a.xmppClient
+	a.xmppClient = nil
+	a.xmppMutex.Unlock()
+	if client != nil {
+		_ = client.Disconnect()
+	}
+	a.setConnected(false, 0, reason)
+}
+
+// reconnectDelay exponential backoff with jitter, delay is between half and full of exponential value
+func reconnectDelay(attempt int) time.Duration {
+	delay := time.Duration(XmppReconnectMax) * time.Second
+	if attempt < 16 {
+		delay = time.Duration(XmppReconnectMin) * time.Second << (attempt - 1)
+		if delay > time.Duration(XmppReconnectMax)*time.Second {
+			delay = time.Duration(XmppReconnectMax) * time.Second
+		}
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
@@ -16,27 +16,42 @@ const (
 	AgentStatePaused              = "PAUSED"
 	AgentStateInterrupted         = "INTERRUPTED"
 	AgentStateNotActive           = "NOT_ACTIVE"
+	AgentStateWork                = "WORK" // AgentStateWork requested wrap-up state, agent is in WORK_NOT_READY after call
 )
 
+// StateSet set of agent states
+type StateSet map[string]struct{}
+
+func newStateSet(states ...string) StateSet {
+	s := make(StateSet, len(states))
+	for _, state := range states {
+		s[state] = struct{}{}
+	}
+	return s
+}
+
+// Contains state is in set
+func (s StateSet) Contains(state string) bool {
+	_, ok := s[state]
+	return ok
+}
+
 // AgentStates All valid agent states
 var AgentStates = []string{AgentStateLogin, AgentStateLogout, AgentStateReady, AgentStateNotReady, AgentStateAvailable,
 	AgentStateTalking, AgentStateWorkNotReady, AgentStateWorkReady, AgentStateReserved, AgentStateUnknown, AgentStateHold,
-	AgentStateActive, AgentStatePaused, AgentStateInterrupted, AgentStateNotActive}
+	AgentStateActive, AgentStatePaused, AgentStateInterrupted, AgentStateNotActive, AgentStateWork}
 
 // AgentReadyStates States when agent is ready for work or work
-var AgentReadyStates = map[string]string{AgentStateReady: AgentStateReady, AgentStateAvailable: AgentStateAvailable,
-	AgentStateTalking: AgentStateTalking, AgentStateWorkReady: AgentStateWorkReady, AgentStateReserved: AgentStateReserved,
-	AgentStateHold: AgentStateHold, AgentStateActive: AgentStateActive}
+var AgentReadyStates = newStateSet(AgentStateReady, AgentStateAvailable, AgentStateTalking, AgentStateWorkReady,
+	AgentStateReserved, AgentStateHold, AgentStateActive)
 
 // AgentLoginStates States when agent is logged in to the system
-var AgentLoginStates = map[string]string{AgentStateLogin: AgentStateLogin, AgentStateReady: AgentStateReady,
-	AgentStateNotReady: AgentStateNotReady, AgentStateAvailable: AgentStateAvailable, AgentStateTalking: AgentStateTalking,
-	AgentStateWorkNotReady: AgentStateWorkNotReady, AgentStateWorkReady: AgentStateWorkReady, AgentStateReserved: AgentStateReserved,
-	AgentStateHold: AgentStateHold, AgentStateActive: AgentStateActive, AgentStatePaused: AgentStatePaused,
-	AgentStateInterrupted: AgentStateInterrupted, AgentStateNotActive: AgentStateNotActive}
+var AgentLoginStates = newStateSet(AgentStateLogin, AgentStateReady, AgentStateNotReady, AgentStateAvailable,
+	AgentStateTalking, AgentStateWorkNotReady, AgentStateWorkReady, AgentStateReserved, AgentStateHold, AgentStateActive,
+	AgentStatePaused, AgentStateInterrupted, AgentStateNotActive)
 
 // AgentNotReadyStates States when agent is not-ready
-var AgentNotReadyStates = map[string]string{AgentStateNotReady: AgentStateNotReady, AgentStateWorkNotReady: AgentStateWorkNotReady}
+var AgentNotReadyStates = newStateSet(AgentStateNotReady, AgentStateWorkNotReady)
 
 // AgentLogoutState States when agent is not logged in to the system
-var AgentLogoutState = map[string]string{AgentStateLogout: AgentStateLogout, AgentStateUnknown: AgentStateUnknown}
+var AgentLogoutState = newStateSet(AgentStateLogout, AgentStateUnknown)
@@ -2,33 +2,48 @@ package finesse_api
 
 import (
 	"context"
-	"crypto/tls"
 	"encoding/xml"
 	"fmt"
-	log "github.com/sirupsen/logrus"
 	"gosrc.io/xmpp"
 	"gosrc.io/xmpp/stanza"
+	"math"
 	"net/http"
 	"reflect"
 	"strings"
-	"time"
+	"sync"
+	"sync/atomic"
 )
 
 const (
-	XmppMessageBuffer = 10 // define channel buffer for collect message from Xmpp notify service
+	// XmppMessageBuffer define channel buffer for collect message from Xmpp notify service
+	//
+	// Deprecated: notifications are delivered to requests by request ID and to subscribers, use Agent.Subscribe
+	XmppMessageBuffer = 10
 )
 
 type Agent struct {
-	LoginName            string              // login name
-	LoginId              string              // login ID
-	Password             string              // password
-	Line                 string              // phone line
-	lastStatus           *XmppUser           // latest agent response
-	httpClient           *http.Client        // prepared HTTP client
-	streamManagerService *xmpp.StreamManager // XMPP scream manager for user
-	ctx                  context.Context     // context for graceful shutdown of notify subroutine
-	server               *Server             // associate finesse server
-	response             chan string         // channel for get strings
+	LoginName  string          // login name
+	LoginId    string          // login ID
+	Password   string          // password
+	Line       string          // phone line
+	lastStatus *XmppUser       // latest agent response
+	httpClient *http.Client    // prepared HTTP client
+	ctx        context.Context // context for graceful shutdown of notify subroutine
+	server     *Server         // associate finesse server, changed by ServerPair failover
+	events     *eventBus       // events distribute notifications to subscribers
+	logger     atomic.Value    // logger agent logger, overrides logger of server
+
+	xmppClient  *xmpp.Client          // actual XMPP client, replaced after reconnect
+	xmppCancel  context.CancelFunc    // xmppCancel stop XMPP supervisor, nil if notification not started
+	xmppDone    chan struct{}         // xmppDone closed when XMPP supervisor ends
+	connected   bool                  // connected XMPP notification is connected
+	connHandler func(ConnectionEvent) // connHandler callback for XMPP connection changes
+	xmppMutex   sync.Mutex
+
+	waiters   []*notifyWaiter // waiters requests waiting for XMPP confirmation
+	waitMutex sync.Mutex
+
+	serverMutex sync.Mutex
 }
 
 // NewAgentNotify create new agent object, but not create/start any additional service
@@ -36,59 +51,75 @@ type Agent struct {
 // Better way is use function Server.CreateAgent, this creates agent and start necessary function
 func NewAgentNotify(ctx context.Context, name string, pwd string, line string, server *Server) *Agent {
 	return &Agent{
-		LoginName:            name,
-		LoginId:              "",
-		Password:             pwd,
-		Line:                 line,
-		lastStatus:           nil,
-		httpClient:           nil,
-		streamManagerService: nil,
-		server:               server,
-		ctx:                  ctx,
-		response:             make(chan string, XmppMessageBuffer),
+		LoginName:  name,
+		LoginId:    "",
+		Password:   pwd,
+		Line:       line,
+		lastStatus: nil,
+		httpClient: nil,
+		server:     server,
+		ctx:        ctx,
+		events:     newEventBus(ctx),
+		waiters:    nil,
 	}
 }
 
 func (a *Agent) newAgentRequest() *AgentRequest {
+	server := a.getServer()
 	r := AgentRequest{
 		id:        randomString(),
-		client:    a.server.getHttpClient(),
-		server:    a.server,
+		client:    server.getHttpClient(),
+		server:    server,
 		request:   nil,
 		loginName: a.LoginName,
 		password:  a.Password,
 		line:      a.Line,
+		logging:   a.logSettings(),
 	}
-	log.WithFields(log.Fields{logProc: "NewRequest", logId: r.id, logServer: r.server.name}).Tracef("prepare new request for server [%s]", a.server.name)
+	a.withFields(Fields{logProc: "NewRequest", logId: r.id, logServer: r.server.name}).Tracef("prepare new request for server [%s]", server.name)
 	return &r
 }
 
+// getServer actual Finesse node used by agent
+func (a *Agent) getServer() *Server {
+	a.serverMutex.Lock()
+	defer a.serverMutex.Unlock()
+	return a.server
+}
+
+// setServer change Finesse node used by agent, XMPP must be restarted to use new node
+func (a *Agent) setServer(server *Server) {
+	a.serverMutex.Lock()
+	a.server = server
+	a.serverMutex.Unlock()
+}
+
 // getId read ID from Finesse and store it if OK
-func (a *Agent) getId() error {
+func (a *Agent) getId(ctx context.Context) error {
 	if a.LoginId != "" {
 		return nil
 	}
 	request := a.newAgentRequest()
-	response := request.doRequest("GET", a.server.urlString(request.id, "User", a.LoginName), nil)
+	response := request.doRequestCtx(ctx, "GET", request.server.urlString(request.id, "User", a.LoginName), nil)
 	defer response.close()
 	msg, err := response.responseError()
 	if err != nil {
-		log.WithFields(log.Fields{logProc: "getAgentId", logId: response.id, logAgent: a.LoginName}).Error(msg)
+		a.withFields(Fields{logProc: "getAgentId", logId: response.id, logAgent: a.LoginName}).Error(msg)
 		return err
 	}
-	log.WithFields(log.Fields{logProc: "getAgentId", logId: response.id, logAgent: a.LoginName}).Trace("success get data for agentId from name")
+	a.withFields(Fields{logProc: "getAgentId", logId: response.id, logAgent: a.LoginName}).Trace("success get data for agentId from name")
 	data, err := newXmppUser(response.GetResponseBody())
 	if err != nil {
-		log.WithFields(log.Fields{logProc: "getAgentId", logId: response.id, logAgent: a.LoginName}).Error(err)
+		a.withFields(Fields{logProc: "getAgentId", logId: response.id, logAgent: a.LoginName}).Error(err)
 		return err
 	}
 	if len(data.LoginId) <= 0 {
-		log.WithFields(log.Fields{logProc: "getAgentId", logId: response.id, logAgent: a.LoginName}).Errorf("problem collect agentId from request")
+		a.withFields(Fields{logProc: "getAgentId", logId: response.id, logAgent: a.LoginName}).Errorf("problem collect agentId from request")
 		return fmt.Errorf("agent ID is empty for agent name %s", a.LoginName)
 	}
 	a.lastStatus = data
 	a.LoginId = data.LoginId
-	log.WithFields(log.Fields{logProc: "getAgentId", logId: response.id, logAgent: a.LoginName}).Tracef("collect agentId [%s] for agent [%s]", data.LoginId, a.LoginName)
+	a.withFields(Fields{logProc: "getAgentId", logId: response.id, logAgent: a.LoginName}).Tracef("collect agentId [%s] for agent [%s]", data.LoginId, a.LoginName)
 	return nil
 }
 
@@ -99,53 +130,133 @@ func (a *Agent) getDomain() string {
 			return sp[1]
 		}
 	}
-	return a.server.getDomain()
+	return a.getServer().getDomain()
 }
 
+// StartXmpp connect XMPP notification for agent and start supervisor, which reconnects lost connection
+//
+// The first connection must be successful, otherwise returns error and notification is not started.
 func (a *Agent) StartXmpp() error {
-	if a.streamManagerService != nil {
-		log.WithFields(log.Fields{logProc: "StartNotification", logAgent: a.LoginName}).Trace("start finesse_notifier - XMPP notifier is ready ")
+	a.xmppMutex.Lock()
+	if a.xmppCancel != nil {
+		a.xmppMutex.Unlock()
+		a.withFields(Fields{logProc: "StartNotification", logAgent: a.LoginName}).Trace("start finesse_notifier - XMPP notifier is ready ")
 		return nil
 	}
 	if a.LoginId == "" {
-		log.WithFields(log.Fields{logProc: "StartNotification", logAgent: a.LoginName}).Errorf("XMPP not start missing agent login ID")
+		a.xmppMutex.Unlock()
+		a.withFields(Fields{logProc: "StartNotification", logAgent: a.LoginName}).Errorf("XMPP not start missing agent login ID")
 		return fmt.Errorf("XMPP not start missing agent login ID")
 	}
+	ctx, cancel := context.WithCancel(a.ctx)
+	done := make(chan struct{})
+	a.xmppCancel = cancel
+	a.xmppDone = done
+	a.xmppMutex.Unlock()
+
+	a.withFields(Fields{logProc: "StartNotification", logAgent: a.LoginName}).Trace("start finesse_notifier")
+	router := a.newXmppRouter()
+	lost, err := a.connectXmpp(router, 0)
+	if err != nil {
+		close(done)
+		a.StopXmpp()
+		a.withFields(Fields{logProc: "StartNotification", logAgent: a.LoginName}).Errorf("agent [%s] XMPP connection problem %s", a.LoginName, err)
+		return err
+	}
+	go func() {
+		defer close(done)
+		a.superviseXmpp(ctx, router, lost)
+	}()
+	return nil
+}
 
-	log.WithFields(log.Fields{logProc: "StartNotification", logAgent: a.LoginName}).Trace("start finesse_notifier")
+// StopXmpp stop XMPP notification and its reconnect supervisor, waits until connection is closed
+func (a *Agent) StopXmpp() {
+	a.xmppMutex.Lock()
+	cancel := a.xmppCancel
+	done := a.xmppDone
+	a.xmppCancel = nil
+	a.xmppDone = nil
+	a.xmppMutex.Unlock()
+	if cancel != nil {
+		a.withFields(Fields{logProc: "StopNotification", logAgent: a.LoginName}).Tracef("stop notify subroutine for agent [%s]", a.LoginName)
+		cancel()
+		<-done
+	}
+}
 
+// connectXmpp create new XMPP client and connect it, returned channel signals lost connection
+func (a *Agent) connectXmpp(router *xmpp.Router, attempt int) (<-chan error, error) {
 	// setup WSS or XMPP connection parameters
-	t := &tls.Config{InsecureSkipVerify: a.server.ignore}
+	s := a.getServer()
+	t := s.TLSConfig()
 	domain := a.getDomain()
-	server := fmt.Sprintf("wss://%s:%d/ws/", a.server.name, a.server.xmppPort)
+	server := fmt.Sprintf("%s:%d", s.name, s.xmppPort)
+	insecure := s.ignore
 
-	if a.server.insecureXmpp {
-		server = fmt.Sprintf("%s:%d", a.server.name, a.server.xmppPort)
+	if !s.insecureXmpp {
+		tunnel, err := s.newWssTunnel()
+		if err != nil {
+			return nil, err
+		}
+		// listener is needed only for WebSocket upgrade, upgraded connection stays open
+		defer tunnel.close()
+		a.withFields(Fields{logProc: "StartNotification", logAgent: a.LoginName}).
+			Tracef("XMPP over WSS [wss://%s/ws/] by tunnel [%s]", server, tunnel.address())
+		server = tunnel.address()
+		// TLS of WSS is terminated by tunnel, STARTTLS is not possible on loopback connection
+		insecure = true
 	}
-	log.WithFields(log.Fields{logProc: "StartNotification", logAgent: a.LoginName}).
-		Debugf("finesse_notifier server [%s] with domain [%s] ignore certificate problem [%t]", server, domain, a.server.ignore)
+	a.withFields(Fields{logProc: "StartNotification", logAgent: a.LoginName}).
+		Debugf("finesse_notifier server [%s] with domain [%s] ignore certificate problem [%t]", server, domain, s.ignore)
 
+	timeout := int(math.Ceil(s.xmppTimeout.Seconds()))
 	config := xmpp.Config{
 		TransportConfiguration: xmpp.TransportConfiguration{
 			Address:   server,
 			Domain:    domain,
 			TLSConfig: t,
 		},
-		Jid:        fmt.Sprintf("%s@%s", a.LoginId, a.server.name),
+		Jid:        fmt.Sprintf("%s@%s", a.LoginId, s.name),
 		Credential: xmpp.Password(a.Password),
 		//StreamLogger: os.Stdout,
-		Insecure: a.server.ignore,
+		ConnectTimeout: timeout, // client copies own timeout (default 15 seconds) into transport configuration
+		Insecure:       insecure,
+	}
+
+	lost := make(chan error, 1)
+	client, err := xmpp.NewClient(&config, router, func(err error) {
+		select {
+		case lost <- err:
+		default:
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	a.withFields(Fields{logProc: "StartNotification", logAgent: a.LoginName}).Debugf("prepare XMPP client for agent [%s]", a.LoginName)
+	if err = client.Connect(); err != nil {
+		return nil, err
 	}
+	a.xmppMutex.Lock()
+	a.xmppClient = client
+	a.xmppMutex.Unlock()
+	a.withFields(Fields{logProc: "StartNotification", logAgent: a.LoginName}).Tracef("started notify client for agent [%s]", a.LoginName)
+	a.setConnected(true, attempt, nil)
+	return lost, nil
+}
 
+// newXmppRouter create router for process pubsub notifications
+func (a *Agent) newXmppRouter() *xmpp.Router {
 	//goland:noinspection HttpUrlsUsage
 	stanza.TypeRegistry.MapExtension(stanza.PKTMessage, xml.Name{Space: "http://jabber.org/protocol/pubsub#event", Local: "event"}, stanza.PubSubEvent{})
 	router := xmpp.NewRouter()
 	//router.HandleFunc("message", a.messageHandler)
 	router.HandleFunc("message", func(s xmpp.Sender, p stanza.Packet) {
-		log.WithFields(log.Fields{logProc: "messageHandler", logAgent: a.LoginName}).Trace("handle XMPP message stream")
+		a.withFields(Fields{logProc: "messageHandler", logAgent: a.LoginName}).Trace("handle XMPP message stream")
 		msg, ok := p.(stanza.Message)
 		if !ok {
-			log.WithFields(log.Fields{logProc: "messageHandler", logAgent: a.LoginName}).Tracef("ignore packet %T", p)
+			a.withFields(Fields{logProc: "messageHandler", logAgent: a.LoginName}).Tracef("ignore packet %T", p)
 			return
 		}
 		if len(msg.Extensions) > 0 {
@@ -155,57 +266,40 @@ func (a *Agent) StartXmpp() error {
 					if "*stanza.ItemsEvent" == reflect.TypeOf(ext.EventElement).String() {
 						element := ext.EventElement.(*stanza.ItemsEvent)
 						for _, item := range element.Items {
-							log.WithFields(log.Fields{logProc: "messageHandler", logAgent: a.LoginName}).Trace("success accept message")
-
-							select {
-							case a.response <- item.Any.Content:
-								log.WithFields(log.Fields{logProc: "messageHandler", logAgent: a.LoginName}).Trace("success send new data into buffered queue")
-							case <-time.After(time.Duration(20) * time.Second):
-								log.WithFields(log.Fields{logProc: "messageHandler", logAgent: a.LoginName}).Warnf("timeout send message to queue")
-							default:
-								log.WithFields(log.Fields{logProc: "messageHandler", logAgent: a.LoginName}).Warnf("response buffered queue is full. Data lost!")
-							}
+							a.withFields(Fields{logProc: "messageHandler", logAgent: a.LoginName}).Trace("success accept message")
+							a.publishNotify(item.Any.Content)
 						}
 					} else {
-						log.WithFields(log.Fields{logProc: "messageHandler", logAgent: a.LoginName}).
+						a.withFields(Fields{logProc: "messageHandler", logAgent: a.LoginName}).
 							Warnf("PubSubEvent doesnt contains unexpected type [%s]", reflect.TypeOf(ext.EventElement).String())
 					}
 				} else {
-					log.WithFields(log.Fields{logProc: "messageHandler", logAgent: a.LoginName}).
+					a.withFields(Fields{logProc: "messageHandler", logAgent: a.LoginName}).
 						Warnf("unknown XMPP extension type [%s]", reflect.TypeOf(extension).String())
 				}
 			}
 		} else {
-			log.WithFields(log.Fields{logProc: "messageHandler", logAgent: a.LoginName}).Warnf("XMPP message without extension type")
+			a.withFields(Fields{logProc: "messageHandler", logAgent: a.LoginName}).Warnf("XMPP message without extension type")
 		}
 	})
+	return router
+}
 
-	client, err := xmpp.NewClient(&config, router, func(err error) {
-		//log.WithFields(log.Fields{logProc: "StartNotification", logAgent: a.LoginName}).Errorf("client error handler messages - %s", err)
-	})
-	log.WithFields(log.Fields{logProc: "StartNotification", logAgent: a.LoginName}).Debugf("prepare XMPP client for agent [%s]", a.LoginName)
-
-	a.streamManagerService = xmpp.NewStreamManager(client, nil)
-	go func() {
-		log.WithFields(log.Fields{logProc: "StartNotification", logAgent: a.LoginName}).Debugf("start XMPP listener for agent [%s]", a.LoginName)
-		err = a.streamManagerService.Run()
-		if err != nil {
-			a.streamManagerService = nil
-			log.WithFields(log.Fields{logProc: "StartNotification", logAgent: a.LoginName}).Errorf("agent [%s] XMPP stream manager problem %s", a.LoginName, err)
-			return
-		}
-		log.WithFields(log.Fields{logProc: "StartNotification", logAgent: a.LoginName}).Tracef("started notify StreamManager for agent [%s]", a.LoginName)
-		// await for stop
-		<-a.ctx.Done()
-		log.WithFields(log.Fields{logProc: "StartNotification", logAgent: a.LoginName}).Tracef("stop notify subroutine for agent [%s]", a.LoginName)
-		if a.streamManagerService != nil {
-			a.streamManagerService.Stop()
-			a.streamManagerService = nil
-		}
-	}()
-	// wait for connect
-	time.Sleep(1 * time.Second)
-	return nil
+// publishNotify parse XMPP notification, deliver it to waiting request and publish it as typed event to subscribers
+func (a *Agent) publishNotify(data string) {
+	var envelope XmppUpdate
+	if err := xml.Unmarshal([]byte(data), &envelope); err != nil {
+		a.withFields(Fields{logProc: "publishNotify", logAgent: a.LoginName}).Warnf("problem with XML unmarshal envelope - %s", err)
+		return
+	}
+	a.dispatchNotify(&envelope)
+	e := newNotifyEvent(a, &envelope)
+	observeNotify(a.getServer().name, e)
+	if e == nil {
+		a.withFields(Fields{logProc: "publishNotify", logAgent: a.LoginName}).Debugf("unknown notification from [%s]", envelope.Source)
+		return
+	}
+	a.events.publish(e)
 }
 
 // GetLastStatus get latest collected user status
@@ -215,18 +309,23 @@ func (a *Agent) GetLastStatus() *XmppUser {
 
 // GetStatus geta actual agent status from finesse server
 func (a *Agent) GetStatus() (*XmppUser, error) {
+	return a.GetStatusCtx(context.Background())
+}
+
+// GetStatusCtx get actual agent status from finesse server, context cancel request
+func (a *Agent) GetStatusCtx(ctx context.Context) (*XmppUser, error) {
 	request := a.newAgentRequest()
-	response := request.doRequest("GET", a.server.urlString(request.id, "User", a.LoginName), nil)
+	response := request.doRequestCtx(ctx, "GET", request.server.urlString(request.id, "User", a.LoginName), nil)
 	defer response.close()
 	msg, err := response.responseError()
 	if err != nil {
-		log.WithFields(log.Fields{logProc: "getAgentId", logId: response.id, logAgent: a.LoginName}).Error(msg)
+		a.withFields(Fields{logProc: "getAgentId", logId: response.id, logAgent: a.LoginName}).Error(msg)
 		return nil, err
 	}
-	log.WithFields(log.Fields{logProc: "getAgentId", logId: response.id, logAgent: a.LoginName}).Trace("success get data for agentId from name")
+	a.withFields(Fields{logProc: "getAgentId", logId: response.id, logAgent: a.LoginName}).Trace("success get data for agentId from name")
 	data, err := newXmppUser(response.GetResponseBody())
 	if err != nil {
-		log.WithFields(log.Fields{logProc: "getAgentId", logId: response.id, logAgent: a.LoginName}).Error(err)
+		a.withFields(Fields{logProc: "getAgentId", logId: response.id, logAgent: a.LoginName}).Error(err)
 		return nil, err
 	}
 	a.lastStatus = data
@@ -252,29 +351,3 @@ func (a *Agent) String() string {
 	}
 	return fmt.Sprintf("%s (%s) => UNKNOWN", a.LoginName, a.Line)
 }
-
-func (a *Agent) analyzeResponse(data string) (*XmppUser, error) {
-	var envelope XmppUpdate
-	var err error
-	err = xml.Unmarshal([]byte(data), &envelope)
-	if err != nil {
-		log.WithFields(log.Fields{logProc: "analyzeResponse", logAgent: a.LoginName}).Warnf("problem with XML unmarshal envelope - %s", err)
-		return nil, fmt.Errorf("problem unmarhal response XML")
-	}
-	if envelope.Data.Error.ApiErrors != nil {
-		// problem here is error
-		log.WithFields(log.Fields{logProc: "analyzeResponse", logAgent: a.LoginName}).Warnf("request ends with error for XMPP User - %s", err)
-		return nil, fmt.Errorf("%s", envelope.Data.Error.ApiErrors[0].ErrorMessage)
-	}
-	if len(envelope.Data.User.URI) > 0 {
-		log.WithFields(log.Fields{logProc: "analyzeResponse", logAgent: a.LoginName}).Tracef("collect User data for XMPP")
-		usr := &envelope.Data.User
-		return usr, nil
-	}
-	if envelope.Data.Dialogs.Dialogs != nil {
-		log.WithFields(log.Fields{logProc: "analyzeResponse", logAgent: a.LoginName}).Warnf("collect dialogs data for XMPP User - %s", err)
-
-	}
-	log.WithFields(log.Fields{logProc: "analyzeResponse", logAgent: a.LoginName}).Warnf("unknown data body in request for XMPP User - %s", err)
-	return nil, fmt.Errorf("problem get right data from response for request %s", envelope.Source)
-}
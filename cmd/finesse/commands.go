@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	api "github.com/pokornyIt/finesse-api"
+)
+
+// runStatus print agent state read by REST API, XMPP notification is not used
+func runStatus(ctx context.Context, cfg *config, args []string) error {
+	if err := parseFlags("status", args); err != nil {
+		return err
+	}
+	a := api.NewAgentNotify(ctx, cfg.user, cfg.password, cfg.line, cfg.finesseServer())
+	status, err := a.GetStatusCtx(ctx)
+	if err != nil {
+		return err
+	}
+	return printAgent(cfg, status)
+}
+
+func runLogin(ctx context.Context, cfg *config, args []string) error {
+	if err := parseFlags("login", args); err != nil {
+		return err
+	}
+	if len(cfg.line) == 0 {
+		return fmt.Errorf("agent line is not defined, use -line flag or %s variable", envLine)
+	}
+	return withAgent(ctx, cfg, func(s *api.Server, a *api.Agent) api.OperationError {
+		return a.LoginCtx(ctx)
+	})
+}
+
+func runReady(ctx context.Context, cfg *config, args []string) error {
+	fs := flag.NewFlagSet("ready", flag.ContinueOnError)
+	force := fs.Bool("force", false, "login agent first when agent is logged out")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	return withAgent(ctx, cfg, func(s *api.Server, a *api.Agent) api.OperationError {
+		return a.ReadyCtx(ctx, *force)
+	})
+}
+
+func runNotReady(ctx context.Context, cfg *config, args []string) error {
+	fs := flag.NewFlagSet("notready", flag.ContinueOnError)
+	reason := fs.String("reason", "", "not-ready reason code label, code or ID")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	return withAgent(ctx, cfg, func(s *api.Server, a *api.Agent) api.OperationError {
+		if len(*reason) == 0 {
+			return a.NotReadyCtx(ctx)
+		}
+		code, err := s.ReasonCode(a, api.ReasonCategoryNotReady, *reason)
+		if err != nil {
+			return api.OperationError{Type: api.TypeErrorRequest, Error: err}
+		}
+		return a.NotReadyWithReasonCtx(ctx, code)
+	})
+}
+
+func runLogout(ctx context.Context, cfg *config, args []string) error {
+	fs := flag.NewFlagSet("logout", flag.ContinueOnError)
+	reason := fs.String("reason", "", "logout reason code label, code or ID")
+	force := fs.Bool("force", false, "set ready agent not-ready first")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	return withAgent(ctx, cfg, func(s *api.Server, a *api.Agent) api.OperationError {
+		if len(*reason) == 0 {
+			return a.LogoutCtx(ctx, *force)
+		}
+		code, err := s.ReasonCode(a, api.ReasonCategoryLogout, *reason)
+		if err != nil {
+			return api.OperationError{Type: api.TypeErrorRequest, Error: err}
+		}
+		return a.LogoutWithReasonCtx(ctx, code, *force)
+	})
+}
+
+// runWatch print agent notifications until interrupt or end of duration
+func runWatch(ctx context.Context, cfg *config, args []string) error {
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	duration := fs.Duration("duration", 0, "stop watching after duration (default until interrupted)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *duration)
+		defer cancel()
+	}
+	a, err := cfg.finesseServer().CreateAgent(ctx, cfg.user, cfg.password, cfg.line)
+	if err != nil {
+		return err
+	}
+	defer a.StopXmpp()
+	sub := a.Subscribe(nil, api.DeliveryLossless)
+	defer sub.Close()
+	if err = printAgent(cfg, a.GetLastStatus()); err != nil {
+		return err
+	}
+	write := formatters[cfg.output]
+	first := true
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case e, ok := <-sub.C:
+			if !ok {
+				return nil
+			}
+			if err = write(os.Stdout, []record{newEventRecord(e)}, first); err != nil {
+				return err
+			}
+			first = false
+		}
+	}
+}
+
+// runTransitions print transitions allowed from actual agent state or path planned to target state
+func runTransitions(ctx context.Context, cfg *config, args []string) error {
+	fs := flag.NewFlagSet("transitions", flag.ContinueOnError)
+	to := fs.String("to", "", "plan transitions to state (e.g. READY, LOGOUT)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	a := api.NewAgentNotify(ctx, cfg.user, cfg.password, cfg.line, cfg.finesseServer())
+	status, err := a.GetStatusCtx(ctx)
+	if err != nil {
+		return err
+	}
+	list := a.StateMachine().Transitions(status.State)
+	if len(*to) > 0 {
+		if list, err = a.StateMachine().PlanFor(status, strings.ToUpper(*to)); err != nil {
+			return err
+		}
+	}
+	records := make([]record, 0, len(list))
+	for _, t := range list {
+		records = append(records, newTransitionRecord(t))
+	}
+	return formatters[cfg.output](os.Stdout, records, true)
+}
+
+// withAgent connect agent with XMPP notification, run operation and print new agent state
+func withAgent(ctx context.Context, cfg *config, operation func(s *api.Server, a *api.Agent) api.OperationError) error {
+	s := cfg.finesseServer()
+	a, err := s.CreateAgent(ctx, cfg.user, cfg.password, cfg.line)
+	if err != nil {
+		return err
+	}
+	defer a.StopXmpp()
+	if op := operation(s, a); op.Type != api.TypeErrorNoError {
+		return op.Error
+	}
+	return printAgent(cfg, a.GetLastStatus())
+}
+
+// parseFlags parse flags of command without own flags
+func parseFlags(name string, args []string) error {
+	return flag.NewFlagSet(name, flag.ContinueOnError).Parse(args)
+}
+
+func printAgent(cfg *config, status *api.XmppUser) error {
+	if status == nil {
+		return fmt.Errorf("agent status is not available")
+	}
+	return formatters[cfg.output](os.Stdout, []record{newAgentRecord(status)}, true)
+}
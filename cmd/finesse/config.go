@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	api "github.com/pokornyIt/finesse-api"
+)
+
+const (
+	envUser     = "FINESSE_USER"     // envUser environment variable with agent login name
+	envPassword = "FINESSE_PASSWORD" // envPassword environment variable with agent password
+	envLine     = "FINESSE_LINE"     // envLine environment variable with agent line (extension)
+)
+
+// config global flags and agent credentials
+type config struct {
+	server       string
+	port         int
+	xmppPort     int
+	insecure     bool
+	insecureXmpp bool
+	timeout      int
+	output       string
+	credentials  string
+	caFile       string
+	pins         string
+	logLevel     string
+	trace        bool
+
+	user     string
+	password string
+	line     string
+
+	finesse *api.Server
+}
+
+func newConfig(fs *flag.FlagSet) *config {
+	c := &config{}
+	fs.StringVar(&c.server, "server", "", "Finesse server FQDN or IP address")
+	fs.IntVar(&c.port, "port", api.DefaultServerHttpsPort, "Finesse REST API port")
+	fs.IntVar(&c.xmppPort, "xmpp-port", 0, fmt.Sprintf("XMPP port (default %d for WSS, %d for plain XMPP)", api.DefaultServerXmppPort, api.DefaultServerDirectXmppPort))
+	fs.BoolVar(&c.insecure, "insecure", false, "ignore invalid server certificate")
+	fs.BoolVar(&c.insecureXmpp, "insecure-xmpp", false, "use plain XMPP instead of XMPP over WSS")
+	fs.StringVar(&c.caFile, "ca-file", "", "PEM file with Finesse server or CA certificate used instead of system trust store")
+	fs.StringVar(&c.pins, "pin", "", "comma separated base64 SHA-256 hashes of accepted server public keys (SPKI)")
+	fs.IntVar(&c.timeout, "timeout", api.DefaultServerTimeout, "timeout for API requests and notifications in seconds")
+	fs.StringVar(&c.output, "output", formatTable, "output format: table, json or xml")
+	fs.StringVar(&c.credentials, "credentials", "", "file with user=, password= and line= lines (default from "+envUser+", "+envPassword+", "+envLine+")")
+	fs.StringVar(&c.user, "user", "", "agent login name, overrides credentials")
+	fs.StringVar(&c.line, "line", "", "agent line (extension), overrides credentials")
+	fs.StringVar(&c.logLevel, "log-level", "fatal", "library log level (trace, debug, info, warn, error, fatal)")
+	fs.BoolVar(&c.trace, "trace", false, "write OpenTelemetry spans of REST requests and notifications to stderr")
+	return c
+}
+
+// validate check flags and load credentials
+func (c *config) validate() error {
+	if len(c.server) == 0 {
+		return fmt.Errorf("server is not defined, use -server flag")
+	}
+	if _, ok := formatters[c.output]; !ok {
+		return fmt.Errorf("unknown output format [%s]", c.output)
+	}
+	if c.xmppPort == 0 {
+		c.xmppPort = api.DefaultServerXmppPort
+		if c.insecureXmpp {
+			c.xmppPort = api.DefaultServerDirectXmppPort
+		}
+	}
+	user, password, line := os.Getenv(envUser), os.Getenv(envPassword), os.Getenv(envLine)
+	if len(c.credentials) > 0 {
+		values, err := readCredentials(c.credentials)
+		if err != nil {
+			return err
+		}
+		user, password, line = values["user"], values["password"], values["line"]
+	}
+	if len(c.user) == 0 {
+		c.user = user
+	}
+	if len(c.line) == 0 {
+		c.line = line
+	}
+	c.password = password
+	if len(c.user) == 0 || len(c.password) == 0 {
+		return fmt.Errorf("agent credentials are not defined, use -credentials file or %s and %s variables", envUser, envPassword)
+	}
+	timeout := time.Duration(c.timeout) * time.Second
+	options := []api.Option{api.WithPort(c.port), api.WithXmppPort(c.xmppPort), api.WithRestTimeout(timeout), api.WithXmppTimeout(timeout)}
+	if c.insecure {
+		options = append(options, api.WithInsecureSkipVerify())
+	}
+	if c.insecureXmpp {
+		options = append(options, api.WithInsecureXmpp())
+	}
+	if len(c.caFile) > 0 {
+		options = append(options, api.WithRootCAFile(c.caFile))
+	}
+	if len(c.pins) > 0 {
+		options = append(options, api.WithPinnedSPKI(strings.Split(c.pins, ",")...))
+	}
+	var err error
+	c.finesse, err = api.NewServerWithOptions(c.server, options...)
+	return err
+}
+
+// finesseServer server created from flags
+func (c *config) finesseServer() *api.Server {
+	return c.finesse
+}
+
+// readCredentials read key=value lines, empty lines and lines starting with # are ignored
+func readCredentials(file string) (map[string]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("problem open credentials file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	n := 0
+	for scanner.Scan() {
+		n++
+		text := strings.TrimSpace(scanner.Text())
+		if len(text) == 0 || strings.HasPrefix(text, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(text, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid line %d in credentials file [%s]", n, file)
+		}
+		values[strings.ToLower(strings.TrimSpace(key))] = strings.TrimSpace(value)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("problem read credentials file: %w", err)
+	}
+	return values, nil
+}
@@ -0,0 +1,105 @@
+// Command finesse manage state of Finesse agent from command line.
+//
+// Usage:
+//
+//	finesse [flags] <command> [command flags]
+//
+// Commands:
+//
+//	status                    show actual agent state (REST only, without XMPP)
+//	login                     login agent on line
+//	ready [-force]            set agent ready, with -force login agent first
+//	notready [-reason label]  set agent not-ready, optionally with reason code (label, code or ID)
+//	logout [-reason label] [-force]
+//	                          logout agent, with -force set ready agent not-ready first
+//	watch [-duration d]       print agent notifications until interrupted
+//	transitions [-to state]   show states agent can request or planned transitions to state
+//
+// Credentials are read from file selected by -credentials (lines user=..., password=..., line=...)
+// or from environment variables FINESSE_USER, FINESSE_PASSWORD and FINESSE_LINE.
+// Flag -trace writes OpenTelemetry spans of REST requests and notification waits to stderr.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// command one CLI subcommand
+type command struct {
+	usage string
+	run   func(ctx context.Context, cfg *config, args []string) error
+}
+
+var commands = map[string]command{
+	"status":      {usage: "show actual agent state", run: runStatus},
+	"login":       {usage: "login agent on line", run: runLogin},
+	"ready":       {usage: "set agent ready", run: runReady},
+	"notready":    {usage: "set agent not-ready", run: runNotReady},
+	"logout":      {usage: "logout agent", run: runLogout},
+	"watch":       {usage: "print agent notifications", run: runWatch},
+	"transitions": {usage: "show allowed or planned state transitions", run: runTransitions},
+}
+
+var commandOrder = []string{"status", "login", "ready", "notready", "logout", "watch", "transitions"}
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	fs := flag.NewFlagSet("finesse", flag.ContinueOnError)
+	cfg := newConfig(fs)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(fs.Output(), "Usage: finesse [flags] <command> [command flags]\n\nCommands:\n")
+		for _, name := range commandOrder {
+			_, _ = fmt.Fprintf(fs.Output(), "  %-10s %s\n", name, commands[name].usage)
+		}
+		_, _ = fmt.Fprintf(fs.Output(), "\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+	cmd, ok := commands[fs.Arg(0)]
+	if !ok {
+		_, _ = fmt.Fprintf(os.Stderr, "unknown command [%s]\n", fs.Arg(0))
+		fs.Usage()
+		return 2
+	}
+	if err := cfg.validate(); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	level, err := log.ParseLevel(cfg.logLevel)
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	log.SetLevel(level)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if cfg.trace {
+		var flush func()
+		if ctx, flush, err = startTracing(ctx, os.Stderr, fs.Arg(0)); err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer flush()
+	}
+	if err = cmd.run(ctx, cfg, fs.Args()[1:]); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "%s: %s\n", fs.Arg(0), err)
+		return 1
+	}
+	return 0
+}
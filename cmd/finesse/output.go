@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	api "github.com/pokornyIt/finesse-api"
+)
+
+const (
+	formatTable = "table" // formatTable aligned text columns
+	formatJson  = "json"  // formatJson one JSON object per record
+	formatXml   = "xml"   // formatXml one XML element per record
+)
+
+// record one output row
+type record interface {
+	header() []string
+	columns() []string
+}
+
+// formatter write records in selected format, table header is printed with printHeader
+type formatter func(w io.Writer, records []record, printHeader bool) error
+
+var formatters = map[string]formatter{formatTable: writeTable, formatJson: writeJson, formatXml: writeXml}
+
+// agentRecord agent state
+type agentRecord struct {
+	XMLName         xml.Name `xml:"Agent" json:"-"`
+	LoginName       string   `xml:"loginName" json:"loginName"`
+	LoginId         string   `xml:"loginId" json:"loginId"`
+	Extension       string   `xml:"extension" json:"extension"`
+	State           string   `xml:"state" json:"state"`
+	PendingState    string   `xml:"pendingState,omitempty" json:"pendingState,omitempty"`
+	ReasonCode      string   `xml:"reasonCode,omitempty" json:"reasonCode,omitempty"`
+	StateChangeTime string   `xml:"stateChangeTime" json:"stateChangeTime"`
+	TeamName        string   `xml:"teamName" json:"teamName"`
+}
+
+// transitionRecord one state transition
+type transitionRecord struct {
+	XMLName xml.Name `xml:"Transition" json:"-"`
+	From    string   `xml:"from" json:"from"`
+	To      string   `xml:"to" json:"to"`
+	Result  string   `xml:"result" json:"result"`
+	Pending bool     `xml:"pending" json:"pending"`
+}
+
+// eventRecord one agent notification
+type eventRecord struct {
+	XMLName   xml.Name  `xml:"Event" json:"-"`
+	Time      time.Time `xml:"time" json:"time"`
+	Type      string    `xml:"type" json:"type"`
+	Operation string    `xml:"operation,omitempty" json:"operation,omitempty"`
+	Source    string    `xml:"source,omitempty" json:"source,omitempty"`
+	Detail    string    `xml:"detail" json:"detail"`
+}
+
+// newAgentRecord agent state, reason code is label or ID when label is not notified
+func newAgentRecord(u *api.XmppUser) agentRecord {
+	r := agentRecord{
+		LoginName:       u.LoginName,
+		LoginId:         u.LoginId,
+		Extension:       u.Extension,
+		State:           u.State,
+		PendingState:    u.PendingState,
+		ReasonCode:      u.ReasonCode.Label,
+		StateChangeTime: u.StateChangeTime,
+		TeamName:        u.TeamName,
+	}
+	if len(r.ReasonCode) == 0 && len(u.ReasonCodeId) > 0 && u.ReasonCodeId != "-1" {
+		r.ReasonCode = u.ReasonCodeId
+	}
+	return r
+}
+
+func newTransitionRecord(t api.Transition) transitionRecord {
+	return transitionRecord{From: t.From, To: t.To, Result: t.Result, Pending: t.Pending}
+}
+
+func (r transitionRecord) header() []string {
+	return []string{"FROM", "REQUEST", "RESULT", "PENDING"}
+}
+
+func (r transitionRecord) columns() []string {
+	return []string{r.From, r.To, r.Result, strconv.FormatBool(r.Pending)}
+}
+
+func (r agentRecord) header() []string {
+	return []string{"LOGIN NAME", "LOGIN ID", "EXTENSION", "STATE", "PENDING", "REASON", "CHANGED", "TEAM"}
+}
+
+func (r agentRecord) columns() []string {
+	return []string{r.LoginName, r.LoginId, r.Extension, r.State, r.PendingState, r.ReasonCode, r.StateChangeTime, r.TeamName}
+}
+
+// newEventRecord describe event in one line
+func newEventRecord(e api.Event) eventRecord {
+	r := eventRecord{Time: time.Now(), Type: e.Type().String()}
+	switch ev := e.(type) {
+	case api.UserEvent:
+		r.setNotify(ev.NotifyEvent)
+		r.Detail = fmt.Sprintf("state %s", ev.User.State)
+		if len(ev.User.ReasonCode.Label) > 0 {
+			r.Detail = fmt.Sprintf("%s (%s)", r.Detail, ev.User.ReasonCode.Label)
+		}
+	case api.DialogEvent:
+		r.setNotify(ev.NotifyEvent)
+		var d []string
+		for _, dialog := range ev.Dialogs {
+			d = append(d, fmt.Sprintf("%s %s %s->%s", dialog.ID, dialog.State, dialog.FromAddress, dialog.ToAddress))
+		}
+		r.Detail = strings.Join(d, ", ")
+	case api.QueueEvent:
+		r.setNotify(ev.NotifyEvent)
+		r.Detail = fmt.Sprintf("queue %s calls %d", ev.QueueId, ev.Statistics.CallsInQueue)
+	case api.TeamEvent:
+		r.setNotify(ev.NotifyEvent)
+	case api.TeamMessageEvent:
+		r.setNotify(ev.NotifyEvent)
+		r.Detail = fmt.Sprintf("%s: %s", ev.TeamMessage.CreatedByName, ev.TeamMessage.Content)
+	case api.ErrorEvent:
+		r.setNotify(ev.NotifyEvent)
+		var d []string
+		for _, err := range ev.Errors {
+			d = append(d, fmt.Sprintf("%s %s", err.ErrorType, err.ErrorMessage))
+		}
+		r.Detail = strings.Join(d, ", ")
+	case api.ConnectionEvent:
+		r.Time = ev.Time
+		r.Detail = ev.String()
+	default:
+		r.Detail = fmt.Sprintf("%v", e)
+	}
+	return r
+}
+
+func (r *eventRecord) setNotify(n api.NotifyEvent) {
+	r.Time = n.Time
+	r.Operation = n.Operation
+	r.Source = n.Source
+}
+
+func (r eventRecord) header() []string {
+	return []string{"TIME", "TYPE", "OPERATION", "SOURCE", "DETAIL"}
+}
+
+func (r eventRecord) columns() []string {
+	return []string{r.Time.Format(time.RFC3339), r.Type, r.Operation, r.Source, r.Detail}
+}
+
+func writeTable(w io.Writer, records []record, printHeader bool) error {
+	if len(records) == 0 {
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if printHeader {
+		if _, err := fmt.Fprintln(tw, strings.Join(records[0].header(), "\t")); err != nil {
+			return err
+		}
+	}
+	for _, r := range records {
+		if _, err := fmt.Fprintln(tw, strings.Join(r.columns(), "\t")); err != nil {
+			return err
+		}
+	}
+	return tw.Flush()
+}
+
+func writeJson(w io.Writer, records []record, _ bool) error {
+	e := json.NewEncoder(w)
+	for _, r := range records {
+		if err := e.Encode(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeXml(w io.Writer, records []record, _ bool) error {
+	for _, r := range records {
+		data, err := xml.Marshal(r)
+		if err != nil {
+			return err
+		}
+		if _, err = fmt.Fprintln(w, string(data)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"io"
+
+	api "github.com/pokornyIt/finesse-api"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// startTracing write library spans to writer, command runs in root span, returned function flushes spans
+func startTracing(ctx context.Context, w io.Writer, name string) (context.Context, func(), error) {
+	exporter, err := stdouttrace.New(stdouttrace.WithWriter(w), stdouttrace.WithPrettyPrint())
+	if err != nil {
+		return ctx, nil, err
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	api.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	ctx, span := provider.Tracer("finesse").Start(ctx, "finesse "+name, trace.WithSpanKind(trace.SpanKindInternal))
+	return ctx, func() {
+		span.End()
+		_ = provider.Shutdown(context.Background())
+	}, nil
+}
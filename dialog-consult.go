@@ -0,0 +1,148 @@
+package finesse_api
+
+import (
+	"context"
+	"fmt"
+)
+
+// LinkedDialog primary dialog with associated consult dialog
+type LinkedDialog struct {
+	Primary *Dialog // primary dialog, held during consult call
+	Consult *Dialog // consult dialog associated with primary dialog
+}
+
+// LinkedDialogs get actual agent dialogs from finesse server and link consult dialogs with it's primary dialogs
+func (a *Agent) LinkedDialogs() ([]*LinkedDialog, error) {
+	return a.LinkedDialogsCtx(context.Background())
+}
+
+// LinkedDialogsCtx get actual agent dialogs with linked consult dialogs, context cancel request
+func (a *Agent) LinkedDialogsCtx(ctx context.Context) ([]*LinkedDialog, error) {
+	dialogs, err := a.GetDialogsCtx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byId := make(map[string]*Dialog, len(dialogs))
+	for _, d := range dialogs {
+		byId[d.Id] = d
+	}
+	var ret []*LinkedDialog
+	for _, d := range dialogs {
+		primary, ok := byId[d.AssociatedDialogId()]
+		if !ok {
+			continue
+		}
+		ret = append(ret, &LinkedDialog{Primary: primary, Consult: d})
+	}
+	a.withFields(Fields{logProc: "LinkedDialogs", logAgent: a.LoginName}).Tracef("collect [%d] linked dialogs for agent [%s]", len(ret), a.LoginName)
+	return ret, nil
+}
+
+// Consult hold dialog and create consult call to destination address
+func (d *Dialog) Consult(toAddress string) (*LinkedDialog, OperationError) {
+	return d.ConsultCtx(context.Background(), toAddress)
+}
+
+// ConsultCtx hold dialog and create consult call, context cancel request and wait for notification
+func (d *Dialog) ConsultCtx(ctx context.Context, toAddress string) (*LinkedDialog, OperationError) {
+	if errOp := d.checkAction(DialogActionConsultCall); errOp.Type != TypeErrorNoError {
+		return nil, errOp
+	}
+	body := dialogActionRequest{
+		RequestedAction: DialogActionConsultCall,
+		FromAddress:     d.agent.Line,
+		ToAddress:       toAddress,
+	}
+	var consult *XmppDialog
+	_, errOp := d.agent.doDialogRequest(ctx, "PUT", body.RequestedAction, &body, func(update *XmppUpdate) bool {
+		for _, dialog := range update.dialogs() {
+			if _, ok := DialogLiveStates[dialog.State]; ok && dialog.ID != d.Id && dialog.associatedDialogId() == d.Id {
+				consult = &dialog
+				return true
+			}
+		}
+		return false
+	}, "Dialog", d.Id)
+	if errOp.Type != TypeErrorNoError {
+		return nil, errOp
+	}
+	return &LinkedDialog{Primary: d, Consult: newDialog(d.agent, consult)}, errOp
+}
+
+// Transfer complete transfer of primary dialog to consulted party
+func (l *LinkedDialog) Transfer() OperationError {
+	return l.TransferCtx(context.Background())
+}
+
+// TransferCtx complete transfer to consulted party, context cancel request and wait for notification
+func (l *LinkedDialog) TransferCtx(ctx context.Context) OperationError {
+	if errOp := l.Consult.checkAction(DialogActionTransfer); errOp.Type != TypeErrorNoError {
+		return errOp
+	}
+	body := dialogActionRequest{
+		RequestedAction:    DialogActionTransfer,
+		TargetMediaAddress: l.Consult.agent.Line,
+	}
+	_, errOp := l.Consult.agent.doDialogRequest(ctx, "PUT", body.RequestedAction, &body, l.Primary.participantStateMatcher(DialogStateDropped, DialogStateWrapUp), "Dialog", l.Consult.Id)
+	return errOp
+}
+
+// Conference join primary dialog and consulted party into conference
+func (l *LinkedDialog) Conference() OperationError {
+	return l.ConferenceCtx(context.Background())
+}
+
+// ConferenceCtx join primary dialog and consulted party, context cancel request and wait for notification
+func (l *LinkedDialog) ConferenceCtx(ctx context.Context) OperationError {
+	if errOp := l.Consult.checkAction(DialogActionConference); errOp.Type != TypeErrorNoError {
+		return errOp
+	}
+	if l.Consult.lastStatus == nil {
+		return OperationError{
+			Type:  TypeErrorWrongState,
+			Error: fmt.Errorf("consult dialog [%s] has no status", l.Consult.Id),
+		}
+	}
+	target := l.Consult.lastStatus.ToAddress
+	body := dialogActionRequest{
+		RequestedAction:    DialogActionConference,
+		TargetMediaAddress: l.Consult.agent.Line,
+	}
+	_, errOp := l.Consult.agent.doDialogRequest(ctx, "PUT", body.RequestedAction, &body, func(update *XmppUpdate) bool {
+		for _, dialog := range update.dialogs() {
+			if dialog.ID != l.Primary.Id {
+				continue
+			}
+			p := dialog.participant(target)
+			if p != nil && p.State == DialogStateActive {
+				l.Primary.lastStatus = &dialog
+				return true
+			}
+		}
+		return false
+	}, "Dialog", l.Consult.Id)
+	return errOp
+}
+
+// Cancel drop consult dialog and retrieve primary dialog
+func (l *LinkedDialog) Cancel() OperationError {
+	return l.CancelCtx(context.Background())
+}
+
+// CancelCtx drop consult dialog and retrieve primary dialog, context cancel requests and wait for notifications
+func (l *LinkedDialog) CancelCtx(ctx context.Context) OperationError {
+	if errOp := l.Consult.DropCtx(ctx); errOp.Type != TypeErrorNoError {
+		return errOp
+	}
+	if _, err := l.Primary.GetStatusCtx(ctx); err != nil {
+		return OperationError{
+			Type:  TypeErrorNoStatus,
+			Error: err,
+		}
+	}
+	return l.Primary.RetrieveCtx(ctx)
+}
+
+func (l *LinkedDialog) String() string {
+	return fmt.Sprintf("%s <- %s", l.Primary, l.Consult)
+}
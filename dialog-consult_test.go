@@ -0,0 +1,83 @@
+package finesse_api_test
+
+import (
+	"testing"
+
+	api "github.com/pokornyIt/finesse-api"
+)
+
+func TestConsultTransfer(t *testing.T) {
+	mock := startMock(t)
+	mock.AddAgent("agent1", "1001", "password", "2001")
+	mock.AddAgent("agent2", "1002", "password", "2002")
+	agent1 := loginAgent(t, mock, "agent1", "2001")
+	agent2 := loginAgent(t, mock, "agent2", "2002")
+	primary := incomingDialog(t, mock, agent1, nil)
+
+	linked, errOp := primary.Consult("2002")
+	checkOperation(t, "consult", errOp)
+	if linked.Consult.AssociatedDialogId() != primary.Id {
+		t.Errorf("consult dialog is associated with [%s], expected [%s]", linked.Consult.AssociatedDialogId(), primary.Id)
+	}
+	checkParticipant(t, mock, "consult", primary.Id, "2001", api.DialogStateHeld)
+	checkParticipant(t, mock, "consult", linked.Consult.Id, "2002", api.DialogStateAlerting)
+	// transfer is possible after consulted agent answers
+	checkErrorType(t, "transfer before answer", linked.Transfer(), api.TypeErrorWrongState)
+	checkOperation(t, "answer consult", agentDialog(t, agent2, linked.Consult.Id).Answer())
+	if _, err := linked.Consult.GetStatus(); err != nil {
+		t.Fatalf("consult status: %s", err)
+	}
+
+	checkOperation(t, "transfer", linked.Transfer())
+	checkParticipant(t, mock, "transfer", primary.Id, "2001", api.DialogStateDropped)
+	checkParticipant(t, mock, "transfer", primary.Id, "2002", api.DialogStateActive)
+	checkParticipant(t, mock, "transfer", linked.Consult.Id, "2002", "")
+	if dialogs, err := agent1.GetDialogs(); err != nil || len(dialogs) != 0 {
+		t.Errorf("dialogs of transferring agent are %v (%v)", dialogs, err)
+	}
+	agentDialog(t, agent2, primary.Id)
+}
+
+func TestConsultConference(t *testing.T) {
+	mock := startMock(t)
+	mock.AddAgent("agent1", "1001", "password", "2001")
+	agent := loginAgent(t, mock, "agent1", "2001")
+	primary := incomingDialog(t, mock, agent, nil)
+
+	_, errOp := primary.Consult("5550200")
+	checkOperation(t, "consult", errOp)
+	linked, err := agent.LinkedDialogs()
+	if err != nil || len(linked) != 1 {
+		t.Fatalf("linked dialogs are %v (%v)", linked, err)
+	}
+	if linked[0].Primary.Id != primary.Id {
+		t.Errorf("primary dialog is [%s], expected [%s]", linked[0].Primary.Id, primary.Id)
+	}
+
+	checkOperation(t, "conference", linked[0].Conference())
+	checkParticipant(t, mock, "conference", primary.Id, "2001", api.DialogStateActive)
+	checkParticipant(t, mock, "conference", primary.Id, "5550200", api.DialogStateActive)
+	checkParticipant(t, mock, "conference", linked[0].Consult.Id, "5550200", "")
+
+	primary = linked[0].Primary
+	checkOperation(t, "drop participant", primary.DropParticipant("5550200"))
+	checkParticipant(t, mock, "drop participant", primary.Id, "5550200", api.DialogStateDropped)
+	checkParticipant(t, mock, "drop participant", primary.Id, "5550100", api.DialogStateActive)
+	checkErrorType(t, "drop last participant", primary.DropParticipant("5550100"), api.TypeErrorWrongState)
+}
+
+func TestConsultCancel(t *testing.T) {
+	mock := startMock(t)
+	mock.AddAgent("agent1", "1001", "password", "2001")
+	agent := loginAgent(t, mock, "agent1", "2001")
+	primary := incomingDialog(t, mock, agent, nil)
+
+	linked, errOp := primary.Consult("5550200")
+	checkOperation(t, "consult", errOp)
+	checkOperation(t, "cancel", linked.Cancel())
+	checkParticipant(t, mock, "cancel", primary.Id, "2001", api.DialogStateActive)
+	checkParticipant(t, mock, "cancel", linked.Consult.Id, "2001", "")
+	if dialogs, err := agent.LinkedDialogs(); err != nil || len(dialogs) != 0 {
+		t.Errorf("linked dialogs after cancel are %v (%v)", dialogs, err)
+	}
+}
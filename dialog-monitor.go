@@ -0,0 +1,301 @@
+package finesse_api
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MonitorStage stage of supervisor monitoring
+type MonitorStage int
+
+const (
+	MonitorStarted     MonitorStage = iota // MonitorStarted supervisor silently monitors agent call
+	MonitorBarged                          // MonitorBarged supervisor joined agent call
+	MonitorIntercepted                     // MonitorIntercepted supervisor took over call, agent was dropped
+	MonitorEnded                           // MonitorEnded monitoring or barge-in dialog ended
+)
+
+var monitorStageNames = map[MonitorStage]string{MonitorStarted: "Started", MonitorBarged: "Barged",
+	MonitorIntercepted: "Intercepted", MonitorEnded: "Ended"}
+
+func (m MonitorStage) String() string {
+	if n, ok := monitorStageNames[m]; ok {
+		return n
+	}
+	return "Unknown"
+}
+
+// MonitorEvent change of supervisor monitoring, published to supervisor subscribers
+type MonitorEvent struct {
+	Supervisor   *Agent       // Supervisor who monitors agent
+	Stage        MonitorStage // Stage actual stage
+	Dialog       *Dialog      // Dialog supervisor dialog (monitor or barge-in)
+	AgentAddress string       // AgentAddress extension of monitored agent
+	Time         time.Time    // Time of change
+}
+
+func (MonitorEvent) Type() EventType { return EventMonitor }
+
+// AgentDialogs get dialogs of team member, dialogs are usable for BargeIn
+func (s *Supervisor) AgentDialogs(loginId string) ([]*Dialog, error) {
+	return s.AgentDialogsCtx(context.Background(), loginId)
+}
+
+// AgentDialogsCtx get dialogs of team member, context cancel requests
+func (s *Supervisor) AgentDialogsCtx(ctx context.Context, loginId string) ([]*Dialog, error) {
+	user, err := s.agentStatus(ctx, loginId)
+	if err != nil {
+		return nil, err
+	}
+	dialogs, err := s.userDialogs(ctx, loginId)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range dialogs {
+		d.monitoredAddress = user.Extension
+		d.monitoredDialogId = d.Id
+	}
+	return dialogs, nil
+}
+
+// SilentMonitor start silent monitoring of team member active call
+//
+// Supervisor must be logged in and NOT_READY without other call, agent must have active call.
+func (s *Supervisor) SilentMonitor(loginId string) (*Dialog, OperationError) {
+	return s.SilentMonitorCtx(context.Background(), loginId)
+}
+
+// SilentMonitorCtx start silent monitoring of team member active call, context cancel requests and wait for notification
+func (s *Supervisor) SilentMonitorCtx(ctx context.Context, loginId string) (*Dialog, OperationError) {
+	if errOp := s.checkDevice(ctx); errOp.Type != TypeErrorNoError {
+		return nil, errOp
+	}
+	dialogs, err := s.AgentDialogsCtx(ctx, loginId)
+	if err != nil {
+		return nil, OperationError{
+			Type:  TypeErrorNoStatus,
+			Error: err,
+		}
+	}
+	var target *Dialog
+	for _, d := range dialogs {
+		if p := d.lastStatus.participant(d.monitoredAddress); p != nil && p.State == DialogStateActive {
+			target = d
+			break
+		}
+	}
+	if target == nil {
+		return nil, OperationError{
+			Type:  TypeErrorWrongState,
+			Error: fmt.Errorf("agent [%s] has no active call for silent monitor", loginId),
+		}
+	}
+	body := dialogActionRequest{
+		RequestedAction:    DialogActionSilentMonitor,
+		TargetMediaAddress: target.monitoredAddress,
+		MediaAddress:       s.Line,
+	}
+	d, errOp := s.startMonitorDialog(ctx, &body, target, MonitorStarted)
+	if errOp.Type != TypeErrorNoError {
+		return nil, errOp
+	}
+	return d, errOp
+}
+
+// BargeIn join agent call, dialog is supervisor monitor dialog (SilentMonitor) or agent dialog (AgentDialogs)
+func (s *Supervisor) BargeIn(dialog *Dialog) (*Dialog, OperationError) {
+	return s.BargeInCtx(context.Background(), dialog)
+}
+
+// BargeInCtx join agent call, context cancel requests and wait for notification
+func (s *Supervisor) BargeInCtx(ctx context.Context, dialog *Dialog) (*Dialog, OperationError) {
+	if len(dialog.monitoredAddress) == 0 {
+		return nil, OperationError{
+			Type:  TypeErrorRequest,
+			Error: fmt.Errorf("dialog [%s] is not monitored agent dialog", dialog.Id),
+		}
+	}
+	if s.lastStatus == nil || s.lastStatus.State == AgentStateLogout || len(s.Line) == 0 {
+		return nil, OperationError{
+			Type:  TypeErrorWrongState,
+			Error: fmt.Errorf("supervisor [%s] is not logged in with device", s.LoginName),
+		}
+	}
+	// agent call must be still active
+	target := newDialog(s.Agent, &XmppDialog{ID: dialog.monitoredDialogId})
+	if _, err := target.GetStatusCtx(ctx); err != nil {
+		return nil, OperationError{
+			Type:  TypeErrorNoStatus,
+			Error: err,
+		}
+	}
+	if p := target.lastStatus.participant(dialog.monitoredAddress); p == nil || p.State != DialogStateActive {
+		return nil, OperationError{
+			Type:  TypeErrorWrongState,
+			Error: fmt.Errorf("agent [%s] is not active in dialog [%s]", dialog.monitoredAddress, target.Id),
+		}
+	}
+	target.monitoredAddress = dialog.monitoredAddress
+	target.monitoredDialogId = dialog.monitoredDialogId
+	body := dialogActionRequest{
+		RequestedAction:  DialogActionBargeCall,
+		FromAddress:      s.Line,
+		ToAddress:        dialog.monitoredAddress,
+		AssociatedDialog: s.getServer().apiPath("Dialog", target.Id),
+	}
+	return s.startMonitorDialog(ctx, &body, target, MonitorBarged)
+}
+
+// Intercept take over barged call, agent is dropped from call and supervisor stays with customer
+func (s *Supervisor) Intercept(dialog *Dialog) OperationError {
+	return s.InterceptCtx(context.Background(), dialog)
+}
+
+// InterceptCtx take over barged call, context cancel request and wait for notification
+func (s *Supervisor) InterceptCtx(ctx context.Context, dialog *Dialog) OperationError {
+	if len(dialog.monitoredAddress) == 0 || dialog.agent != s.Agent {
+		return OperationError{
+			Type:  TypeErrorRequest,
+			Error: fmt.Errorf("dialog [%s] is not barge-in dialog of supervisor [%s]", dialog.Id, s.LoginName),
+		}
+	}
+	if p := dialog.participant(); p == nil || p.State != DialogStateActive {
+		return OperationError{
+			Type:  TypeErrorWrongState,
+			Error: fmt.Errorf("supervisor [%s] is not active in dialog [%s]", s.LoginName, dialog.Id),
+		}
+	}
+	// agent must be still active in call
+	agentDialog := newDialog(s.Agent, &XmppDialog{ID: dialog.monitoredDialogId})
+	if _, err := agentDialog.GetStatusCtx(ctx); err != nil {
+		return OperationError{
+			Type:  TypeErrorNoStatus,
+			Error: err,
+		}
+	}
+	if p := agentDialog.lastStatus.participant(dialog.monitoredAddress); p == nil || p.State != DialogStateActive {
+		return OperationError{
+			Type:  TypeErrorWrongState,
+			Error: fmt.Errorf("agent [%s] is not active in dialog [%s]", dialog.monitoredAddress, agentDialog.Id),
+		}
+	}
+	errOp := dialog.DropParticipantCtx(ctx, dialog.monitoredAddress)
+	if errOp.Type == TypeErrorNoError {
+		s.publishMonitor(MonitorIntercepted, dialog)
+	}
+	return errOp
+}
+
+// checkDevice supervisor is logged in, NOT_READY and without live call on device
+func (s *Supervisor) checkDevice(ctx context.Context) OperationError {
+	if s.lastStatus == nil || s.lastStatus.State != AgentStateNotReady || len(s.Line) == 0 {
+		state := "UNKNOWN"
+		if s.lastStatus != nil {
+			state = s.lastStatus.State
+		}
+		return OperationError{
+			Type:  TypeErrorWrongState,
+			Error: fmt.Errorf("supervisor [%s] is in [%s] state and not possible start monitoring", s.LoginName, state),
+		}
+	}
+	dialogs, err := s.GetDialogsCtx(ctx)
+	if err != nil {
+		return OperationError{
+			Type:  TypeErrorNoStatus,
+			Error: err,
+		}
+	}
+	for _, d := range dialogs {
+		if p := d.participant(); p != nil {
+			if _, ok := DialogLiveStates[p.State]; ok {
+				return OperationError{
+					Type:  TypeErrorWrongState,
+					Error: fmt.Errorf("supervisor [%s] device [%s] is busy with dialog [%s]", s.LoginName, s.Line, d.Id),
+				}
+			}
+		}
+	}
+	return OperationError{
+		Type:  TypeErrorNoError,
+		Error: nil,
+	}
+}
+
+// startMonitorDialog create supervisor dialog to agent call and watch it until end
+func (s *Supervisor) startMonitorDialog(ctx context.Context, body *dialogActionRequest, target *Dialog, stage MonitorStage) (*Dialog, OperationError) {
+	// subscribe before request, dialog can end before watch starts
+	sub := s.Subscribe(EventTypes(EventDialog), DeliveryLossless)
+	var created *XmppDialog
+	_, errOp := s.doDialogRequest(ctx, "POST", body.RequestedAction, body, func(update *XmppUpdate) bool {
+		for _, dialog := range update.dialogs() {
+			p := dialog.participant(s.Line)
+			if p == nil || dialog.participant(target.monitoredAddress) == nil {
+				continue
+			}
+			if _, ok := DialogLiveStates[p.State]; ok && (stage != MonitorBarged || p.State == DialogStateActive) {
+				created = &dialog
+				return true
+			}
+		}
+		return false
+	}, "User", s.LoginId, "Dialogs")
+	if errOp.Type != TypeErrorNoError {
+		sub.Close()
+		return nil, errOp
+	}
+	d := newDialog(s.Agent, created)
+	d.monitoredAddress = target.monitoredAddress
+	d.monitoredDialogId = target.monitoredDialogId
+	s.publishMonitor(stage, d)
+	go s.watchMonitor(sub, d)
+	return d, errOp
+}
+
+// watchMonitor publish MonitorEnded when supervisor dialog is deleted or supervisor is dropped
+func (s *Supervisor) watchMonitor(sub *Subscription, d *Dialog) {
+	defer sub.Close()
+	for e := range sub.C {
+		de, ok := e.(DialogEvent)
+		if !ok {
+			continue
+		}
+		for _, dialog := range de.Dialogs {
+			if dialog.ID != d.Id {
+				continue
+			}
+			p := dialog.participant(s.Line)
+			_, ended := DialogEndStates[dialog.State]
+			if strings.EqualFold(de.Operation, "DELETE") || ended || p == nil || p.State == DialogStateDropped {
+				s.withFields(Fields{logProc: "watchMonitor", logAgent: s.LoginName}).
+					Debugf("supervisor [%s] monitoring dialog [%s] ended", s.LoginName, d.Id)
+				s.publishMonitor(MonitorEnded, d)
+				return
+			}
+		}
+	}
+}
+
+func (s *Supervisor) publishMonitor(stage MonitorStage, d *Dialog) {
+	s.events.publish(MonitorEvent{
+		Supervisor:   s.Agent,
+		Stage:        stage,
+		Dialog:       d,
+		AgentAddress: d.monitoredAddress,
+		Time:         time.Now(),
+	})
+}
+
+// agentStatus get actual status of team member
+func (s *Supervisor) agentStatus(ctx context.Context, loginId string) (*XmppUser, error) {
+	request := s.newAgentRequest()
+	response := request.doRequestCtx(ctx, "GET", request.server.urlString(request.id, "User", loginId), nil)
+	defer response.close()
+	msg, err := response.responseError()
+	if err != nil {
+		s.withFields(Fields{logProc: "agentStatus", logId: response.id, logAgent: s.LoginName}).Error(msg)
+		return nil, err
+	}
+	return newXmppUser(response.GetResponseBody())
+}
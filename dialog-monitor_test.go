@@ -0,0 +1,80 @@
+package finesse_api_test
+
+import (
+	"testing"
+	"time"
+
+	api "github.com/pokornyIt/finesse-api"
+)
+
+// nextMonitorStages wait for n monitor events, returns dialogs by stage
+func nextMonitorStages(t *testing.T, sub *api.Subscription, n int) map[api.MonitorStage]*api.Dialog {
+	t.Helper()
+	stages := make(map[api.MonitorStage]*api.Dialog)
+	timeout := time.After(2 * time.Second)
+	for len(stages) < n {
+		select {
+		case e := <-sub.C:
+			if me, ok := e.(api.MonitorEvent); ok {
+				stages[me.Stage] = me.Dialog
+			}
+		case <-timeout:
+			t.Fatalf("received monitor stages %v, expected [%d] stages", stages, n)
+		}
+	}
+	return stages
+}
+
+func TestSupervisorMonitor(t *testing.T) {
+	mock := startMock(t)
+	mock.AddSupervisor("super", "1000", "password", "2000")
+	mock.AddAgent("agent1", "1001", "password", "2001")
+	supervisor, err := api.NewSupervisor(loginAgent(t, mock, "super", "2000"))
+	if err != nil {
+		t.Fatalf("create supervisor: %s", err)
+	}
+	agent := loginAgent(t, mock, "agent1", "2001")
+	sub := supervisor.Subscribe(api.EventTypes(api.EventMonitor), api.DeliveryLossless)
+	defer sub.Close()
+
+	_, errOp := supervisor.SilentMonitor("1001")
+	checkErrorType(t, "monitor agent without call", errOp, api.TypeErrorWrongState)
+	call := incomingDialog(t, mock, agent, nil)
+
+	monitor, errOp := supervisor.SilentMonitor("1001")
+	checkOperation(t, "silent monitor", errOp)
+	if stages := nextMonitorStages(t, sub, 1); stages[api.MonitorStarted] == nil {
+		t.Errorf("monitor stages are %v", stages)
+	}
+	checkParticipant(t, mock, "silent monitor", monitor.Id, "2000", api.DialogStateActive)
+	if dialogs, err := agent.GetDialogs(); err != nil || len(dialogs) != 1 {
+		t.Errorf("silent monitor is visible for agent, dialogs %v (%v)", dialogs, err)
+	}
+
+	// barge-in ends silent monitoring
+	barged, errOp := supervisor.BargeIn(monitor)
+	checkOperation(t, "barge-in", errOp)
+	stages := nextMonitorStages(t, sub, 2)
+	if stages[api.MonitorBarged] == nil || stages[api.MonitorEnded] == nil || stages[api.MonitorEnded].Id != monitor.Id {
+		t.Errorf("barge-in stages are %v", stages)
+	}
+	checkParticipant(t, mock, "barge-in", monitor.Id, "2000", "")
+	checkParticipant(t, mock, "barge-in", call.Id, "2000", api.DialogStateActive)
+
+	checkOperation(t, "intercept", supervisor.Intercept(barged))
+	if stages = nextMonitorStages(t, sub, 1); stages[api.MonitorIntercepted] == nil {
+		t.Errorf("intercept stages are %v", stages)
+	}
+	checkParticipant(t, mock, "intercept", call.Id, "2001", api.DialogStateDropped)
+	checkParticipant(t, mock, "intercept", call.Id, "5550100", api.DialogStateActive)
+	if dialogs, err := agent.GetDialogs(); err != nil || len(dialogs) != 0 {
+		t.Errorf("dialogs of intercepted agent are %v (%v)", dialogs, err)
+	}
+
+	if err = mock.EndDialog(call.Id); err != nil {
+		t.Fatalf("end dialog: %s", err)
+	}
+	if stages = nextMonitorStages(t, sub, 1); stages[api.MonitorEnded] == nil {
+		t.Errorf("end of call stages are %v", stages)
+	}
+}
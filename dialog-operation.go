@@ -0,0 +1,322 @@
+package finesse_api
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// MakeCall create new call from agent line to destination address, agent must be in NOT_READY state
+func (a *Agent) MakeCall(toAddress string) (*Dialog, OperationError) {
+	return a.MakeCallCtx(context.Background(), toAddress)
+}
+
+// MakeCallCtx create new call, context cancel request and wait for notification
+func (a *Agent) MakeCallCtx(ctx context.Context, toAddress string) (*Dialog, OperationError) {
+	if a.lastStatus == nil || a.lastStatus.State != AgentStateNotReady {
+		state := AgentStateUnknown
+		if a.lastStatus != nil {
+			state = a.lastStatus.State
+		}
+		return nil, OperationError{
+			Type:  TypeErrorWrongState,
+			Error: fmt.Errorf("agent [%s] is in [%s] state and not possible make call", a.LoginName, state),
+		}
+	}
+	body := dialogActionRequest{
+		RequestedAction: DialogActionMakeCall,
+		FromAddress:     a.Line,
+		ToAddress:       toAddress,
+	}
+	var created *XmppDialog
+	_, errOp := a.doDialogRequest(ctx, "POST", body.RequestedAction, &body, func(update *XmppUpdate) bool {
+		if !strings.EqualFold(update.Event, "POST") {
+			return false
+		}
+		for _, dialog := range update.dialogs() {
+			if _, ok := DialogLiveStates[dialog.State]; ok && dialog.FromAddress == a.Line {
+				created = &dialog
+				return true
+			}
+		}
+		return false
+	}, "User", a.LoginId, "Dialogs")
+	if errOp.Type != TypeErrorNoError {
+		return nil, errOp
+	}
+	return newDialog(a, created), errOp
+}
+
+// Answer answer alerting dialog
+func (d *Dialog) Answer() OperationError {
+	return d.AnswerCtx(context.Background())
+}
+
+// AnswerCtx answer alerting dialog, context cancel request and wait for notification
+func (d *Dialog) AnswerCtx(ctx context.Context) OperationError {
+	return d.doAction(ctx, DialogActionAnswer, DialogStateActive)
+}
+
+// Hold put active dialog on hold
+func (d *Dialog) Hold() OperationError {
+	return d.HoldCtx(context.Background())
+}
+
+// HoldCtx put active dialog on hold, context cancel request and wait for notification
+func (d *Dialog) HoldCtx(ctx context.Context) OperationError {
+	return d.doAction(ctx, DialogActionHold, DialogStateHeld)
+}
+
+// Retrieve retrieve held dialog
+func (d *Dialog) Retrieve() OperationError {
+	return d.RetrieveCtx(context.Background())
+}
+
+// RetrieveCtx retrieve held dialog, context cancel request and wait for notification
+func (d *Dialog) RetrieveCtx(ctx context.Context) OperationError {
+	return d.doAction(ctx, DialogActionRetrieve, DialogStateActive)
+}
+
+// Drop drop agent from dialog
+func (d *Dialog) Drop() OperationError {
+	return d.DropCtx(context.Background())
+}
+
+// DropCtx drop agent from dialog, context cancel request and wait for notification
+func (d *Dialog) DropCtx(ctx context.Context) OperationError {
+	return d.doAction(ctx, DialogActionDrop, DialogStateDropped, DialogStateWrapUp, DialogStateFailed)
+}
+
+// TransferSst single step (blind) transfer of dialog to destination address
+func (d *Dialog) TransferSst(toAddress string) OperationError {
+	return d.TransferSstCtx(context.Background(), toAddress)
+}
+
+// TransferSstCtx single step transfer of dialog, context cancel request and wait for notification
+func (d *Dialog) TransferSstCtx(ctx context.Context, toAddress string) OperationError {
+	if errOp := d.checkAction(DialogActionTransferSst); errOp.Type != TypeErrorNoError {
+		return errOp
+	}
+	body := dialogActionRequest{
+		RequestedAction:    DialogActionTransferSst,
+		ToAddress:          toAddress,
+		TargetMediaAddress: d.agent.Line,
+	}
+	_, errOp := d.agent.doDialogRequest(ctx, "PUT", body.RequestedAction, &body, d.participantStateMatcher(DialogStateDropped, DialogStateWrapUp), "Dialog", d.Id)
+	return errOp
+}
+
+// DropParticipant drop other participant (by media address) from conference dialog
+func (d *Dialog) DropParticipant(mediaAddress string) OperationError {
+	return d.DropParticipantCtx(context.Background(), mediaAddress)
+}
+
+// DropParticipantCtx drop other participant from conference dialog, context cancel request and wait for notification
+func (d *Dialog) DropParticipantCtx(ctx context.Context, mediaAddress string) OperationError {
+	if errOp := d.checkAction(DialogActionParticipantDrop); errOp.Type != TypeErrorNoError {
+		return errOp
+	}
+	body := dialogActionRequest{
+		RequestedAction:    DialogActionParticipantDrop,
+		TargetMediaAddress: mediaAddress,
+	}
+	_, errOp := d.agent.doDialogRequest(ctx, "PUT", body.RequestedAction, &body, func(update *XmppUpdate) bool {
+		for _, dialog := range update.dialogs() {
+			if dialog.ID != d.Id {
+				continue
+			}
+			p := dialog.participant(mediaAddress)
+			if p == nil || p.State == DialogStateDropped {
+				d.lastStatus = &dialog
+				return true
+			}
+		}
+		return false
+	}, "Dialog", d.Id)
+	return errOp
+}
+
+// SetCallVariables update call or ECC variables (name "user.xxx") of dialog
+func (d *Dialog) SetCallVariables(variables map[string]string) OperationError {
+	return d.UpdateCallDataCtx(context.Background(), variables, "")
+}
+
+// SetCallVariablesCtx update call or ECC variables of dialog, context cancel request and wait for notification
+func (d *Dialog) SetCallVariablesCtx(ctx context.Context, variables map[string]string) OperationError {
+	return d.UpdateCallDataCtx(ctx, variables, "")
+}
+
+// SetWrapUpReason set wrap-up reason label for dialog
+func (d *Dialog) SetWrapUpReason(reason string) OperationError {
+	return d.UpdateCallDataCtx(context.Background(), nil, reason)
+}
+
+// SetWrapUpReasonCtx set wrap-up reason label for dialog, context cancel request and wait for notification
+func (d *Dialog) SetWrapUpReasonCtx(ctx context.Context, reason string) OperationError {
+	return d.UpdateCallDataCtx(ctx, nil, reason)
+}
+
+// UpdateCallData update call or ECC variables and wrap-up reason of dialog
+//
+// Variables must exist in latest dialog status, wrapUpReason must be label from Server.WrapUpReasons,
+// empty wrapUpReason is not changed.
+func (d *Dialog) UpdateCallData(variables map[string]string, wrapUpReason string) OperationError {
+	return d.UpdateCallDataCtx(context.Background(), variables, wrapUpReason)
+}
+
+// UpdateCallDataCtx update call variables and wrap-up reason of dialog, context cancel request and wait for notification
+func (d *Dialog) UpdateCallDataCtx(ctx context.Context, variables map[string]string, wrapUpReason string) OperationError {
+	if len(variables) == 0 && len(wrapUpReason) == 0 {
+		return OperationError{
+			Type:  TypeErrorRequest,
+			Error: fmt.Errorf("no call data for update dialog [%s]", d.Id),
+		}
+	}
+	if errOp := d.checkAction(DialogActionUpdateCallData); errOp.Type != TypeErrorNoError {
+		return errOp
+	}
+	names := make([]string, 0, len(variables))
+	for name := range variables {
+		if _, ok := d.lastStatus.callVariable(name); !ok {
+			return OperationError{
+				Type:  TypeErrorRequest,
+				Error: fmt.Errorf("call variable [%s] not exists in dialog [%s]", name, d.Id),
+			}
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(wrapUpReason) > 0 {
+		reason, err := d.agent.getServer().WrapUpReason(d.agent, wrapUpReason)
+		if err != nil {
+			return OperationError{Type: TypeErrorRequest, Error: err}
+		}
+		wrapUpReason = reason.Label
+	}
+
+	body := dialogCallDataRequest{RequestedAction: DialogActionUpdateCallData}
+	body.MediaProperties.WrapUpReason = wrapUpReason
+	if len(names) > 0 {
+		body.MediaProperties.CallVariables = &callVariablesRequest{}
+		for _, name := range names {
+			body.MediaProperties.CallVariables.CallVariable = append(body.MediaProperties.CallVariables.CallVariable,
+				XmppCallVariable{Name: name, Value: variables[name]})
+		}
+	}
+	_, errOp := d.agent.doDialogRequest(ctx, "PUT", body.RequestedAction, &body, func(update *XmppUpdate) bool {
+		for _, dialog := range update.dialogs() {
+			if dialog.ID != d.Id {
+				continue
+			}
+			if len(wrapUpReason) > 0 && dialog.MediaProperties.WrapUpReason != wrapUpReason {
+				continue
+			}
+			updated := true
+			for _, name := range names {
+				if v, _ := dialog.callVariable(name); v != variables[name] {
+					updated = false
+				}
+			}
+			if updated {
+				d.lastStatus = &dialog
+				return true
+			}
+		}
+		return false
+	}, "Dialog", d.Id)
+	return errOp
+}
+
+// doAction send requested action for agent participant and wait until participant is in one of confirmed states
+func (d *Dialog) doAction(ctx context.Context, action string, confirmStates ...string) OperationError {
+	if errOp := d.checkAction(action); errOp.Type != TypeErrorNoError {
+		return errOp
+	}
+	body := dialogActionRequest{
+		RequestedAction:    action,
+		TargetMediaAddress: d.agent.Line,
+	}
+	_, errOp := d.agent.doDialogRequest(ctx, "PUT", body.RequestedAction, &body, d.participantStateMatcher(confirmStates...), "Dialog", d.Id)
+	return errOp
+}
+
+// checkAction verify agent is participant of dialog and action is allowed for him
+func (d *Dialog) checkAction(action string) OperationError {
+	p := d.participant()
+	if p == nil {
+		return OperationError{
+			Type:  TypeErrorWrongState,
+			Error: fmt.Errorf("agent [%s] is not participant of dialog [%s]", d.agent.LoginName, d.Id),
+		}
+	}
+	if !p.allowAction(action) {
+		return OperationError{
+			Type:  TypeErrorWrongState,
+			Error: fmt.Errorf("agent [%s] is in [%s] state in dialog [%s] and action [%s] is not allowed", d.agent.LoginName, p.State, d.Id, action),
+		}
+	}
+	return OperationError{
+		Type:  TypeErrorNoError,
+		Error: nil,
+	}
+}
+
+// participantStateMatcher accept dialog notification when agent participant is in one of required states or dialog is deleted
+func (d *Dialog) participantStateMatcher(states ...string) notifyMatcher {
+	return func(update *XmppUpdate) bool {
+		for _, dialog := range update.dialogs() {
+			if dialog.ID != d.Id {
+				continue
+			}
+			if strings.EqualFold(update.Event, "DELETE") {
+				d.lastStatus = &dialog
+				return true
+			}
+			p := dialog.participant(d.agent.Line)
+			if p == nil {
+				continue
+			}
+			for _, s := range states {
+				if p.State == s {
+					d.lastStatus = &dialog
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+// doDialogRequest send dialog request and wait for XMPP confirmation accepted by matcher
+func (a *Agent) doDialogRequest(ctx context.Context, method string, action string, body dialogRequest, match notifyMatcher, pathPart ...string) (*XmppUpdate, OperationError) {
+	request := a.newAgentRequest()
+	requestBody, err := body.getDialogRequest()
+	if err != nil {
+		a.withFields(Fields{logProc: "doDialogRequest", logId: request.id, logAgent: a.LoginName, logRequestType: action}).
+			Errorf("prepare dialog action [%s] for agent [%s]. Problem is %s", action, a.LoginName, err)
+		return nil, OperationError{
+			Type:  TypeErrorRequest,
+			Error: err,
+		}
+	}
+	if errOp := a.checkConnected(request.id); errOp.Type != TypeErrorNoError {
+		return nil, errOp
+	}
+	w := a.expectNotify(request.id, request.server.apiPath(pathPart...), match)
+	defer a.dropNotify(w)
+
+	response := request.doRequestCtx(ctx, method, request.server.urlString(request.id, pathPart...), requestBody)
+	defer response.close()
+	msg, err := response.responseError()
+	if err != nil {
+		a.withFields(Fields{logProc: "doDialogRequest", logId: response.id, logAgent: a.LoginName, logRequestType: action}).Error(msg)
+		return nil, OperationError{
+			Type:  TypeErrorResponse,
+			Error: err,
+		}
+	}
+	a.withFields(Fields{logProc: "doDialogRequest", logId: response.id, logAgent: a.LoginName, logRequestType: action}).
+		Tracef("agent [%s] dialog action [%s] request", a.LoginName, action)
+	return a.awaitNotify(ctx, w)
+}
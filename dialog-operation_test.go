@@ -0,0 +1,190 @@
+package finesse_api_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	api "github.com/pokornyIt/finesse-api"
+	"github.com/pokornyIt/finesse-api/finessetest"
+)
+
+func TestMakeCallWithoutStatus(t *testing.T) {
+	server := api.NewServer("finesse.example.com", true)
+	server.SetLogger(api.NewNopLogger())
+	agent := api.NewAgentNotify(context.Background(), "agent1", "password", "2001", server)
+
+	dialog, errOp := agent.MakeCall("2002")
+	if dialog != nil {
+		t.Errorf("dialog created for agent without status")
+	}
+	if errOp.Type != api.TypeErrorWrongState {
+		t.Fatalf("error type is [%d], expected [%d]: %s", errOp.Type, api.TypeErrorWrongState, errOp.Error)
+	}
+	if errOp.Error == nil {
+		t.Errorf("missing error description")
+	}
+}
+
+// loginAgent create agent connected to mock and login with line, agent is NOT_READY
+func loginAgent(t *testing.T, mock *finessetest.Server, name string, line string) *api.Agent {
+	t.Helper()
+	agent := mockAgent(t, mock, name, line)
+	checkOperation(t, "login "+name, agent.Login())
+	return agent
+}
+
+// incomingDialog create incoming call from customer to agent and answer it
+func incomingDialog(t *testing.T, mock *finessetest.Server, agent *api.Agent, variables map[string]string) *api.Dialog {
+	t.Helper()
+	d := mock.AddDialog("5550100", agent.Line, variables)
+	dialog := agentDialog(t, agent, d.Id)
+	checkOperation(t, "answer", dialog.Answer())
+	return dialog
+}
+
+// agentDialog find actual dialog of agent by ID
+func agentDialog(t *testing.T, agent *api.Agent, id string) *api.Dialog {
+	t.Helper()
+	dialogs, err := agent.GetDialogs()
+	if err != nil {
+		t.Fatalf("dialogs of [%s]: %s", agent.LoginName, err)
+	}
+	for _, d := range dialogs {
+		if d.Id == id {
+			return d
+		}
+	}
+	t.Fatalf("agent [%s] has no dialog [%s] in %v", agent.LoginName, id, dialogs)
+	return nil
+}
+
+// checkErrorType operation ends with error type
+func checkErrorType(t *testing.T, name string, errOp api.OperationError, errorType int) {
+	t.Helper()
+	if errOp.Type != errorType {
+		t.Errorf("%s: error type is [%d], expected [%d]: %v", name, errOp.Type, errorType, errOp.Error)
+	}
+}
+
+// checkParticipant participant state on mock, empty state for ended dialog
+func checkParticipant(t *testing.T, mock *finessetest.Server, name string, dialogId string, address string, state string) {
+	t.Helper()
+	if s := mock.ParticipantState(dialogId, address); s != state {
+		t.Errorf("%s: participant [%s] of dialog [%s] is in [%s] state, expected [%s]", name, address, dialogId, s, state)
+	}
+}
+
+// nextDialogEvent wait for dialog event with operation for dialog
+func nextDialogEvent(t *testing.T, sub *api.Subscription, operation string, dialogId string) api.DialogEvent {
+	t.Helper()
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case e := <-sub.C:
+			de, ok := e.(api.DialogEvent)
+			if !ok || de.Operation != operation {
+				continue
+			}
+			for _, d := range de.Dialogs {
+				if d.ID == dialogId {
+					return de
+				}
+			}
+		case <-timeout:
+			t.Fatalf("dialog [%s] event [%s] not received", dialogId, operation)
+		}
+	}
+}
+
+func TestMakeCall(t *testing.T) {
+	mock := startMock(t)
+	mock.AddAgent("agent1", "1001", "password", "2001")
+	agent := loginAgent(t, mock, "agent1", "2001")
+
+	dialog, errOp := agent.MakeCall("5550100")
+	checkOperation(t, "make call", errOp)
+	checkParticipant(t, mock, "make call", dialog.Id, "2001", api.DialogStateActive)
+	checkParticipant(t, mock, "make call", dialog.Id, "5550100", api.DialogStateActive)
+
+	checkOperation(t, "hold", dialog.Hold())
+	checkParticipant(t, mock, "hold", dialog.Id, "2001", api.DialogStateHeld)
+	checkErrorType(t, "hold of held dialog", dialog.Hold(), api.TypeErrorWrongState)
+	checkOperation(t, "retrieve", dialog.Retrieve())
+	checkParticipant(t, mock, "retrieve", dialog.Id, "2001", api.DialogStateActive)
+	checkErrorType(t, "unknown call variable", dialog.SetCallVariables(map[string]string{"callVariable1": "x"}), api.TypeErrorRequest)
+
+	checkOperation(t, "drop", dialog.Drop())
+	checkParticipant(t, mock, "drop", dialog.Id, "2001", "")
+	if dialogs, err := agent.GetDialogs(); err != nil || len(dialogs) != 0 {
+		t.Errorf("dialogs after drop are %v (%v)", dialogs, err)
+	}
+}
+
+func TestIncomingDialog(t *testing.T) {
+	mock := startMock(t)
+	mock.AddAgent("agent1", "1001", "password", "2001")
+	agent := loginAgent(t, mock, "agent1", "2001")
+	sub := agent.Subscribe(api.EventTypes(api.EventDialog), api.DeliveryLossless)
+	defer sub.Close()
+
+	d := mock.AddDialog("5550100", "2001", map[string]string{"callVariable1": "case 42"})
+	e := nextDialogEvent(t, sub, "POST", d.Id)
+	if state := e.Dialogs[0].State; state != api.DialogStateAlerting {
+		t.Errorf("new dialog is in [%s] state, expected [%s]", state, api.DialogStateAlerting)
+	}
+	dialog := agentDialog(t, agent, d.Id)
+	checkErrorType(t, "hold of alerting dialog", dialog.Hold(), api.TypeErrorWrongState)
+	checkOperation(t, "answer", dialog.Answer())
+	if v, _ := dialog.CallVariable("callVariable1"); v != "case 42" {
+		t.Errorf("call variable is [%s]", v)
+	}
+
+	checkOperation(t, "update call data", dialog.UpdateCallData(map[string]string{"callVariable1": "case 43"}, "Sale"))
+	variables, reason, _ := mock.CallData(d.Id)
+	if variables["callVariable1"] != "case 43" || reason != "Sale" {
+		t.Errorf("call data on mock are %v, wrap-up reason [%s]", variables, reason)
+	}
+	if v, _ := dialog.CallVariable("callVariable1"); v != "case 43" {
+		t.Errorf("updated call variable is [%s]", v)
+	}
+	checkErrorType(t, "unknown wrap-up reason", dialog.SetWrapUpReason("Unknown"), api.TypeErrorRequest)
+
+	// call is held by other desktop, server rejects action allowed by latest status
+	if err := mock.SetParticipantState(d.Id, "2001", api.DialogStateHeld); err != nil {
+		t.Fatalf("hold on mock: %s", err)
+	}
+	errOp := dialog.Hold()
+	checkErrorType(t, "hold of held dialog", errOp, api.TypeErrorResponse)
+	if !errors.Is(errOp.Error, api.ErrInvalidState) {
+		t.Errorf("rejected hold: error [%v] is not ErrInvalidState", errOp.Error)
+	}
+
+	// customer hangs up
+	if err := mock.EndDialog(d.Id); err != nil {
+		t.Fatalf("end dialog: %s", err)
+	}
+	nextDialogEvent(t, sub, "DELETE", d.Id)
+	if err := mock.EndDialog(d.Id); err == nil {
+		t.Errorf("ended dialog can be ended again")
+	}
+}
+
+func TestTransferSst(t *testing.T) {
+	mock := startMock(t)
+	mock.AddAgent("agent1", "1001", "password", "2001")
+	mock.AddAgent("agent2", "1002", "password", "2002")
+	agent1 := loginAgent(t, mock, "agent1", "2001")
+	agent2 := loginAgent(t, mock, "agent2", "2002")
+	dialog := incomingDialog(t, mock, agent1, nil)
+
+	checkOperation(t, "transfer", dialog.TransferSst("2002"))
+	checkParticipant(t, mock, "transfer", dialog.Id, "2001", api.DialogStateDropped)
+	checkParticipant(t, mock, "transfer", dialog.Id, "2002", api.DialogStateAlerting)
+	if dialogs, err := agent1.GetDialogs(); err != nil || len(dialogs) != 0 {
+		t.Errorf("dialogs of transferring agent are %v (%v)", dialogs, err)
+	}
+	checkOperation(t, "answer transferred call", agentDialog(t, agent2, dialog.Id).Answer())
+	checkParticipant(t, mock, "answer transferred call", dialog.Id, "2002", api.DialogStateActive)
+}
@@ -0,0 +1,51 @@
+package finesse_api
+
+import (
+	"encoding/xml"
+)
+
+// dialogActionRequest structure for dialog operations (make call, answer, hold, ...)
+type dialogActionRequest struct {
+	XMLName            xml.Name `xml:"Dialog"`
+	RequestedAction    string   `xml:"requestedAction"`
+	FromAddress        string   `xml:"fromAddress,omitempty"`
+	ToAddress          string   `xml:"toAddress,omitempty"`
+	TargetMediaAddress string   `xml:"targetMediaAddress,omitempty"`
+	MediaAddress       string   `xml:"mediaAddress,omitempty"`
+	AssociatedDialog   string   `xml:"associatedDialogUri,omitempty"`
+}
+
+// dialogCallDataRequest structure for update call variables and wrap-up reason
+type dialogCallDataRequest struct {
+	XMLName         xml.Name `xml:"Dialog"`
+	RequestedAction string   `xml:"requestedAction"`
+	MediaProperties struct {
+		WrapUpReason  string                `xml:"wrapUpReason,omitempty"`
+		CallVariables *callVariablesRequest `xml:"callvariables,omitempty"`
+	} `xml:"mediaProperties"`
+}
+
+// callVariablesRequest list of call variables for update
+type callVariablesRequest struct {
+	CallVariable []XmppCallVariable `xml:"CallVariable"`
+}
+
+type dialogRequest interface {
+	getDialogRequest() ([]byte, error)
+}
+
+func (d *dialogActionRequest) getDialogRequest() ([]byte, error) {
+	data, err := xml.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (d *dialogCallDataRequest) getDialogRequest() ([]byte, error) {
+	data, err := xml.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
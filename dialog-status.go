@@ -0,0 +1,38 @@
+package finesse_api
+
+const (
+	DialogStateInitiating = "INITIATING"
+	DialogStateInitiated  = "INITIATED"
+	DialogStateAlerting   = "ALERTING"
+	DialogStateActive     = "ACTIVE"
+	DialogStateHeld       = "HELD"
+	DialogStateFailed     = "FAILED"
+	DialogStateDropped    = "DROPPED"
+	DialogStateWrapUp     = "WRAP_UP"
+)
+
+const (
+	DialogActionMakeCall = "MAKE_CALL"
+	DialogActionAnswer   = "ANSWER"
+	DialogActionHold     = "HOLD"
+	DialogActionRetrieve = "RETRIEVE"
+	DialogActionDrop     = "DROP"
+
+	DialogActionConsultCall     = "CONSULT_CALL"
+	DialogActionTransfer        = "TRANSFER"
+	DialogActionTransferSst     = "TRANSFER_SST"
+	DialogActionConference      = "CONFERENCE"
+	DialogActionParticipantDrop = "PARTICIPANT_DROP"
+	DialogActionUpdateCallData  = "UPDATE_CALL_DATA"
+
+	DialogActionSilentMonitor = "SILENT_MONITOR"
+	DialogActionBargeCall     = "BARGE_CALL"
+)
+
+// DialogLiveStates States when dialog is not finished
+var DialogLiveStates = map[string]string{DialogStateInitiating: DialogStateInitiating, DialogStateInitiated: DialogStateInitiated,
+	DialogStateAlerting: DialogStateAlerting, DialogStateActive: DialogStateActive, DialogStateHeld: DialogStateHeld}
+
+// DialogEndStates States when dialog or participant is finished
+var DialogEndStates = map[string]string{DialogStateFailed: DialogStateFailed, DialogStateDropped: DialogStateDropped,
+	DialogStateWrapUp: DialogStateWrapUp}
@@ -0,0 +1,130 @@
+package finesse_api
+
+import (
+	"context"
+	"fmt"
+)
+
+// Dialog one call (dialog) controlled by agent
+type Dialog struct {
+	Id         string      // dialog ID
+	agent      *Agent      // agent who control the dialog
+	lastStatus *XmppDialog // latest dialog response
+
+	monitoredAddress  string // monitoredAddress extension of agent monitored by supervisor
+	monitoredDialogId string // monitoredDialogId ID of agent dialog monitored by supervisor
+}
+
+func newDialog(agent *Agent, data *XmppDialog) *Dialog {
+	return &Dialog{
+		Id:         data.ID,
+		agent:      agent,
+		lastStatus: data,
+	}
+}
+
+// GetDialogs get actual agent dialogs from finesse server
+func (a *Agent) GetDialogs() ([]*Dialog, error) {
+	return a.GetDialogsCtx(context.Background())
+}
+
+// GetDialogsCtx get actual agent dialogs from finesse server, context cancel request
+func (a *Agent) GetDialogsCtx(ctx context.Context) ([]*Dialog, error) {
+	return a.userDialogs(ctx, a.LoginId)
+}
+
+// userDialogs get dialogs of user, supervisor can read dialogs of team members
+func (a *Agent) userDialogs(ctx context.Context, loginId string) ([]*Dialog, error) {
+	request := a.newAgentRequest()
+	response := request.doRequestCtx(ctx, "GET", request.server.urlString(request.id, "User", loginId, "Dialogs"), nil)
+	defer response.close()
+	msg, err := response.responseError()
+	if err != nil {
+		a.withFields(Fields{logProc: "GetDialogs", logId: response.id, logAgent: a.LoginName}).Error(msg)
+		return nil, err
+	}
+	data, err := newXmppDialogs(response.GetResponseBody())
+	if err != nil {
+		a.withFields(Fields{logProc: "GetDialogs", logId: response.id, logAgent: a.LoginName}).Error(err)
+		return nil, err
+	}
+	var ret []*Dialog
+	for i := range data.Dialogs {
+		ret = append(ret, newDialog(a, &data.Dialogs[i]))
+	}
+	a.withFields(Fields{logProc: "GetDialogs", logId: response.id, logAgent: a.LoginName}).Tracef("collect [%d] dialogs for user [%s]", len(ret), loginId)
+	return ret, nil
+}
+
+// GetLastStatus get latest collected dialog status
+func (d *Dialog) GetLastStatus() *XmppDialog {
+	return d.lastStatus
+}
+
+// GetStatus get actual dialog status from finesse server
+func (d *Dialog) GetStatus() (*XmppDialog, error) {
+	return d.GetStatusCtx(context.Background())
+}
+
+// GetStatusCtx get actual dialog status from finesse server, context cancel request
+func (d *Dialog) GetStatusCtx(ctx context.Context) (*XmppDialog, error) {
+	request := d.agent.newAgentRequest()
+	response := request.doRequestCtx(ctx, "GET", request.server.urlString(request.id, "Dialog", d.Id), nil)
+	defer response.close()
+	msg, err := response.responseError()
+	if err != nil {
+		d.agent.withFields(Fields{logProc: "GetDialogStatus", logId: response.id, logAgent: d.agent.LoginName}).Error(msg)
+		return nil, err
+	}
+	data, err := newXmppDialog(response.GetResponseBody())
+	if err != nil {
+		d.agent.withFields(Fields{logProc: "GetDialogStatus", logId: response.id, logAgent: d.agent.LoginName}).Error(err)
+		return nil, err
+	}
+	d.lastStatus = data
+	return d.lastStatus, nil
+}
+
+// AssociatedDialogId ID of associated dialog (primary dialog for consult call), empty if dialog is not associated
+func (d *Dialog) AssociatedDialogId() string {
+	if d.lastStatus == nil {
+		return ""
+	}
+	return d.lastStatus.associatedDialogId()
+}
+
+// Associated get associated dialog from finesse server, returns nil if dialog is not associated
+func (d *Dialog) Associated() (*Dialog, error) {
+	id := d.AssociatedDialogId()
+	if id == "" {
+		return nil, nil
+	}
+	a := newDialog(d.agent, &XmppDialog{ID: id})
+	if _, err := a.GetStatus(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// CallVariable get value of call or ECC variable from latest dialog status
+func (d *Dialog) CallVariable(name string) (string, bool) {
+	if d.lastStatus == nil {
+		return "", false
+	}
+	return d.lastStatus.callVariable(name)
+}
+
+// participant get agent participant data from latest dialog status
+func (d *Dialog) participant() *XmppParticipant {
+	if d.lastStatus == nil {
+		return nil
+	}
+	return d.lastStatus.participant(d.agent.Line)
+}
+
+func (d *Dialog) String() string {
+	if d.lastStatus != nil {
+		return fmt.Sprintf("%s (%s -> %s) => %s", d.Id, d.lastStatus.FromAddress, d.lastStatus.ToAddress, d.lastStatus.State)
+	}
+	return fmt.Sprintf("%s => UNKNOWN", d.Id)
+}
@@ -0,0 +1,177 @@
+package finesse_api
+
+import (
+	"context"
+	"sync"
+)
+
+const (
+	EventBufferSize = 100 // EventBufferSize number of events kept for subscriber with DeliveryDropOldest policy
+)
+
+// DeliveryPolicy define what happens with events when subscriber is slow
+type DeliveryPolicy int
+
+const (
+	DeliveryLossless   DeliveryPolicy = iota // DeliveryLossless all events are queued until subscriber reads it
+	DeliveryDropOldest                       // DeliveryDropOldest only last EventBufferSize events are queued, the oldest are dropped
+)
+
+// EventFilter select events for subscriber, nil filter accepts all events
+type EventFilter func(e Event) bool
+
+// EventTypes filter accepts only events of listed types
+func EventTypes(types ...EventType) EventFilter {
+	return func(e Event) bool {
+		for _, t := range types {
+			if e.Type() == t {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Subscription one subscriber of agent events
+type Subscription struct {
+	C <-chan Event // C channel with events, closed after Close or end of agent context
+
+	out     chan Event
+	filter  EventFilter
+	policy  DeliveryPolicy
+	queue   []Event
+	dropped int
+	signal  chan struct{}
+	done    chan struct{}
+	once    sync.Once
+	bus     *eventBus
+	mutex   sync.Mutex
+}
+
+// eventBus distribute agent events to independent subscribers
+type eventBus struct {
+	subscribers map[*Subscription]struct{}
+	mutex       sync.Mutex
+}
+
+func newEventBus(ctx context.Context) *eventBus {
+	b := &eventBus{subscribers: make(map[*Subscription]struct{})}
+	go func() {
+		<-ctx.Done()
+		b.closeAll()
+	}()
+	return b
+}
+
+// Subscribe create new subscription for agent events accepted by filter
+//
+// Each subscription has own queue, slow subscriber does not block others.
+func (a *Agent) Subscribe(filter EventFilter, policy DeliveryPolicy) *Subscription {
+	return a.events.subscribe(filter, policy)
+}
+
+func (b *eventBus) subscribe(filter EventFilter, policy DeliveryPolicy) *Subscription {
+	out := make(chan Event)
+	s := &Subscription{
+		C:      out,
+		out:    out,
+		filter: filter,
+		policy: policy,
+		signal: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+		bus:    b,
+	}
+	b.mutex.Lock()
+	b.subscribers[s] = struct{}{}
+	b.mutex.Unlock()
+	go s.pump()
+	return s
+}
+
+// publish deliver event to all subscribers
+func (b *eventBus) publish(e Event) {
+	if e == nil {
+		return
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for s := range b.subscribers {
+		s.push(e)
+	}
+}
+
+func (b *eventBus) closeAll() {
+	b.mutex.Lock()
+	subscribers := b.subscribers
+	b.subscribers = make(map[*Subscription]struct{})
+	b.mutex.Unlock()
+	for s := range subscribers {
+		s.stop()
+	}
+}
+
+// Close stop subscription and close its channel
+func (s *Subscription) Close() {
+	s.bus.mutex.Lock()
+	delete(s.bus.subscribers, s)
+	s.bus.mutex.Unlock()
+	s.stop()
+}
+
+// Dropped number of events dropped by DeliveryDropOldest policy
+func (s *Subscription) Dropped() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.dropped
+}
+
+func (s *Subscription) stop() {
+	s.once.Do(func() {
+		close(s.done)
+	})
+}
+
+// push add event into subscriber queue
+func (s *Subscription) push(e Event) {
+	if s.filter != nil && !s.filter(e) {
+		return
+	}
+	s.mutex.Lock()
+	if s.policy == DeliveryDropOldest && len(s.queue) >= EventBufferSize {
+		observeEventDropped(s.queue[0])
+		s.queue = s.queue[1:]
+		s.dropped++
+	}
+	s.queue = append(s.queue, e)
+	s.mutex.Unlock()
+	select {
+	case s.signal <- struct{}{}:
+	default:
+	}
+}
+
+// pump send queued events into subscriber channel
+func (s *Subscription) pump() {
+	defer close(s.out)
+	for {
+		s.mutex.Lock()
+		if len(s.queue) == 0 {
+			s.mutex.Unlock()
+			select {
+			case <-s.signal:
+				continue
+			case <-s.done:
+				return
+			}
+		}
+		e := s.queue[0]
+		s.queue[0] = nil
+		s.queue = s.queue[1:]
+		s.mutex.Unlock()
+		select {
+		case s.out <- e:
+		case <-s.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package finesse_api
+
+import (
+	"strings"
+	"time"
+)
+
+// EventType type of event delivered to subscribers
+type EventType int
+
+const (
+	EventUser        EventType = iota // EventUser agent (user) state notification
+	EventDialog                       // EventDialog dialog (call) notification
+	EventQueue                        // EventQueue queue statistics notification
+	EventTeam                         // EventTeam team notification
+	EventTeamMessage                  // EventTeamMessage team message (broadcast) notification
+	EventError                        // EventError notification with API errors
+	EventConnection                   // EventConnection XMPP connection up/down change
+	EventMonitor                      // EventMonitor supervisor monitoring lifecycle (silent monitor, barge-in, intercept)
+)
+
+var eventTypeNames = map[EventType]string{EventUser: "User", EventDialog: "Dialog", EventQueue: "Queue", EventTeam: "Team",
+	EventTeamMessage: "TeamMessage", EventError: "Error", EventConnection: "Connection", EventMonitor: "Monitor"}
+
+func (t EventType) String() string {
+	if n, ok := eventTypeNames[t]; ok {
+		return n
+	}
+	return "Unknown"
+}
+
+// Event one event delivered to subscribers
+type Event interface {
+	Type() EventType
+}
+
+// NotifyEvent common part of events created from XMPP notification
+type NotifyEvent struct {
+	Agent     *Agent      // Agent which receive notification
+	Source    string      // Source notification source (node URI)
+	Operation string      // Operation notified operation (PUT, POST, DELETE)
+	RequestId string      // RequestId ID of request which create notification
+	Time      time.Time   // Time when notification was received
+	Update    *XmppUpdate // Update full notification data
+}
+
+// UserEvent agent (user) state change
+type UserEvent struct {
+	NotifyEvent
+	User *XmppUser
+}
+
+// DialogEvent dialog (call) change
+type DialogEvent struct {
+	NotifyEvent
+	Dialogs []XmppDialog
+}
+
+// QueueEvent queue statistics change
+type QueueEvent struct {
+	NotifyEvent
+	QueueId    string          // QueueId ID of queue
+	Queue      *XmppQueue      // Queue raw notification data
+	Statistics QueueStatistics // Statistics converted queue statistics
+}
+
+// TeamEvent team change
+type TeamEvent struct {
+	NotifyEvent
+	Team *XmppTeam
+}
+
+// TeamMessageEvent team message (broadcast) created or deleted
+type TeamMessageEvent struct {
+	NotifyEvent
+	Message     *XmppTeamMessage // Message raw notification data
+	TeamMessage TeamMessage      // TeamMessage converted message, Operation DELETE for removed message
+}
+
+// ErrorEvent asynchronous API errors
+type ErrorEvent struct {
+	NotifyEvent
+	Errors []XmppError
+}
+
+func (UserEvent) Type() EventType        { return EventUser }
+func (DialogEvent) Type() EventType      { return EventDialog }
+func (QueueEvent) Type() EventType       { return EventQueue }
+func (TeamEvent) Type() EventType        { return EventTeam }
+func (TeamMessageEvent) Type() EventType { return EventTeamMessage }
+func (ErrorEvent) Type() EventType       { return EventError }
+func (ConnectionEvent) Type() EventType  { return EventConnection }
+
+// newNotifyEvent create typed event from XMPP notification, returns nil for unknown notification
+func newNotifyEvent(a *Agent, update *XmppUpdate) Event {
+	base := NotifyEvent{
+		Agent:     a,
+		Source:    update.Source,
+		Operation: strings.ToUpper(update.Event),
+		RequestId: update.RequestId,
+		Time:      time.Now(),
+		Update:    update,
+	}
+	switch {
+	case update.Data.Error.ApiErrors != nil:
+		return ErrorEvent{NotifyEvent: base, Errors: update.Data.Error.ApiErrors}
+	case len(update.Data.User.URI) > 0:
+		return UserEvent{NotifyEvent: base, User: &update.Data.User}
+	case len(update.dialogs()) > 0 || strings.HasSuffix(update.Source, "/Dialogs"):
+		return DialogEvent{NotifyEvent: base, Dialogs: update.dialogs()}
+	case len(update.Data.Queue.URI) > 0:
+		return QueueEvent{NotifyEvent: base, QueueId: update.Data.Queue.Id(), Queue: &update.Data.Queue, Statistics: update.Data.Queue.statistics()}
+	case len(update.Data.Team.URI) > 0:
+		return TeamEvent{NotifyEvent: base, Team: &update.Data.Team}
+	case len(update.Data.TeamMessage.URI) > 0 || len(update.Data.TeamMessage.ID) > 0:
+		return TeamMessageEvent{NotifyEvent: base, Message: &update.Data.TeamMessage, TeamMessage: update.Data.TeamMessage.teamMessage()}
+	}
+	return nil
+}
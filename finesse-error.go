@@ -0,0 +1,127 @@
+package finesse_api
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// sentinel errors for errors.Is, FinesseError match them by HTTP status and Finesse error type
+var (
+	ErrInvalidState  = errors.New("finesse: invalid state")                 // ErrInvalidState requested change is not possible in actual state
+	ErrInvalidDevice = errors.New("finesse: invalid device")                // ErrInvalidDevice line (device) is not valid for agent
+	ErrInvalidInput  = errors.New("finesse: invalid input")                 // ErrInvalidInput request contains invalid value
+	ErrUnauthorized  = errors.New("finesse: unauthorized")                  // ErrUnauthorized invalid credentials or not allowed operation
+	ErrNotFound      = errors.New("finesse: not found")                     // ErrNotFound requested object not exists
+	ErrNotifyTimeout = errors.New("finesse: notification timeout")          // ErrNotifyTimeout request was not confirmed by XMPP notification in time
+	ErrNotConnected  = errors.New("finesse: notification is not connected") // ErrNotConnected XMPP notification is not connected
+)
+
+// FinesseError error reported by Finesse server in REST response (ApiErrors) or in XMPP notification (apiErrors)
+type FinesseError struct {
+	HttpStatus          int    // HttpStatus status of REST response, 0 for error from XMPP notification
+	RequestId           string // RequestId request identification
+	ErrorType           string // ErrorType e.g. "Invalid State", "Invalid Device", "Authorization Failure"
+	ErrorMessage        string // ErrorMessage e.g. CF_INVALID_OBJECT_STATE
+	ErrorData           string // ErrorData additional data, e.g. name of invalid element
+	PeripheralErrorCode int    // PeripheralErrorCode error code from peripheral (CTI server)
+	PeripheralErrorText string // PeripheralErrorText description of peripheral error
+	PeripheralErrorMsg  string // PeripheralErrorMsg peripheral error name
+}
+
+// restApiErrors body of REST error response
+type restApiErrors struct {
+	ApiErrors []struct {
+		ErrorData           string `xml:"ErrorData"`
+		ErrorMessage        string `xml:"ErrorMessage"`
+		ErrorType           string `xml:"ErrorType"`
+		PeripheralErrorCode int    `xml:"PeripheralErrorCode"`
+		PeripheralErrorText string `xml:"PeripheralErrorText"`
+		PeripheralErrorMsg  string `xml:"PeripheralErrorMsg"`
+	} `xml:"ApiError"`
+}
+
+func (e *FinesseError) Error() string {
+	var sb strings.Builder
+	sb.WriteString("finesse error")
+	if e.HttpStatus > 0 {
+		sb.WriteString(fmt.Sprintf(" [%d]", e.HttpStatus))
+	}
+	if len(e.ErrorType) > 0 {
+		sb.WriteString(" " + e.ErrorType)
+	}
+	if len(e.ErrorMessage) > 0 {
+		sb.WriteString(": " + e.ErrorMessage)
+	}
+	if len(e.ErrorData) > 0 {
+		sb.WriteString(" (" + e.ErrorData + ")")
+	}
+	if len(e.PeripheralErrorMsg) > 0 {
+		sb.WriteString(fmt.Sprintf(" peripheral %d %s", e.PeripheralErrorCode, e.PeripheralErrorMsg))
+	}
+	return sb.String()
+}
+
+// Is match error with sentinel errors
+func (e *FinesseError) Is(target error) bool {
+	switch target {
+	case ErrInvalidState:
+		return strings.EqualFold(e.ErrorType, "Invalid State") || e.ErrorMessage == "CF_INVALID_OBJECT_STATE"
+	case ErrInvalidDevice:
+		return strings.EqualFold(e.ErrorType, "Invalid Device") || strings.HasPrefix(e.ErrorMessage, "CF_INVALID_LOGON_DEVICE")
+	case ErrInvalidInput:
+		return strings.EqualFold(e.ErrorType, "Invalid Input") || strings.EqualFold(e.ErrorType, "Parameter Missing")
+	case ErrUnauthorized:
+		return e.HttpStatus == http.StatusUnauthorized || e.HttpStatus == http.StatusForbidden || strings.EqualFold(e.ErrorType, "Authorization Failure")
+	case ErrNotFound:
+		return e.HttpStatus == http.StatusNotFound || strings.EqualFold(e.ErrorType, "Not Found")
+	}
+	return false
+}
+
+// newRestError create error from REST response, body with ApiErrors is optional
+func newRestError(status int, statusMessage string, requestId string, body string) *FinesseError {
+	e := &FinesseError{HttpStatus: status, RequestId: requestId, ErrorMessage: statusMessage}
+	var data restApiErrors
+	if err := xml.Unmarshal([]byte(body), &data); err != nil || len(data.ApiErrors) == 0 {
+		return e
+	}
+	a := data.ApiErrors[0]
+	e.ErrorType = a.ErrorType
+	e.ErrorMessage = a.ErrorMessage
+	e.ErrorData = a.ErrorData
+	e.PeripheralErrorCode = a.PeripheralErrorCode
+	e.PeripheralErrorText = a.PeripheralErrorText
+	e.PeripheralErrorMsg = a.PeripheralErrorMsg
+	return e
+}
+
+// newXmppError create error from XMPP notification
+func newXmppError(x XmppError, requestId string) *FinesseError {
+	e := &FinesseError{
+		RequestId:           requestId,
+		ErrorType:           x.ErrorType,
+		ErrorMessage:        x.ErrorMessage,
+		PeripheralErrorCode: x.PeripheralErrorCode,
+		PeripheralErrorText: x.PeripheralErrorText,
+		PeripheralErrorMsg:  x.PeripheralErrorMsg,
+	}
+	if x.ErrorData != 0 {
+		e.ErrorData = strconv.Itoa(x.ErrorData)
+	}
+	return e
+}
+
+// Matches match operation error with sentinel errors, e.g. op.Matches(ErrInvalidState)
+//
+// OperationError is not error, Matches is errors.Is for wrapped Error. Operation refused by library
+// for wrong agent state matches also ErrInvalidState.
+func (o OperationError) Matches(target error) bool {
+	if o.Type == TypeErrorWrongState && target == ErrInvalidState {
+		return true
+	}
+	return o.Error != nil && errors.Is(o.Error, target)
+}
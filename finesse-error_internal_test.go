@@ -0,0 +1,124 @@
+package finesse_api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestNewRestError(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		status int
+		body   string
+		exp    FinesseError
+		text   string
+	}{
+		{
+			name:   "invalid state",
+			status: http.StatusBadRequest,
+			body: `<ApiErrors><ApiError><ErrorData>state</ErrorData><ErrorMessage>CF_INVALID_OBJECT_STATE</ErrorMessage>` +
+				`<ErrorType>Invalid State</ErrorType><PeripheralErrorCode>-1</PeripheralErrorCode>` +
+				`<PeripheralErrorMsg>E_CTI_INVALID_OBJECT_STATE</PeripheralErrorMsg><PeripheralErrorText>object state</PeripheralErrorText>` +
+				`</ApiError><ApiError><ErrorType>Second</ErrorType></ApiError></ApiErrors>`,
+			exp: FinesseError{HttpStatus: http.StatusBadRequest, RequestId: "r1", ErrorType: "Invalid State", ErrorMessage: "CF_INVALID_OBJECT_STATE",
+				ErrorData: "state", PeripheralErrorCode: -1, PeripheralErrorText: "object state", PeripheralErrorMsg: "E_CTI_INVALID_OBJECT_STATE"},
+			text: "finesse error [400] Invalid State: CF_INVALID_OBJECT_STATE (state) peripheral -1 E_CTI_INVALID_OBJECT_STATE",
+		},
+		{
+			name:   "empty body",
+			status: http.StatusUnauthorized,
+			exp:    FinesseError{HttpStatus: http.StatusUnauthorized, RequestId: "r1", ErrorMessage: "401 Unauthorized"},
+			text:   "finesse error [401]: 401 Unauthorized",
+		},
+		{
+			name:   "body without ApiError",
+			status: http.StatusInternalServerError,
+			body:   `<ApiErrors></ApiErrors>`,
+			exp:    FinesseError{HttpStatus: http.StatusInternalServerError, RequestId: "r1", ErrorMessage: "500 Internal Server Error"},
+		},
+		{
+			name:   "not XML body",
+			status: http.StatusBadGateway,
+			body:   `<html><body>Bad Gateway</body>`,
+			exp:    FinesseError{HttpStatus: http.StatusBadGateway, RequestId: "r1", ErrorMessage: "502 Bad Gateway"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			e := newRestError(tc.status, fmt.Sprintf("%d %s", tc.status, http.StatusText(tc.status)), "r1", tc.body)
+			if *e != tc.exp {
+				t.Errorf("error is %+v, expected %+v", *e, tc.exp)
+			}
+			if len(tc.text) > 0 && e.Error() != tc.text {
+				t.Errorf("error text is [%s], expected [%s]", e.Error(), tc.text)
+			}
+		})
+	}
+}
+
+func TestNewXmppError(t *testing.T) {
+	e := newXmppError(XmppError{ErrorType: "Invalid Device", ErrorMessage: "CF_INVALID_LOGON_DEVICE_SPECIFIED", ErrorData: 9999}, "r2")
+	exp := FinesseError{RequestId: "r2", ErrorType: "Invalid Device", ErrorMessage: "CF_INVALID_LOGON_DEVICE_SPECIFIED", ErrorData: "9999"}
+	if *e != exp {
+		t.Errorf("error is %+v, expected %+v", *e, exp)
+	}
+	if text := e.Error(); text != "finesse error Invalid Device: CF_INVALID_LOGON_DEVICE_SPECIFIED (9999)" {
+		t.Errorf("error text is [%s]", text)
+	}
+	if e = newXmppError(XmppError{ErrorType: "Invalid State"}, "r3"); e.ErrorData != "" {
+		t.Errorf("empty error data is [%s]", e.ErrorData)
+	}
+}
+
+func TestFinesseErrorIs(t *testing.T) {
+	sentinels := []error{ErrInvalidState, ErrInvalidDevice, ErrInvalidInput, ErrUnauthorized, ErrNotFound, ErrNotifyTimeout, ErrNotConnected}
+	for _, tc := range []struct {
+		name string
+		err  *FinesseError
+		exp  error // the only matching sentinel, nil for none
+	}{
+		{name: "invalid state type", err: &FinesseError{HttpStatus: 400, ErrorType: "Invalid State"}, exp: ErrInvalidState},
+		{name: "invalid state message", err: &FinesseError{ErrorMessage: "CF_INVALID_OBJECT_STATE"}, exp: ErrInvalidState},
+		{name: "invalid device type", err: &FinesseError{ErrorType: "invalid device"}, exp: ErrInvalidDevice},
+		{name: "invalid device message", err: &FinesseError{ErrorMessage: "CF_INVALID_LOGON_DEVICE_SPECIFIED"}, exp: ErrInvalidDevice},
+		{name: "invalid input", err: &FinesseError{HttpStatus: 400, ErrorType: "Invalid Input"}, exp: ErrInvalidInput},
+		{name: "parameter missing", err: &FinesseError{HttpStatus: 400, ErrorType: "Parameter Missing"}, exp: ErrInvalidInput},
+		{name: "unauthorized status", err: &FinesseError{HttpStatus: http.StatusUnauthorized}, exp: ErrUnauthorized},
+		{name: "forbidden status", err: &FinesseError{HttpStatus: http.StatusForbidden}, exp: ErrUnauthorized},
+		{name: "authorization type", err: &FinesseError{ErrorType: "Authorization Failure"}, exp: ErrUnauthorized},
+		{name: "not found status", err: &FinesseError{HttpStatus: http.StatusNotFound}, exp: ErrNotFound},
+		{name: "not found type", err: &FinesseError{HttpStatus: 400, ErrorType: "Not Found"}, exp: ErrNotFound},
+		{name: "server error", err: &FinesseError{HttpStatus: 500, ErrorType: "Generic Error"}, exp: nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			wrapped := fmt.Errorf("agent [agent1]: %w", tc.err)
+			for _, s := range sentinels {
+				if r := errors.Is(wrapped, s); r != (s == tc.exp) {
+					t.Errorf("errors.Is(%s) is [%t]", s, r)
+				}
+			}
+		})
+	}
+}
+
+func TestOperationErrorMatches(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		op     OperationError
+		target error
+		exp    bool
+	}{
+		{name: "no error", op: OperationError{Type: TypeErrorNoError}, target: ErrInvalidState, exp: false},
+		{name: "wrong state by library", op: OperationError{Type: TypeErrorWrongState, Error: errors.New("agent is in READY state")}, target: ErrInvalidState, exp: true},
+		{name: "wrong state is not device", op: OperationError{Type: TypeErrorWrongState, Error: errors.New("agent is in READY state")}, target: ErrInvalidDevice, exp: false},
+		{name: "finesse error", op: OperationError{Type: TypeErrorResponse, Error: &FinesseError{HttpStatus: 401}}, target: ErrUnauthorized, exp: true},
+		{name: "wrapped notification error", op: OperationError{Type: TypeErrorAnalyzeResponse, Error: fmt.Errorf("login: %w", &FinesseError{ErrorType: "Invalid Device"})}, target: ErrInvalidDevice, exp: true},
+		{name: "notify timeout", op: OperationError{Type: TypeErrorNotifyTimeout, Error: fmt.Errorf("request [r1]: %w", ErrNotifyTimeout)}, target: ErrNotifyTimeout, exp: true},
+		{name: "other sentinel", op: OperationError{Type: TypeErrorNotifyTimeout, Error: ErrNotifyTimeout}, target: ErrNotConnected, exp: false},
+	} {
+		if r := tc.op.Matches(tc.target); r != tc.exp {
+			t.Errorf("%s: matches [%s] is [%t], expected [%t]", tc.name, tc.target, r, tc.exp)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package finessetest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	api "github.com/pokornyIt/finesse-api"
+)
+
+// Agent one agent (user) configured on mock server
+type Agent struct {
+	LoginName string   // LoginName name used for REST authentication
+	LoginId   string   // LoginId ID used in API and XMPP
+	Password  string   // Password for REST and XMPP
+	Extension string   // Extension the only valid line (device) for login
+	FirstName string   // FirstName first name
+	LastName  string   // LastName last name
+	Roles     []string // Roles Agent, Supervisor
+	TeamId    string   // TeamId team ID
+	TeamName  string   // TeamName team name
+
+	state           string
+	pendingState    string
+	reasonCodeId    int
+	line            string
+	stateChangeTime time.Time
+}
+
+// transitions valid state changes requested by agent
+var transitions = map[string][]string{
+	api.AgentStateLogout:   {api.AgentStateLogin},
+	api.AgentStateNotReady: {api.AgentStateReady, api.AgentStateNotReady, api.AgentStateLogout},
+	api.AgentStateReady:    {api.AgentStateNotReady},
+}
+
+// supervisorTransitions valid state changes requested by supervisor for team member
+var supervisorTransitions = map[string][]string{
+	api.AgentStateNotReady: {api.AgentStateReady, api.AgentStateNotReady, api.AgentStateLogout},
+	api.AgentStateReady:    {api.AgentStateNotReady, api.AgentStateLogout},
+}
+
+// State actual agent state
+func (a *Agent) State() string {
+	return a.state
+}
+
+// changeState process requested state, supervisor can logout ready agent
+func (a *Agent) changeState(state string, line string, reasonCodeId int, bySupervisor bool) error {
+	valid := false
+	allowed := transitions[a.state]
+	if bySupervisor {
+		allowed = supervisorTransitions[a.state]
+	}
+	for _, s := range allowed {
+		if s == state {
+			valid = true
+		}
+	}
+	if !valid {
+		return errInvalidState
+	}
+	switch state {
+	case api.AgentStateLogin:
+		if line != a.Extension {
+			return errInvalidDevice
+		}
+		a.line = line
+		a.setState(api.AgentStateNotReady, -1)
+	case api.AgentStateLogout:
+		a.line = ""
+		a.setState(api.AgentStateLogout, reasonCodeId)
+	default:
+		a.setState(state, reasonCodeId)
+	}
+	return nil
+}
+
+func (a *Agent) setState(state string, reasonCodeId int) {
+	a.state = state
+	a.pendingState = ""
+	a.reasonCodeId = reasonCodeId
+	a.stateChangeTime = time.Now().UTC()
+}
+
+// userValues values of simple user elements
+func (a *Agent) userValues() [][2]string {
+	return [][2]string{
+		{"dialogs", "/finesse/api/User/" + a.LoginId + "/Dialogs"},
+		{"extension", a.line},
+		{"firstName", a.FirstName},
+		{"lastName", a.LastName},
+		{"loginId", a.LoginId},
+		{"loginName", a.LoginName},
+		{"pendingState", a.pendingState},
+		{"reasonCodeId", strconv.Itoa(a.reasonCodeId)},
+		{"state", a.state},
+		{"stateChangeTime", a.stateChangeTime.Format("2006-01-02T15:04:05.000Z")},
+		{"teamId", a.TeamId},
+		{"teamName", a.TeamName},
+		{"uri", "/finesse/api/User/" + a.LoginId},
+	}
+}
+
+func (a *Agent) rolesXml() string {
+	var b strings.Builder
+	b.WriteString("<roles>")
+	for _, r := range a.Roles {
+		b.WriteString("<role>" + escape(r) + "</role>")
+	}
+	b.WriteString("</roles>")
+	return b.String()
+}
+
+func (a *Agent) teamsXml() string {
+	return fmt.Sprintf("<teams><Team><id>%s</id><name>%s</name><uri>/finesse/api/Team/%s</uri></Team></teams>", escape(a.TeamId), escape(a.TeamName), escape(a.TeamId))
+}
+
+func (a *Agent) isSupervisor() bool {
+	if a == nil {
+		return false
+	}
+	for _, r := range a.Roles {
+		if r == "Supervisor" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,668 @@
+package finessetest
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	api "github.com/pokornyIt/finesse-api"
+)
+
+// Dialog call on mock server, participants are agent extensions or external addresses
+//
+// Agent state is not changed by dialogs, agent sees dialogs where extension of his login is participant.
+type Dialog struct {
+	Id          string // Id dialog ID
+	FromAddress string // FromAddress calling address
+	ToAddress   string // ToAddress called address
+
+	callType     string
+	associatedId string // associatedId primary dialog of consult call
+	monitoredId  string // monitoredId agent dialog of silent monitor dialog
+	variables    []api.XmppCallVariable
+	wrapUpReason string
+	participants []*participant
+	notified     map[string]bool // notified agents (login ID) which received dialog in POST notification
+}
+
+// participant one party of dialog
+type participant struct {
+	address         string
+	state           string
+	silent          bool // silent participant is not notified about dialog (agent in silent monitor dialog)
+	startTime       time.Time
+	stateChangeTime time.Time
+}
+
+// dialogRequest body of POST /finesse/api/User/{id}/Dialogs and PUT /finesse/api/Dialog/{id}
+type dialogRequest struct {
+	RequestedAction     string `xml:"requestedAction"`
+	FromAddress         string `xml:"fromAddress"`
+	ToAddress           string `xml:"toAddress"`
+	TargetMediaAddress  string `xml:"targetMediaAddress"`
+	MediaAddress        string `xml:"mediaAddress"`
+	AssociatedDialogUri string `xml:"associatedDialogUri"`
+	MediaProperties     struct {
+		WrapUpReason  string `xml:"wrapUpReason"`
+		CallVariables struct {
+			CallVariable []api.XmppCallVariable `xml:"CallVariable"`
+		} `xml:"callvariables"`
+	} `xml:"mediaProperties"`
+}
+
+// notification XMPP message for agent
+type notification struct {
+	loginId string
+	msg     string
+}
+
+// AddDialog create incoming call from external address to agent extension and notify agent, call is alerting
+func (s *Server) AddDialog(fromAddress string, toAddress string, variables map[string]string) *Dialog {
+	s.mutex.Lock()
+	d := s.newDialog(fromAddress, toAddress, "ACD_IN")
+	names := make([]string, 0, len(variables))
+	for name := range variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		d.variables = append(d.variables, api.XmppCallVariable{Name: name, Value: variables[name]})
+	}
+	d.addParticipant(fromAddress, api.DialogStateActive)
+	d.addParticipant(toAddress, api.DialogStateAlerting)
+	messages := s.dialogMessages(d, nil, "")
+	s.mutex.Unlock()
+	s.publishAll(messages)
+	return d
+}
+
+// SetParticipantState change state of dialog participant outside of agent requests (e.g. called party answers) and notify agents
+//
+// Dialog ends when less than two participants are connected.
+func (s *Server) SetParticipantState(dialogId string, address string, state string) error {
+	s.mutex.Lock()
+	d := s.dialog(dialogId)
+	if d == nil {
+		s.mutex.Unlock()
+		return fmt.Errorf("dialog [%s] not exists", dialogId)
+	}
+	p := d.participant(address)
+	if p == nil {
+		s.mutex.Unlock()
+		return fmt.Errorf("address [%s] is not participant of dialog [%s]", address, dialogId)
+	}
+	p.setState(state)
+	messages := s.dialogMessages(d, nil, "")
+	s.mutex.Unlock()
+	s.publishAll(messages)
+	return nil
+}
+
+// EndDialog drop all participants (e.g. customer hangs up) and notify agents
+func (s *Server) EndDialog(dialogId string) error {
+	s.mutex.Lock()
+	d := s.dialog(dialogId)
+	if d == nil {
+		s.mutex.Unlock()
+		return fmt.Errorf("dialog [%s] not exists", dialogId)
+	}
+	for _, p := range d.participants {
+		if p.live() {
+			p.setState(api.DialogStateDropped)
+		}
+	}
+	messages := s.dialogMessages(d, nil, "")
+	s.mutex.Unlock()
+	s.publishAll(messages)
+	return nil
+}
+
+// ParticipantState actual state of dialog participant, empty for unknown participant or ended dialog
+func (s *Server) ParticipantState(dialogId string, address string) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if d := s.dialog(dialogId); d != nil {
+		if p := d.participant(address); p != nil {
+			return p.state
+		}
+	}
+	return ""
+}
+
+// CallData call variables and wrap-up reason of dialog, ok is false for ended dialog
+func (s *Server) CallData(dialogId string) (variables map[string]string, wrapUpReason string, ok bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	d := s.dialog(dialogId)
+	if d == nil {
+		return nil, "", false
+	}
+	variables = make(map[string]string, len(d.variables))
+	for _, v := range d.variables {
+		variables[v.Name] = v.Value
+	}
+	return variables, d.wrapUpReason, true
+}
+
+// newDialog create dialog without participants, must be called with locked mutex
+func (s *Server) newDialog(fromAddress string, toAddress string, callType string) *Dialog {
+	s.dialogId++
+	d := &Dialog{
+		Id:          strconv.Itoa(s.dialogId),
+		FromAddress: fromAddress,
+		ToAddress:   toAddress,
+		callType:    callType,
+		notified:    make(map[string]bool),
+	}
+	s.dialogs = append(s.dialogs, d)
+	return d
+}
+
+// dialog find live dialog by ID, must be called with locked mutex
+func (s *Server) dialog(id string) *Dialog {
+	for _, d := range s.dialogs {
+		if d.Id == id {
+			return d
+		}
+	}
+	return nil
+}
+
+// loggedAgent agent logged in with extension, must be called with locked mutex
+func (s *Server) loggedAgent(address string) *Agent {
+	for _, a := range s.agents {
+		if len(a.line) > 0 && a.line == address {
+			return a
+		}
+	}
+	return nil
+}
+
+// calledState initial state of called party, logged agent is alerting, external address answers immediately, must be called with locked mutex
+func (s *Server) calledState(address string) string {
+	if s.loggedAgent(address) != nil {
+		return api.DialogStateAlerting
+	}
+	return api.DialogStateActive
+}
+
+// connect add caller and called party into new dialog, caller is initiated until called agent answers, must be called with locked mutex
+func (s *Server) connect(d *Dialog) {
+	called := s.calledState(d.ToAddress)
+	caller := api.DialogStateActive
+	if called == api.DialogStateAlerting {
+		caller = api.DialogStateInitiated
+	}
+	d.addParticipant(d.FromAddress, caller)
+	d.addParticipant(d.ToAddress, called)
+}
+
+// serveUserDialogs process GET and POST /finesse/api/User/{id}/Dialogs
+func (s *Server) serveUserDialogs(w http.ResponseWriter, r *http.Request, user *Agent, a *Agent) {
+	if r.Method == http.MethodGet {
+		var b strings.Builder
+		b.WriteString("<Dialogs>")
+		s.mutex.Lock()
+		for _, d := range s.dialogs {
+			if p := d.participant(a.line); len(a.line) > 0 && p != nil && !p.silent && p.live() {
+				b.WriteString(d.xml())
+			}
+		}
+		s.mutex.Unlock()
+		b.WriteString("</Dialogs>")
+		s.writeXml(w, http.StatusOK, b.String())
+		return
+	}
+	req, ok := readDialogRequest(w, r)
+	if !ok {
+		return
+	}
+	if user != a || len(user.line) == 0 {
+		writeApiError(w, http.StatusBadRequest, "Invalid State", "User is not logged in with device", user.LoginId)
+		return
+	}
+	s.mutex.Lock()
+	var messages []notification
+	var errType, errMessage, errData string
+	switch req.RequestedAction {
+	case api.DialogActionMakeCall:
+		if req.FromAddress != user.line || len(req.ToAddress) == 0 || req.ToAddress == user.line {
+			errType, errMessage, errData = "Invalid Input", "Invalid address specified", req.ToAddress
+			break
+		}
+		d := s.newDialog(user.line, req.ToAddress, "OUT")
+		s.connect(d)
+		messages = s.dialogMessages(d, user, r.Header.Get("RequestId"))
+	case api.DialogActionSilentMonitor:
+		target := s.monitoredDialog(user, req.TargetMediaAddress)
+		if req.MediaAddress != user.line || target == nil {
+			errType, errMessage, errData = "Invalid State", "Agent has no active call for silent monitor", req.TargetMediaAddress
+			break
+		}
+		d := s.newDialog(user.line, req.TargetMediaAddress, "SUPERVISOR_MONITOR")
+		d.monitoredId = target.Id
+		d.addParticipant(user.line, api.DialogStateActive)
+		d.addParticipant(req.TargetMediaAddress, api.DialogStateActive).silent = true
+		messages = s.dialogMessages(d, user, r.Header.Get("RequestId"))
+	case api.DialogActionBargeCall:
+		target := s.monitoredDialog(user, req.ToAddress)
+		if req.FromAddress != user.line || target == nil || target.Id != path.Base(req.AssociatedDialogUri) {
+			errType, errMessage, errData = "Invalid State", "Agent has no active call for barge-in", req.ToAddress
+			break
+		}
+		// barge-in ends silent monitoring of supervisor
+		for _, m := range append([]*Dialog(nil), s.dialogs...) {
+			if p := m.participant(user.line); len(m.monitoredId) > 0 && p != nil && p.live() {
+				p.setState(api.DialogStateDropped)
+				messages = append(messages, s.dialogMessages(m, user, "")...)
+			}
+		}
+		target.addParticipant(user.line, api.DialogStateActive)
+		messages = append(messages, s.dialogMessages(target, user, r.Header.Get("RequestId"))...)
+	default:
+		errType, errMessage, errData = "Invalid Input", "Invalid action specified", req.RequestedAction
+	}
+	s.mutex.Unlock()
+	if len(errType) > 0 {
+		writeApiError(w, http.StatusBadRequest, errType, errMessage, errData)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+	s.publishLater(messages)
+}
+
+// monitoredDialog dialog with agent (by extension) active in it, agent must be team member of supervisor, must be called with locked mutex
+func (s *Server) monitoredDialog(supervisor *Agent, address string) *Dialog {
+	a := s.loggedAgent(address)
+	if !supervisor.isSupervisor() || a == nil || a.TeamId != supervisor.TeamId {
+		return nil
+	}
+	for _, d := range s.dialogs {
+		if p := d.participant(address); len(d.monitoredId) == 0 && p != nil && !p.silent && p.state == api.DialogStateActive {
+			return d
+		}
+	}
+	return nil
+}
+
+// serveDialog process GET and PUT /finesse/api/Dialog/{id}
+func (s *Server) serveDialog(w http.ResponseWriter, r *http.Request, user *Agent, dialogId string) {
+	s.mutex.Lock()
+	d := s.dialog(dialogId)
+	var p *participant
+	visible := false
+	if d != nil {
+		p = d.participant(user.line)
+		visible = len(user.line) > 0 && p != nil
+		// supervisor reads dialogs of team members
+		for _, x := range d.participants {
+			if a := s.loggedAgent(x.address); user.isSupervisor() && a != nil && a.TeamId == user.TeamId {
+				visible = true
+			}
+		}
+	}
+	body := ""
+	if visible && r.Method == http.MethodGet {
+		body = d.xml()
+	}
+	s.mutex.Unlock()
+	switch {
+	case d == nil:
+		writeApiError(w, http.StatusNotFound, "Not Found", "Dialog not found", dialogId)
+	case !visible:
+		writeApiError(w, http.StatusUnauthorized, "Authorization Failure", "User is not participant of dialog", dialogId)
+	case r.Method == http.MethodGet:
+		s.writeXml(w, http.StatusOK, body)
+	case r.Method == http.MethodPut:
+		s.putDialog(w, r, user, dialogId)
+	default:
+		writeApiError(w, http.StatusNotFound, "Not Found", "Resource not found", r.URL.Path)
+	}
+}
+
+// putDialog process dialog action of participant, result is notified over XMPP
+func (s *Server) putDialog(w http.ResponseWriter, r *http.Request, user *Agent, dialogId string) {
+	req, ok := readDialogRequest(w, r)
+	if !ok {
+		return
+	}
+	requestId := r.Header.Get("RequestId")
+	s.mutex.Lock()
+	d := s.dialog(dialogId)
+	var p *participant
+	if d != nil && len(user.line) > 0 {
+		p = d.participant(user.line)
+	}
+	if p == nil || !p.allowAction(d, req.RequestedAction) {
+		s.mutex.Unlock()
+		writeApiError(w, http.StatusBadRequest, "Invalid State", "Action is not allowed for participant", req.RequestedAction)
+		return
+	}
+	var messages []notification
+	var errType, errMessage, errData string
+	switch req.RequestedAction {
+	case api.DialogActionAnswer:
+		p.setState(api.DialogStateActive)
+		for _, x := range d.participants {
+			if x.state == api.DialogStateInitiated {
+				x.setState(api.DialogStateActive)
+			}
+		}
+		messages = s.dialogMessages(d, user, requestId)
+	case api.DialogActionHold:
+		p.setState(api.DialogStateHeld)
+		messages = s.dialogMessages(d, user, requestId)
+	case api.DialogActionRetrieve:
+		p.setState(api.DialogStateActive)
+		messages = s.dialogMessages(d, user, requestId)
+	case api.DialogActionDrop:
+		p.setState(api.DialogStateDropped)
+		messages = s.dialogMessages(d, user, requestId)
+	case api.DialogActionTransferSst:
+		if len(req.ToAddress) == 0 || d.participant(req.ToAddress) != nil {
+			errType, errMessage, errData = "Invalid Input", "Invalid address specified", req.ToAddress
+			break
+		}
+		d.addParticipant(req.ToAddress, s.calledState(req.ToAddress))
+		p.setState(api.DialogStateDropped)
+		messages = s.dialogMessages(d, user, requestId)
+	case api.DialogActionParticipantDrop:
+		target := d.participant(req.TargetMediaAddress)
+		if target == nil || target == p || !target.live() {
+			errType, errMessage, errData = "Invalid Input", "Invalid participant specified", req.TargetMediaAddress
+			break
+		}
+		target.setState(api.DialogStateDropped)
+		messages = s.dialogMessages(d, user, requestId)
+	case api.DialogActionUpdateCallData:
+		reason := req.MediaProperties.WrapUpReason
+		if _, found := s.wrapUpReasons.ByLabel(reason); len(reason) > 0 && !found {
+			errType, errMessage, errData = "Invalid Input", "Invalid wrap-up reason specified", reason
+			break
+		}
+		if len(reason) > 0 {
+			d.wrapUpReason = reason
+		}
+		for _, v := range req.MediaProperties.CallVariables.CallVariable {
+			d.setVariable(v)
+		}
+		messages = s.dialogMessages(d, user, requestId)
+	case api.DialogActionConsultCall:
+		if req.FromAddress != user.line || len(req.ToAddress) == 0 || d.participant(req.ToAddress) != nil {
+			errType, errMessage, errData = "Invalid Input", "Invalid address specified", req.ToAddress
+			break
+		}
+		p.setState(api.DialogStateHeld)
+		messages = s.dialogMessages(d, user, requestId)
+		consult := s.newDialog(user.line, req.ToAddress, "CONSULT")
+		consult.associatedId = d.Id
+		s.connect(consult)
+		messages = append(messages, s.dialogMessages(consult, user, requestId)...)
+	case api.DialogActionTransfer, api.DialogActionConference:
+		primary := s.dialog(d.associatedId)
+		if primary == nil {
+			errType, errMessage, errData = "Invalid State", "Primary dialog not exists", d.associatedId
+			break
+		}
+		messages = s.joinPrimary(d, primary, user, requestId, req.RequestedAction == api.DialogActionConference)
+	}
+	s.mutex.Unlock()
+	if len(errType) > 0 {
+		writeApiError(w, http.StatusBadRequest, errType, errMessage, errData)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+	s.publishLater(messages)
+}
+
+// joinPrimary move consulted parties into primary dialog and end consult dialog, must be called with locked mutex
+//
+// Conference keeps user in primary dialog, transfer drops him.
+func (s *Server) joinPrimary(consult *Dialog, primary *Dialog, user *Agent, requestId string, conference bool) []notification {
+	for _, x := range consult.participants {
+		if x.address != user.line && x.live() {
+			primary.addParticipant(x.address, api.DialogStateActive)
+		}
+		x.setState(api.DialogStateDropped)
+	}
+	if p := primary.participant(user.line); p != nil {
+		if conference {
+			p.setState(api.DialogStateActive)
+		} else {
+			p.setState(api.DialogStateDropped)
+		}
+	}
+	messages := s.dialogMessages(primary, user, requestId)
+	return append(messages, s.dialogMessages(consult, user, requestId)...)
+}
+
+// dialogMessages notifications of dialog change for participating agents, ended dialog is removed, must be called with locked mutex
+//
+// Agent gets POST for new dialog, PUT for change and DELETE when he is dropped or dialog ends. Request ID is sent only to user.
+func (s *Server) dialogMessages(d *Dialog, user *Agent, requestId string) []notification {
+	if d.liveCount() < 2 {
+		for _, p := range d.participants {
+			if p.live() {
+				p.setState(api.DialogStateDropped)
+			}
+		}
+		for i, x := range s.dialogs {
+			if x == d {
+				s.dialogs = append(s.dialogs[:i], s.dialogs[i+1:]...)
+				break
+			}
+		}
+	}
+	var messages []notification
+	for _, a := range s.agents {
+		p := d.participant(a.line)
+		if len(a.line) == 0 || p == nil || p.silent {
+			continue
+		}
+		id := ""
+		if a == user {
+			id = requestId
+		}
+		event, data, source := "PUT", d.xml(), "/finesse/api/Dialog/"+d.Id
+		switch {
+		case p.live() && !d.notified[a.LoginId]:
+			event, data, source = "POST", "<dialogs>"+data+"</dialogs>", "/finesse/api/User/"+a.LoginId+"/Dialogs"
+			d.notified[a.LoginId] = true
+		case !p.live() && d.notified[a.LoginId]:
+			event, data, source = "DELETE", "<dialogs>"+data+"</dialogs>", "/finesse/api/User/"+a.LoginId+"/Dialogs"
+			delete(d.notified, a.LoginId)
+		case !p.live():
+			continue
+		}
+		update := fmt.Sprintf("<Update><data>%s</data><event>%s</event><requestId>%s</requestId><source>%s</source></Update>",
+			data, event, escape(id), source)
+		messages = append(messages, notification{
+			loginId: a.LoginId,
+			msg:     s.templates.user.message(s.Host, a.LoginId, "/finesse/api/User/"+a.LoginId+"/Dialogs", s.nextMsgId(), update),
+		})
+	}
+	if s.dialog(d.Id) == nil {
+		// silent monitoring ends with monitored call
+		for _, m := range append([]*Dialog(nil), s.dialogs...) {
+			if m.monitoredId == d.Id {
+				for _, p := range m.participants {
+					p.setState(api.DialogStateDropped)
+				}
+				messages = append(messages, s.dialogMessages(m, nil, "")...)
+			}
+		}
+	}
+	return messages
+}
+
+// publishAll send notifications in order
+func (s *Server) publishAll(messages []notification) {
+	for _, n := range messages {
+		s.publish(n.loginId, n.msg)
+	}
+}
+
+// publishLater send notifications after REST response, Finesse confirms request asynchronously
+func (s *Server) publishLater(messages []notification) {
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		s.publishAll(messages)
+	}()
+}
+
+// readDialogRequest parse dialog request body, writes error response for invalid body
+func readDialogRequest(w http.ResponseWriter, r *http.Request) (*dialogRequest, bool) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeApiError(w, http.StatusBadRequest, "Invalid Input", "Problem read request body", "")
+		return nil, false
+	}
+	var req dialogRequest
+	if err = xml.Unmarshal(data, &req); err != nil || len(req.RequestedAction) == 0 {
+		writeApiError(w, http.StatusBadRequest, "Invalid Input", "Invalid action specified", "requestedAction")
+		return nil, false
+	}
+	return &req, true
+}
+
+// addParticipant add participant or change state of existing participant
+func (d *Dialog) addParticipant(address string, state string) *participant {
+	if p := d.participant(address); p != nil {
+		p.silent = false
+		p.setState(state)
+		return p
+	}
+	now := time.Now().UTC()
+	p := &participant{address: address, state: state, startTime: now, stateChangeTime: now}
+	d.participants = append(d.participants, p)
+	return p
+}
+
+// participant find participant by address, nil if not exists
+func (d *Dialog) participant(address string) *participant {
+	for _, p := range d.participants {
+		if p.address == address {
+			return p
+		}
+	}
+	return nil
+}
+
+// liveCount number of participants in live state
+func (d *Dialog) liveCount() int {
+	n := 0
+	for _, p := range d.participants {
+		if p.live() {
+			n++
+		}
+	}
+	return n
+}
+
+// state dialog state derived from participants
+func (d *Dialog) state() string {
+	if d.liveCount() < 2 {
+		return api.DialogStateDropped
+	}
+	for _, p := range d.participants {
+		if p.state == api.DialogStateAlerting {
+			return api.DialogStateAlerting
+		}
+	}
+	return api.DialogStateActive
+}
+
+// setVariable update existing call variable or add new one
+func (d *Dialog) setVariable(v api.XmppCallVariable) {
+	for i := range d.variables {
+		if d.variables[i].Name == v.Name {
+			d.variables[i].Value = v.Value
+			return
+		}
+	}
+	d.variables = append(d.variables, v)
+}
+
+func (d *Dialog) xml() string {
+	associated := ""
+	if len(d.associatedId) > 0 {
+		associated = "/finesse/api/Dialog/" + d.associatedId
+	}
+	var variables strings.Builder
+	for _, v := range d.variables {
+		variables.WriteString("<CallVariable><name>" + escape(v.Name) + "</name><value>" + escape(v.Value) + "</value></CallVariable>")
+	}
+	var participants strings.Builder
+	for _, p := range d.participants {
+		participants.WriteString("<Participant><actions>")
+		for _, a := range p.actions(d) {
+			participants.WriteString("<action>" + a + "</action>")
+		}
+		participants.WriteString(fmt.Sprintf("</actions><mediaAddress>%s</mediaAddress><mediaAddressType>AGENT_DEVICE</mediaAddressType>"+
+			"<startTime>%s</startTime><state>%s</state><stateCause></stateCause><stateChangeTime>%s</stateChangeTime></Participant>",
+			escape(p.address), p.startTime.Format("2006-01-02T15:04:05.000Z"), p.state, p.stateChangeTime.Format("2006-01-02T15:04:05.000Z")))
+	}
+	return fmt.Sprintf("<Dialog><associatedDialogUri>%s</associatedDialogUri><fromAddress>%s</fromAddress><id>%s</id>"+
+		"<mediaProperties><mediaId>1</mediaId><DNIS>%s</DNIS><callType>%s</callType><dialedNumber>%s</dialedNumber>"+
+		"<callvariables>%s</callvariables><wrapUpReason>%s</wrapUpReason></mediaProperties><mediaType>Voice</mediaType>"+
+		"<participants>%s</participants><state>%s</state><toAddress>%s</toAddress><uri>/finesse/api/Dialog/%s</uri></Dialog>",
+		associated, escape(d.FromAddress), escape(d.Id), escape(d.ToAddress), d.callType, escape(d.ToAddress),
+		variables.String(), escape(d.wrapUpReason), participants.String(), d.state(), escape(d.ToAddress), escape(d.Id))
+}
+
+// live participant is connected to dialog
+func (p *participant) live() bool {
+	_, ok := api.DialogLiveStates[p.state]
+	return ok
+}
+
+func (p *participant) setState(state string) {
+	p.state = state
+	p.stateChangeTime = time.Now().UTC()
+}
+
+// actions allowed actions of participant in dialog
+func (p *participant) actions(d *Dialog) []string {
+	if len(d.monitoredId) > 0 {
+		if p.live() && !p.silent {
+			return []string{api.DialogActionDrop}
+		}
+		return nil
+	}
+	switch p.state {
+	case api.DialogStateAlerting:
+		return []string{api.DialogActionAnswer}
+	case api.DialogStateInitiating, api.DialogStateInitiated:
+		return []string{api.DialogActionDrop}
+	case api.DialogStateHeld:
+		return []string{api.DialogActionRetrieve, api.DialogActionDrop, api.DialogActionUpdateCallData}
+	case api.DialogStateActive:
+		actions := []string{api.DialogActionHold, api.DialogActionDrop, api.DialogActionTransferSst, api.DialogActionUpdateCallData}
+		if len(d.associatedId) == 0 {
+			actions = append(actions, api.DialogActionConsultCall)
+		} else if d.state() == api.DialogStateActive {
+			actions = append(actions, api.DialogActionTransfer, api.DialogActionConference)
+		}
+		if d.liveCount() > 2 {
+			actions = append(actions, api.DialogActionParticipantDrop)
+		}
+		return actions
+	}
+	return nil
+}
+
+// allowAction participant can process action in dialog
+func (p *participant) allowAction(d *Dialog, action string) bool {
+	for _, a := range p.actions(d) {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package finessetest
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	api "github.com/pokornyIt/finesse-api"
+)
+
+// Queue queue configured on mock server, all agents are members of all queues
+type Queue struct {
+	Id   string // Id queue ID
+	Name string // Name queue name
+
+	callsInQueue int
+	longestStart time.Time
+}
+
+// AddQueue add queue without waiting calls
+func (s *Server) AddQueue(id string, name string) *Queue {
+	q := &Queue{Id: id, Name: name}
+	s.mutex.Lock()
+	s.queues = append(s.queues, q)
+	s.mutex.Unlock()
+	return q
+}
+
+// SetQueueCalls change waiting calls in queue and notify all logged-in agents
+func (s *Server) SetQueueCalls(id string, callsInQueue int, longestStart time.Time) error {
+	s.mutex.Lock()
+	q := s.queue(id)
+	if q == nil {
+		s.mutex.Unlock()
+		return fmt.Errorf("queue [%s] not exists", id)
+	}
+	q.callsInQueue = callsInQueue
+	q.longestStart = longestStart
+	update := fmt.Sprintf("<Update><data>%s</data><event>PUT</event><requestId></requestId><source>/finesse/api/Queue/%s</source></Update>",
+		s.queueXml(q), escape(q.Id))
+	messages := make(map[string]string)
+	for _, a := range s.agents {
+		if a.state != api.AgentStateLogout {
+			messages[a.LoginId] = s.templates.user.message(s.Host, a.LoginId, "/finesse/api/User/"+a.LoginId+"/Queues", s.nextMsgId(), update)
+		}
+	}
+	s.mutex.Unlock()
+	for loginId, msg := range messages {
+		s.publish(loginId, msg)
+	}
+	return nil
+}
+
+// queue find queue by ID, must be called with locked mutex
+func (s *Server) queue(id string) *Queue {
+	for _, q := range s.queues {
+		if q.Id == id {
+			return q
+		}
+	}
+	return nil
+}
+
+// queueXml queue with statistics computed from agent states, must be called with locked mutex
+func (s *Server) queueXml(q *Queue) string {
+	ready, notReady, loggedOn := 0, 0, 0
+	for _, a := range s.agents {
+		switch a.state {
+		case api.AgentStateReady:
+			ready++
+		case api.AgentStateNotReady:
+			notReady++
+		}
+		if a.state != api.AgentStateLogout {
+			loggedOn++
+		}
+	}
+	longest := ""
+	if q.callsInQueue > 0 && !q.longestStart.IsZero() {
+		longest = q.longestStart.UTC().Format("2006-01-02T15:04:05.000Z")
+	}
+	return fmt.Sprintf("<Queue><uri>/finesse/api/Queue/%s</uri><name>%s</name><statistics>"+
+		"<callsInQueue>%d</callsInQueue><startTimeOfLongestCallInQueue>%s</startTimeOfLongestCallInQueue>"+
+		"<agentsReady>%d</agentsReady><agentsNotReady>%d</agentsNotReady><agentsBusyOther>0</agentsBusyOther>"+
+		"<agentsLoggedOn>%d</agentsLoggedOn><agentsTalkingInbound>0</agentsTalkingInbound>"+
+		"<agentsTalkingOutbound>0</agentsTalkingOutbound><agentsTalkingInternal>0</agentsTalkingInternal>"+
+		"<agentsWrapUpNotReady>0</agentsWrapUpNotReady><agentsWrapUpReady>0</agentsWrapUpReady></statistics></Queue>",
+		escape(q.Id), escape(q.Name), q.callsInQueue, longest, ready, notReady, loggedOn)
+}
+
+// queuesXml all queues, must be called with locked mutex
+func (s *Server) queuesXml() string {
+	var b strings.Builder
+	b.WriteString("<Queues>")
+	for _, q := range s.queues {
+		b.WriteString(s.queueXml(q))
+	}
+	b.WriteString("</Queues>")
+	return b.String()
+}
@@ -0,0 +1,245 @@
+package finessetest
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	api "github.com/pokornyIt/finesse-api"
+)
+
+// userRequest body of PUT /finesse/api/User/{id}
+type userRequest struct {
+	State        string `xml:"state"`
+	Extension    string `xml:"extension"`
+	ReasonCodeId *int   `xml:"reasonCodeId"`
+}
+
+// serveRest process /finesse/api requests
+func (s *Server) serveRest(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/finesse/api"), "/"), "/")
+	if len(parts) == 1 && parts[0] == "SystemInfo" && r.Method == http.MethodGet {
+		s.writeXml(w, http.StatusOK, s.systemInfo())
+		return
+	}
+	if len(parts) < 2 && parts[0] != "TeamMessage" {
+		writeApiError(w, http.StatusNotFound, "Not Found", "Resource not found", r.URL.Path)
+		return
+	}
+	user, ok := s.authenticate(w, r)
+	if !ok {
+		return
+	}
+	if parts[0] == "Team" && len(parts) == 2 && r.Method == http.MethodGet {
+		s.getTeam(w, r, user, parts[1])
+		return
+	}
+	if parts[0] == "TeamMessage" && len(parts) == 1 && r.Method == http.MethodPost {
+		s.postTeamMessage(w, r, user)
+		return
+	}
+	if parts[0] == "TeamMessage" && len(parts) == 2 && r.Method == http.MethodDelete {
+		s.deleteTeamMessage(w, user, parts[1])
+		return
+	}
+	if parts[0] == "Queue" && len(parts) == 2 && r.Method == http.MethodGet {
+		s.mutex.Lock()
+		q := s.queue(parts[1])
+		body := ""
+		if q != nil {
+			body = s.queueXml(q)
+		}
+		s.mutex.Unlock()
+		if q == nil {
+			writeApiError(w, http.StatusNotFound, "Not Found", "Queue not found", parts[1])
+			return
+		}
+		s.writeXml(w, http.StatusOK, body)
+		return
+	}
+	if parts[0] == "Dialog" && len(parts) == 2 {
+		s.serveDialog(w, r, user, parts[1])
+		return
+	}
+	if parts[0] != "User" {
+		writeApiError(w, http.StatusNotFound, "Not Found", "Resource not found", r.URL.Path)
+		return
+	}
+	a, ok := s.authorize(w, user, parts[1])
+	if !ok {
+		return
+	}
+	switch {
+	case len(parts) == 2 && r.Method == http.MethodGet:
+		s.mutex.Lock()
+		body := s.templates.user.userXml(a)
+		s.mutex.Unlock()
+		s.writeXml(w, http.StatusOK, body)
+	case len(parts) == 2 && r.Method == http.MethodPut:
+		s.putUser(w, r, user, a)
+	case len(parts) == 3 && parts[2] == "ReasonCodes" && r.Method == http.MethodGet:
+		s.writeXml(w, http.StatusOK, s.reasonCodesXml(r.URL.Query().Get("category")))
+	case len(parts) == 3 && parts[2] == "WrapUpReasons" && r.Method == http.MethodGet:
+		s.writeXml(w, http.StatusOK, s.wrapUpReasonsXml())
+	case len(parts) == 3 && parts[2] == "Queues" && r.Method == http.MethodGet:
+		s.mutex.Lock()
+		body := s.queuesXml()
+		s.mutex.Unlock()
+		s.writeXml(w, http.StatusOK, body)
+	case len(parts) == 3 && parts[2] == "TeamMessages" && r.Method == http.MethodGet:
+		s.mutex.Lock()
+		body := s.teamMessagesXml(a.TeamId)
+		s.mutex.Unlock()
+		s.writeXml(w, http.StatusOK, body)
+	case len(parts) == 3 && parts[2] == "Dialogs" && (r.Method == http.MethodGet || r.Method == http.MethodPost):
+		s.serveUserDialogs(w, r, user, a)
+	default:
+		writeApiError(w, http.StatusNotFound, "Not Found", "Resource not found", r.URL.Path)
+	}
+}
+
+// authenticate check Basic authentication
+func (s *Server) authenticate(w http.ResponseWriter, r *http.Request) (*Agent, bool) {
+	name, pwd, ok := r.BasicAuth()
+	s.mutex.Lock()
+	user, userOk := s.agent(name)
+	s.mutex.Unlock()
+	if !ok || !userOk || user.Password != pwd {
+		writeApiError(w, http.StatusUnauthorized, "Authorization Failure", "Invalid authorization user specified", name)
+		return nil, false
+	}
+	return user, true
+}
+
+// authorize agent can access own data, supervisor data of team members
+func (s *Server) authorize(w http.ResponseWriter, user *Agent, id string) (*Agent, bool) {
+	s.mutex.Lock()
+	target, targetOk := s.agent(id)
+	s.mutex.Unlock()
+	if !targetOk {
+		writeApiError(w, http.StatusNotFound, "Not Found", "User not found", id)
+		return nil, false
+	}
+	if user != target && !(user.isSupervisor() && user.TeamId == target.TeamId) {
+		writeApiError(w, http.StatusUnauthorized, "Authorization Failure", "Access to other user is not allowed", id)
+		return nil, false
+	}
+	return target, true
+}
+
+// putUser accept state change, result is notified over XMPP
+func (s *Server) putUser(w http.ResponseWriter, r *http.Request, user *Agent, a *Agent) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeApiError(w, http.StatusBadRequest, "Invalid Input", "Problem read request body", "")
+		return
+	}
+	var req userRequest
+	if err = xml.Unmarshal(data, &req); err != nil || len(req.State) == 0 {
+		writeApiError(w, http.StatusBadRequest, "Invalid Input", "Invalid state specified for user", "state")
+		return
+	}
+	if req.State != api.AgentStateLogin && req.State != api.AgentStateLogout &&
+		req.State != api.AgentStateReady && req.State != api.AgentStateNotReady {
+		writeApiError(w, http.StatusBadRequest, "Invalid Input", "Invalid state specified for user", req.State)
+		return
+	}
+	reason := -1
+	if req.ReasonCodeId != nil {
+		s.mutex.Lock()
+		code, ok := s.reasonCodes.ById(*req.ReasonCodeId)
+		s.mutex.Unlock()
+		if !ok || code.Category != req.State {
+			writeApiError(w, http.StatusBadRequest, "Invalid Input", "Invalid reason code specified", fmt.Sprint(*req.ReasonCodeId))
+			return
+		}
+		reason = code.Id
+	}
+	w.WriteHeader(http.StatusAccepted)
+	s.requestState(user, a, req.State, req.Extension, reason, r.Header.Get("RequestId"))
+}
+
+// getTeam team with users, only for supervisors of team
+func (s *Server) getTeam(w http.ResponseWriter, r *http.Request, user *Agent, teamId string) {
+	if !user.isSupervisor() || user.TeamId != teamId {
+		writeApiError(w, http.StatusUnauthorized, "Authorization Failure", "User is not supervisor of team", teamId)
+		return
+	}
+	loggedOut := strings.EqualFold(r.URL.Query().Get("includeLoggedOutAgents"), "true")
+	var b strings.Builder
+	s.mutex.Lock()
+	b.WriteString(fmt.Sprintf("<Team><uri>/finesse/api/Team/%s</uri><id>%s</id><name>%s</name><users>", escape(teamId), escape(teamId), escape(user.TeamName)))
+	for _, a := range s.agents {
+		if a.TeamId != teamId || (!loggedOut && a.state == api.AgentStateLogout) {
+			continue
+		}
+		b.WriteString(fmt.Sprintf("<User><uri>/finesse/api/User/%s</uri><loginId>%s</loginId><loginName>%s</loginName>"+
+			"<firstName>%s</firstName><lastName>%s</lastName><extension>%s</extension><pendingState>%s</pendingState>"+
+			"<state>%s</state><stateChangeTime>%s</stateChangeTime></User>",
+			escape(a.LoginId), escape(a.LoginId), escape(a.LoginName), escape(a.FirstName), escape(a.LastName), escape(a.line),
+			a.pendingState, a.state, a.stateChangeTime.Format("2006-01-02T15:04:05.000Z")))
+	}
+	s.mutex.Unlock()
+	b.WriteString("</users></Team>")
+	s.writeXml(w, http.StatusOK, b.String())
+}
+
+func (s *Server) reasonCodesXml(category string) string {
+	var b strings.Builder
+	b.WriteString("<ReasonCodes>")
+	s.mutex.Lock()
+	for _, c := range s.reasonCodes {
+		if c.Category != category {
+			continue
+		}
+		data, _ := xml.Marshal(struct {
+			XMLName xml.Name `xml:"ReasonCode"`
+			api.ReasonCode
+		}{ReasonCode: c})
+		b.Write(data)
+	}
+	s.mutex.Unlock()
+	b.WriteString("</ReasonCodes>")
+	return b.String()
+}
+
+func (s *Server) wrapUpReasonsXml() string {
+	var b strings.Builder
+	b.WriteString("<WrapUpReasons>")
+	s.mutex.Lock()
+	for _, r := range s.wrapUpReasons {
+		data, _ := xml.Marshal(struct {
+			XMLName xml.Name `xml:"WrapUpReason"`
+			api.WrapUpReason
+		}{WrapUpReason: r})
+		b.Write(data)
+	}
+	s.mutex.Unlock()
+	b.WriteString("</WrapUpReasons>")
+	return b.String()
+}
+
+func (s *Server) systemInfo() string {
+	s.mutex.Lock()
+	status := s.status
+	s.mutex.Unlock()
+	return fmt.Sprintf("<SystemInfo><deploymentType>UCCE</deploymentType><status>%s</status>"+
+		"<xmppDomain>%s</xmppDomain><pubsubDomain>pubsub.%s</pubsubDomain><uri>/finesse/api/SystemInfo</uri></SystemInfo>",
+		escape(status), escape(s.Host), escape(s.Host))
+}
+
+func (s *Server) writeXml(w http.ResponseWriter, status int, body string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_, _ = io.WriteString(w, body)
+}
+
+// writeApiError write Finesse REST error body
+func writeApiError(w http.ResponseWriter, status int, errorType string, message string, data string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_, _ = fmt.Fprintf(w, "<ApiErrors><ApiError><ErrorData>%s</ErrorData><ErrorMessage>%s</ErrorMessage><ErrorType>%s</ErrorType></ApiError></ApiErrors>",
+		escape(data), escape(message), escape(errorType))
+}
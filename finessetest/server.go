@@ -0,0 +1,286 @@
+// Package finessetest provides in-process mock Finesse server for testing applications build on finesse_api.
+//
+// Mock server serves REST API /finesse/api/User/... with Basic authentication and publishes pubsub notifications
+// over WSS and plain XMPP endpoints. Notifications are created from samples in XMPP directory.
+//
+// Calls are scripted by AddDialog, SetParticipantState and EndDialog, agents control them by dialog requests.
+package finessetest
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"time"
+
+	api "github.com/pokornyIt/finesse-api"
+)
+
+var (
+	errInvalidState  = errors.New("invalid state")
+	errInvalidDevice = errors.New("invalid device")
+)
+
+// Server mock Finesse server with REST, WSS and plain XMPP endpoints
+type Server struct {
+	Host string // Host address where mock listen
+
+	rest      *httptest.Server
+	wss       *httptest.Server
+	xmpp      net.Listener
+	templates *templates
+
+	agents        map[string]*Agent // agents by login ID
+	reasonCodes   api.ReasonCodes
+	wrapUpReasons api.WrapUpReasons
+	sessions      map[string]map[*xmppSession]struct{}
+	status        string // status reported by SystemInfo
+	queues        []*Queue
+	teamMessages  []*teamMessage
+	teamMessageId int
+	dialogs       []*Dialog
+	dialogId      int
+	msgId         int
+	mutex         sync.Mutex
+	wg            sync.WaitGroup
+}
+
+// NewServer start mock server on random local ports
+func NewServer() (*Server, error) {
+	return NewServerPorts("127.0.0.1", 0, 0, 0)
+}
+
+// NewServerPorts start mock server on host with REST, WSS and plain XMPP ports, port 0 selects random port
+func NewServerPorts(host string, restPort int, wssPort int, xmppPort int) (*Server, error) {
+	t, err := loadTemplates()
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		Host:      host,
+		templates: t,
+		agents:    make(map[string]*Agent),
+		sessions:  make(map[string]map[*xmppSession]struct{}),
+		status:    api.SystemStatusInService,
+		reasonCodes: api.ReasonCodes{
+			{Id: 1, Category: api.ReasonCategoryNotReady, Code: "1", Label: "Lunch", ForAll: true, URI: "/finesse/api/ReasonCode/1"},
+			{Id: 2, Category: api.ReasonCategoryNotReady, Code: "2", Label: "Break", ForAll: true, URI: "/finesse/api/ReasonCode/2"},
+			{Id: 3, Category: api.ReasonCategoryNotReady, Code: "3", Label: "Meeting", ForAll: true, URI: "/finesse/api/ReasonCode/3"},
+			{Id: 4, Category: api.ReasonCategoryLogout, Code: "10", Label: "End of shift", ForAll: true, URI: "/finesse/api/ReasonCode/4"},
+		},
+		wrapUpReasons: api.WrapUpReasons{
+			{Id: 1, Label: "Sale", ForAll: true, URI: "/finesse/api/WrapUpReason/1"},
+			{Id: 2, Label: "No sale", ForAll: true, URI: "/finesse/api/WrapUpReason/2"},
+		},
+	}
+	if s.rest, err = startTls(host, restPort, http.HandlerFunc(s.serveRest)); err != nil {
+		return nil, err
+	}
+	if s.wss, err = startTls(host, wssPort, http.HandlerFunc(s.serveWebsocket)); err != nil {
+		s.rest.Close()
+		return nil, err
+	}
+	if s.xmpp, err = net.Listen("tcp", net.JoinHostPort(host, strconv.Itoa(xmppPort))); err != nil {
+		s.rest.Close()
+		s.wss.Close()
+		return nil, err
+	}
+	s.wg.Add(1)
+	go s.acceptXmpp()
+	return s, nil
+}
+
+func startTls(host string, port int, handler http.Handler) (*httptest.Server, error) {
+	l, err := net.Listen("tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		return nil, err
+	}
+	ts := httptest.NewUnstartedServer(handler)
+	_ = ts.Listener.Close()
+	ts.Listener = l
+	ts.StartTLS()
+	return ts, nil
+}
+
+// Close stop all endpoints and disconnect XMPP sessions
+func (s *Server) Close() {
+	_ = s.xmpp.Close()
+	s.DropConnections()
+	s.wss.Close()
+	s.rest.Close()
+	s.wg.Wait()
+}
+
+// RestPort port of REST API
+func (s *Server) RestPort() int {
+	return s.rest.Listener.Addr().(*net.TCPAddr).Port
+}
+
+// WssPort port of XMPP over WSS
+func (s *Server) WssPort() int {
+	return s.wss.Listener.Addr().(*net.TCPAddr).Port
+}
+
+// XmppPort port of plain XMPP
+func (s *Server) XmppPort() int {
+	return s.xmpp.Addr().(*net.TCPAddr).Port
+}
+
+// Certificate TLS certificate of REST and WSS endpoints
+func (s *Server) Certificate() *x509.Certificate {
+	return s.rest.Certificate()
+}
+
+// Finesse create finesse_api server connected to mock, insecureXmpp select plain XMPP instead of WSS
+func (s *Server) Finesse(insecureXmpp bool, timeOut ...int) *api.Server {
+	t := 5
+	if len(timeOut) > 0 {
+		t = timeOut[0]
+	}
+	port := s.WssPort()
+	if insecureXmpp {
+		port = s.XmppPort()
+	}
+	return api.NewServerDetail(s.Host, s.RestPort(), true, port, insecureXmpp, t)
+}
+
+// SetSystemStatus change status reported by SystemInfo, OUT_OF_SERVICE simulates failover or maintenance
+func (s *Server) SetSystemStatus(status string) {
+	s.mutex.Lock()
+	s.status = status
+	s.mutex.Unlock()
+}
+
+// AddSupervisor add agent with Agent and Supervisor roles
+func (s *Server) AddSupervisor(loginName string, loginId string, password string, extension string) *Agent {
+	a := s.AddAgent(loginName, loginId, password, extension)
+	s.mutex.Lock()
+	a.Roles = append(a.Roles, "Supervisor")
+	s.mutex.Unlock()
+	return a
+}
+
+// AddAgent add agent in LOGOUT state with Agent role
+func (s *Server) AddAgent(loginName string, loginId string, password string, extension string) *Agent {
+	a := &Agent{
+		LoginName: loginName,
+		LoginId:   loginId,
+		Password:  password,
+		Extension: extension,
+		FirstName: loginName,
+		LastName:  "Test",
+		Roles:     []string{"Agent"},
+		TeamId:    "5000",
+		TeamName:  "Default",
+	}
+	a.setState(api.AgentStateLogout, -1)
+	s.mutex.Lock()
+	s.agents[loginId] = a
+	s.mutex.Unlock()
+	return a
+}
+
+// AgentState actual state of agent on mock server, empty for unknown agent
+func (s *Server) AgentState(loginId string) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if a, ok := s.agents[loginId]; ok {
+		return a.State()
+	}
+	return ""
+}
+
+// AddReasonCode add Not Ready or Logout reason code
+func (s *Server) AddReasonCode(code api.ReasonCode) {
+	s.mutex.Lock()
+	s.reasonCodes = append(s.reasonCodes, code)
+	s.mutex.Unlock()
+}
+
+// SetAgentState change agent state outside of agent requests (e.g. supervisor) and notify agent sessions
+func (s *Server) SetAgentState(loginId string, state string, reasonCodeId ...int) error {
+	s.mutex.Lock()
+	a, ok := s.agents[loginId]
+	if !ok {
+		s.mutex.Unlock()
+		return fmt.Errorf("agent [%s] not exists", loginId)
+	}
+	reason := -1
+	if len(reasonCodeId) > 0 {
+		reason = reasonCodeId[0]
+	}
+	if state == api.AgentStateLogout {
+		a.line = ""
+	} else if a.line == "" {
+		a.line = a.Extension
+	}
+	a.setState(state, reason)
+	messages := s.userMessages(a, "")
+	s.mutex.Unlock()
+	for id, msg := range messages {
+		s.publish(id, msg)
+	}
+	return nil
+}
+
+// agent find agent by login ID or login name, must be called with locked mutex
+func (s *Server) agent(id string) (*Agent, bool) {
+	if a, ok := s.agents[id]; ok {
+		return a, true
+	}
+	for _, a := range s.agents {
+		if a.LoginName == id {
+			return a, true
+		}
+	}
+	return nil, false
+}
+
+// nextMsgId unique XMPP message ID, must be called with locked mutex
+func (s *Server) nextMsgId() string {
+	s.msgId++
+	return fmt.Sprintf("mock%06d", s.msgId)
+}
+
+// requestState process state change requested by user (agent or supervisor) and create XMPP notifications
+//
+// Errors are notified only to requesting user, change is notified to agent and supervisors of agent team.
+func (s *Server) requestState(user *Agent, a *Agent, state string, line string, reasonCodeId int, requestId string) {
+	s.mutex.Lock()
+	messages := make(map[string]string)
+	node := "/finesse/api/User/" + user.LoginId
+	switch a.changeState(state, line, reasonCodeId, user != a) {
+	case errInvalidState:
+		messages[user.LoginId] = s.templates.invalidState.message(s.Host, user.LoginId, node, s.nextMsgId(), s.templates.invalidState.errorUpdate(a, requestId))
+	case errInvalidDevice:
+		messages[user.LoginId] = s.templates.invalidDevice.message(s.Host, user.LoginId, node, s.nextMsgId(), s.templates.invalidDevice.errorUpdate(a, requestId))
+	default:
+		messages = s.userMessages(a, requestId)
+	}
+	s.mutex.Unlock()
+	go func() {
+		// Finesse confirms request asynchronously after REST response
+		time.Sleep(10 * time.Millisecond)
+		for id, msg := range messages {
+			s.publish(id, msg)
+		}
+	}()
+}
+
+// userMessages notifications of agent change for agent and supervisors of agent team, must be called with locked mutex
+func (s *Server) userMessages(a *Agent, requestId string) map[string]string {
+	update := s.templates.user.userUpdate(a, requestId)
+	messages := map[string]string{
+		a.LoginId: s.templates.user.message(s.Host, a.LoginId, "/finesse/api/User/"+a.LoginId, s.nextMsgId(), update),
+	}
+	for _, sup := range s.agents {
+		if sup != a && sup.isSupervisor() && sup.TeamId == a.TeamId {
+			messages[sup.LoginId] = s.templates.user.message(s.Host, sup.LoginId, "/finesse/api/Team/"+a.TeamId+"/Users", s.nextMsgId(), update)
+		}
+	}
+	return messages
+}
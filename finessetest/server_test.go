@@ -0,0 +1,129 @@
+package finessetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	api "github.com/pokornyIt/finesse-api"
+)
+
+// startAgent start mock with one agent and connect library agent over WSS or plain XMPP
+func startAgent(t *testing.T, insecureXmpp bool) (*Server, *api.Agent) {
+	t.Helper()
+	mock, err := NewServer()
+	if err != nil {
+		t.Fatalf("start mock server: %s", err)
+	}
+	t.Cleanup(mock.Close)
+	mock.AddAgent("agent1", "1001", "password", "2001")
+
+	server := mock.Finesse(insecureXmpp)
+	server.SetLogger(api.NewNopLogger())
+	agent, err := server.CreateAgent(context.Background(), "agent1", "password", "2001")
+	if err != nil {
+		t.Fatalf("create agent: %s", err)
+	}
+	t.Cleanup(agent.StopXmpp)
+	return mock, agent
+}
+
+// mockState actual agent state on mock server
+func mockState(mock *Server, loginId string) string {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+	return mock.agents[loginId].State()
+}
+
+func TestAgentStateChanges(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		insecureXmpp bool
+	}{
+		{name: "wss", insecureXmpp: false},
+		{name: "xmpp", insecureXmpp: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mock, agent := startAgent(t, tc.insecureXmpp)
+			steps := []struct {
+				name      string
+				operation func() api.OperationError
+				state     string
+			}{
+				{name: "login", operation: agent.Login, state: api.AgentStateNotReady},
+				{name: "ready", operation: func() api.OperationError { return agent.Ready() }, state: api.AgentStateReady},
+				{name: "not-ready", operation: agent.NotReady, state: api.AgentStateNotReady},
+				{name: "logout", operation: func() api.OperationError { return agent.Logout() }, state: api.AgentStateLogout},
+			}
+			for _, step := range steps {
+				if errOp := step.operation(); errOp.Type != api.TypeErrorNoError {
+					t.Fatalf("%s: unexpected error type [%d]: %s", step.name, errOp.Type, errOp.Error)
+				}
+				if state := agent.GetLastStatus().State; state != step.state {
+					t.Errorf("%s: agent state is [%s], expected [%s]", step.name, state, step.state)
+				}
+				if state := mockState(mock, "1001"); state != step.state {
+					t.Errorf("%s: mock state is [%s], expected [%s]", step.name, state, step.state)
+				}
+			}
+		})
+	}
+}
+
+func TestAgentInvalidState(t *testing.T) {
+	mock, agent := startAgent(t, true)
+	if errOp := agent.Login(); errOp.Type != api.TypeErrorNoError {
+		t.Fatalf("login: unexpected error type [%d]: %s", errOp.Type, errOp.Error)
+	}
+	// agent is logged out on server without notification, library still expects NOT_READY
+	mock.mutex.Lock()
+	mock.agents["1001"].setState(api.AgentStateLogout, -1)
+	mock.mutex.Unlock()
+
+	errOp := agent.Ready()
+	if errOp.Type != api.TypeErrorAnalyzeResponse {
+		t.Fatalf("ready: error type is [%d], expected [%d]: %s", errOp.Type, api.TypeErrorAnalyzeResponse, errOp.Error)
+	}
+	if !errors.Is(errOp.Error, api.ErrInvalidState) {
+		t.Errorf("ready: error [%s] is not ErrInvalidState", errOp.Error)
+	}
+	var finesseError *api.FinesseError
+	if !errors.As(errOp.Error, &finesseError) || finesseError.ErrorType != "Invalid State" {
+		t.Errorf("ready: error [%s] is not Finesse error with type [Invalid State]", errOp.Error)
+	}
+	if state := mockState(mock, "1001"); state != api.AgentStateLogout {
+		t.Errorf("mock state is [%s], expected [%s]", state, api.AgentStateLogout)
+	}
+}
+
+func TestScriptedDialog(t *testing.T) {
+	mock, agent := startAgent(t, true)
+	if errOp := agent.Login(); errOp.Type != api.TypeErrorNoError {
+		t.Fatalf("login: unexpected error type [%d]: %s", errOp.Type, errOp.Error)
+	}
+	d := mock.AddDialog("5550100", "2001", map[string]string{"callVariable1": "case 42"})
+	dialogs, err := agent.GetDialogs()
+	if err != nil || len(dialogs) != 1 {
+		t.Fatalf("dialogs are %v (%v)", dialogs, err)
+	}
+	if status := dialogs[0].GetLastStatus(); status.ID != d.Id || status.State != api.DialogStateAlerting || status.FromAddress != "5550100" {
+		t.Errorf("dialog status is %+v", status)
+	}
+	if err = mock.SetParticipantState(d.Id, "9999", api.DialogStateActive); err == nil {
+		t.Errorf("state set for unknown participant")
+	}
+
+	// caller hangs up before answer, dialog with one participant ends
+	if err = mock.SetParticipantState(d.Id, "5550100", api.DialogStateDropped); err != nil {
+		t.Fatalf("drop caller: %s", err)
+	}
+	if state := mock.ParticipantState(d.Id, "2001"); state != "" {
+		t.Errorf("participant of ended dialog is in [%s] state", state)
+	}
+	if _, _, ok := mock.CallData(d.Id); ok {
+		t.Errorf("call data of ended dialog exist")
+	}
+	if dialogs, err = agent.GetDialogs(); err != nil || len(dialogs) != 0 {
+		t.Errorf("dialogs after end are %v (%v)", dialogs, err)
+	}
+}
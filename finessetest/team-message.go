@@ -0,0 +1,161 @@
+package finessetest
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	api "github.com/pokornyIt/finesse-api"
+)
+
+// teamMessage team message created by supervisor
+type teamMessage struct {
+	id        string
+	content   string
+	createdBy *Agent
+	createdAt time.Time
+	duration  int // duration in seconds
+	teams     []string
+}
+
+// teamMessageRequest body of POST /finesse/api/TeamMessage
+type teamMessageRequest struct {
+	Duration int    `xml:"duration"`
+	Content  string `xml:"content"`
+	Teams    struct {
+		Team []string `xml:"team"`
+	} `xml:"teams"`
+}
+
+// expired message is not displayed
+func (m *teamMessage) expired(now time.Time) bool {
+	return !now.Before(m.createdAt.Add(time.Duration(m.duration) * time.Second))
+}
+
+// forTeam message is sent to team
+func (m *teamMessage) forTeam(teamId string) bool {
+	for _, t := range m.teams {
+		if t == teamId {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *teamMessage) xml() string {
+	var teams strings.Builder
+	for _, t := range m.teams {
+		teams.WriteString("<team>" + escape(t) + "</team>")
+	}
+	return fmt.Sprintf("<TeamMessage><uri>/finesse/api/TeamMessage/%s</uri><id>%s</id><createdBy><id>%s</id>"+
+		"<firstName>%s</firstName><lastName>%s</lastName></createdBy><createdAt>%d</createdAt><duration>%d</duration>"+
+		"<content>%s</content><teams>%s</teams></TeamMessage>",
+		escape(m.id), escape(m.id), escape(m.createdBy.LoginId), escape(m.createdBy.FirstName), escape(m.createdBy.LastName),
+		m.createdAt.UnixMilli(), m.duration, escape(m.content), teams.String())
+}
+
+// postTeamMessage create team message, only supervisor of all target teams
+func (s *Server) postTeamMessage(w http.ResponseWriter, r *http.Request, user *Agent) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeApiError(w, http.StatusBadRequest, "Invalid Input", "Problem read request body", "")
+		return
+	}
+	var req teamMessageRequest
+	if err = xml.Unmarshal(data, &req); err != nil || len(req.Content) == 0 || len(req.Teams.Team) == 0 {
+		writeApiError(w, http.StatusBadRequest, "Invalid Input", "Invalid team message specified", "")
+		return
+	}
+	if req.Duration < 1 || req.Duration > 86400 {
+		writeApiError(w, http.StatusBadRequest, "Invalid Input", "Invalid duration specified", strconv.Itoa(req.Duration))
+		return
+	}
+	if !user.isSupervisor() {
+		writeApiError(w, http.StatusUnauthorized, "Authorization Failure", "User is not supervisor", user.LoginId)
+		return
+	}
+	for _, t := range req.Teams.Team {
+		if t != user.TeamId {
+			writeApiError(w, http.StatusUnauthorized, "Authorization Failure", "User is not supervisor of team", t)
+			return
+		}
+	}
+	s.mutex.Lock()
+	s.teamMessageId++
+	m := &teamMessage{
+		id:        strconv.Itoa(s.teamMessageId),
+		content:   req.Content,
+		createdBy: user,
+		createdAt: time.Now(),
+		duration:  req.Duration,
+		teams:     req.Teams.Team,
+	}
+	s.teamMessages = append(s.teamMessages, m)
+	messages := s.teamMessageMessages(m, "POST")
+	s.mutex.Unlock()
+	w.Header().Set("Location", "/finesse/api/TeamMessage/"+m.id)
+	w.WriteHeader(http.StatusCreated)
+	for id, msg := range messages {
+		s.publish(id, msg)
+	}
+}
+
+// deleteTeamMessage remove team message, only supervisor of message teams
+func (s *Server) deleteTeamMessage(w http.ResponseWriter, user *Agent, id string) {
+	s.mutex.Lock()
+	index := -1
+	for i, m := range s.teamMessages {
+		if m.id == id {
+			index = i
+		}
+	}
+	if index < 0 {
+		s.mutex.Unlock()
+		writeApiError(w, http.StatusNotFound, "Not Found", "Team message not found", id)
+		return
+	}
+	m := s.teamMessages[index]
+	if !user.isSupervisor() || !m.forTeam(user.TeamId) {
+		s.mutex.Unlock()
+		writeApiError(w, http.StatusUnauthorized, "Authorization Failure", "User is not supervisor of team", id)
+		return
+	}
+	s.teamMessages = append(s.teamMessages[:index], s.teamMessages[index+1:]...)
+	messages := s.teamMessageMessages(m, "DELETE")
+	s.mutex.Unlock()
+	w.WriteHeader(http.StatusAccepted)
+	for loginId, msg := range messages {
+		s.publish(loginId, msg)
+	}
+}
+
+// teamMessagesXml not expired messages for team, must be called with locked mutex
+func (s *Server) teamMessagesXml(teamId string) string {
+	now := time.Now()
+	var b strings.Builder
+	b.WriteString("<TeamMessages>")
+	for _, m := range s.teamMessages {
+		if m.forTeam(teamId) && !m.expired(now) {
+			b.WriteString(m.xml())
+		}
+	}
+	b.WriteString("</TeamMessages>")
+	return b.String()
+}
+
+// teamMessageMessages notifications of team message for logged-in members of message teams, must be called with locked mutex
+func (s *Server) teamMessageMessages(m *teamMessage, event string) map[string]string {
+	update := fmt.Sprintf("<Update><data>%s</data><event>%s</event><requestId></requestId><source>/finesse/api/TeamMessage/%s</source></Update>",
+		m.xml(), event, escape(m.id))
+	messages := make(map[string]string)
+	for _, a := range s.agents {
+		if a.state != api.AgentStateLogout && m.forTeam(a.TeamId) {
+			messages[a.LoginId] = s.templates.user.message(s.Host, a.LoginId, "/finesse/api/Team/"+a.TeamId+"/TeamMessages", s.nextMsgId(), update)
+		}
+	}
+	return messages
+}
@@ -0,0 +1,126 @@
+package finessetest
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	xmpp_samples "github.com/pokornyIt/finesse-api/XMPP"
+)
+
+const (
+	notificationOpen  = `<notification xmlns="urn:xmpp:push:0">`
+	notificationClose = `</notification>`
+)
+
+// template one XMPP sample split into message wrapper and Finesse update
+type template struct {
+	prefix string // prefix XMPP message up to notification content
+	update string // update unescaped Finesse update XML
+	suffix string // suffix XMPP message after notification content
+}
+
+// templates all samples used by mock server
+type templates struct {
+	user          *template
+	invalidState  *template
+	invalidDevice *template
+}
+
+var (
+	reRoles = regexp.MustCompile(`(?s)<roles>.*</roles>`)
+	reTeams = regexp.MustCompile(`(?s)<teams>.*</teams>`)
+	reUser  = regexp.MustCompile(`(?s)<user>.*</user>`)
+)
+
+func loadTemplate(name string) (*template, error) {
+	msg, err := xmpp_samples.Message(name)
+	if err != nil {
+		return nil, err
+	}
+	start := strings.Index(msg, notificationOpen)
+	end := strings.Index(msg, notificationClose)
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("sample [%s] does not contain notification", name)
+	}
+	start += len(notificationOpen)
+	return &template{
+		prefix: strings.Replace(msg[:start], "<message ", `<message xmlns="jabber:client" `, 1),
+		update: html.UnescapeString(strings.TrimSpace(msg[start:end])),
+		suffix: msg[end:],
+	}, nil
+}
+
+func loadTemplates() (*templates, error) {
+	var err error
+	t := &templates{}
+	if t.user, err = loadTemplate(xmpp_samples.NotReady); err != nil {
+		return nil, err
+	}
+	if t.invalidState, err = loadTemplate(xmpp_samples.ErrorInvalidState); err != nil {
+		return nil, err
+	}
+	if t.invalidDevice, err = loadTemplate(xmpp_samples.ErrorInvalidDevice); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// message create XMPP message for user with update published on pubsub node
+func (t *template) message(domain string, loginId string, node string, msgId string, update string) string {
+	prefix := setAttribute(t.prefix, "from", "pubsub."+domain)
+	prefix = setAttribute(prefix, "to", loginId+"@"+domain)
+	prefix = setAttribute(prefix, "id", msgId)
+	prefix = setAttribute(prefix, "node", node)
+	return prefix + "\n" + escape(update) + "\n" + t.suffix
+}
+
+// userUpdate fill user template with agent data
+func (t *template) userUpdate(a *Agent, requestId string) string {
+	update := reRoles.ReplaceAllLiteralString(t.update, a.rolesXml())
+	update = reTeams.ReplaceAllLiteralString(update, a.teamsXml())
+	for _, v := range a.userValues() {
+		update = setElement(update, v[0], v[1])
+	}
+	update = setElement(update, "requestId", requestId)
+	update = setElement(update, "source", "/finesse/api/User/"+a.LoginId)
+	return update
+}
+
+// userXml REST representation of user created from user template
+func (t *template) userXml(a *Agent) string {
+	user := reUser.FindString(t.userUpdate(a, ""))
+	return "<User>" + strings.TrimSuffix(strings.TrimPrefix(user, "<user>"), "</user>") + "</User>"
+}
+
+// errorUpdate fill error template for agent
+func (t *template) errorUpdate(a *Agent, requestId string) string {
+	update := setElement(t.update, "requestId", requestId)
+	return setElement(update, "source", "/finesse/api/User/"+a.LoginId)
+}
+
+// escape escape text for XML
+func escape(text string) string {
+	var b bytes.Buffer
+	_ = xml.EscapeText(&b, []byte(text))
+	return b.String()
+}
+
+// setElement set text value of all elements with tag
+func setElement(data string, tag string, value string) string {
+	re := regexp.MustCompile(`<` + tag + `>[^<]*</` + tag + `>`)
+	return re.ReplaceAllLiteralString(data, "<"+tag+">"+escape(value)+"</"+tag+">")
+}
+
+// setAttribute set value of first attribute with name
+func setAttribute(data string, name string, value string) string {
+	re := regexp.MustCompile(` ` + name + `="[^"]*"`)
+	loc := re.FindStringIndex(data)
+	if loc == nil {
+		return data
+	}
+	return data[:loc[0]] + " " + name + `="` + html.EscapeString(value) + `"` + data[loc[1]:]
+}
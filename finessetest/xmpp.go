@@ -0,0 +1,279 @@
+package finessetest
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"nhooyr.io/websocket"
+)
+
+const (
+	nsFraming = "urn:ietf:params:xml:ns:xmpp-framing"
+	nsStream  = "http://etherx.jabber.org/streams"
+	nsSasl    = "urn:ietf:params:xml:ns:xmpp-sasl"
+	nsBind    = "urn:ietf:params:xml:ns:xmpp-bind"
+)
+
+// xmppSession one connected XMPP client (plain or over WSS)
+type xmppSession struct {
+	loginId string
+	conn    net.Conn
+	framing bool // framing RFC 7395 websocket framing instead of stream
+	mutex   sync.Mutex
+}
+
+// saslAuth SASL PLAIN authentication request
+type saslAuth struct {
+	Mechanism string `xml:"mechanism,attr"`
+	Value     string `xml:",chardata"`
+}
+
+func (x *xmppSession) write(data string) error {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+	_, err := io.WriteString(x.conn, data)
+	return err
+}
+
+// closeStream answer stream close, client waits for it before disconnect
+func (x *xmppSession) closeStream() {
+	if x.framing {
+		_ = x.write(fmt.Sprintf("<close xmlns='%s'/>", nsFraming))
+		return
+	}
+	_ = x.write("</stream:stream>")
+}
+
+// acceptXmpp accept plain XMPP connections (port 5222 on Finesse)
+func (s *Server) acceptXmpp() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.xmpp.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.serveXmpp(&xmppSession{conn: conn})
+		}()
+	}
+}
+
+// serveWebsocket accept XMPP over websocket connections (port 7443 on Finesse)
+func (s *Server) serveWebsocket(w http.ResponseWriter, r *http.Request) {
+	c, err := websocket.Accept(w, r, &websocket.AcceptOptions{Subprotocols: []string{"xmpp"}, InsecureSkipVerify: true})
+	if err != nil {
+		return
+	}
+	if c.Subprotocol() != "xmpp" {
+		_ = c.Close(websocket.StatusPolicyViolation, "xmpp subprotocol required")
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.serveXmpp(&xmppSession{conn: websocket.NetConn(ctx, c, websocket.MessageText), framing: true})
+}
+
+// serveXmpp negotiate session and keep it open until client close it or server drop connection
+func (s *Server) serveXmpp(x *xmppSession) {
+	s.register(x)
+	defer s.unregister(x)
+	reader := bufio.NewReader(x.conn)
+	if err := s.negotiate(x, reader); err != nil {
+		return
+	}
+	s.mutex.Lock()
+	delete(s.sessions[""], x)
+	if s.sessions[x.loginId] == nil {
+		s.sessions[x.loginId] = make(map[*xmppSession]struct{})
+	}
+	s.sessions[x.loginId][x] = struct{}{}
+	s.mutex.Unlock()
+	defer x.closeStream()
+
+	d := xml.NewDecoder(reader)
+	for {
+		t, err := d.Token()
+		if err != nil {
+			return
+		}
+		switch e := t.(type) {
+		case xml.StartElement:
+			if e.Name.Space == nsFraming && e.Name.Local == "close" {
+				return
+			}
+			if err = d.Skip(); err != nil {
+				return
+			}
+		case xml.EndElement:
+			// end of stream
+			return
+		}
+	}
+}
+
+// negotiate open stream, authenticate (SASL PLAIN) and bind resource
+func (s *Server) negotiate(x *xmppSession, reader *bufio.Reader) error {
+	d := xml.NewDecoder(reader)
+	if err := s.openStream(x, d, fmt.Sprintf("<mechanisms xmlns='%s'><mechanism>PLAIN</mechanism></mechanisms>", nsSasl)); err != nil {
+		return err
+	}
+	e, err := nextElement(d)
+	if err != nil {
+		return err
+	}
+	var auth saslAuth
+	if err = d.DecodeElement(&auth, &e); err != nil {
+		return err
+	}
+	if x.loginId, err = s.authenticateSasl(auth); err != nil {
+		_ = x.write(fmt.Sprintf("<failure xmlns='%s'><not-authorized/></failure>", nsSasl))
+		return err
+	}
+	if err = x.write(fmt.Sprintf("<success xmlns='%s'/>", nsSasl)); err != nil {
+		return err
+	}
+
+	// stream restart after authentication
+	d = xml.NewDecoder(reader)
+	if err = s.openStream(x, d, fmt.Sprintf("<bind xmlns='%s'/>", nsBind)); err != nil {
+		return err
+	}
+	if e, err = nextElement(d); err != nil {
+		return err
+	}
+	id := ""
+	for _, a := range e.Attr {
+		if a.Name.Local == "id" {
+			id = a.Value
+		}
+	}
+	if err = d.Skip(); err != nil {
+		return err
+	}
+	return x.write(fmt.Sprintf("<iq xmlns='jabber:client' type='result' id='%s'><bind xmlns='%s'><jid>%s@%s/finesse</jid></bind></iq>",
+		escape(id), nsBind, escape(x.loginId), escape(s.Host)))
+}
+
+// openStream read stream open from client and send stream header with features
+func (s *Server) openStream(x *xmppSession, d *xml.Decoder, features string) error {
+	e, err := nextElement(d)
+	if err != nil {
+		return err
+	}
+	if !(e.Name.Space == nsStream && e.Name.Local == "stream") && !(e.Name.Space == nsFraming && e.Name.Local == "open") {
+		return fmt.Errorf("expected stream open, got <%s>", e.Name.Local)
+	}
+	if x.framing {
+		// framing open is complete element
+		if err = d.Skip(); err != nil {
+			return err
+		}
+		err = x.write(fmt.Sprintf("<open xmlns='%s' from='%s' id='%s' version='1.0'/>", nsFraming, escape(s.Host), s.streamId()))
+	} else {
+		err = x.write(fmt.Sprintf("<?xml version='1.0'?><stream:stream from='%s' id='%s' xmlns='jabber:client' xmlns:stream='%s' version='1.0'>",
+			escape(s.Host), s.streamId(), nsStream))
+	}
+	if err != nil {
+		return err
+	}
+	return x.write(fmt.Sprintf("<stream:features xmlns:stream='%s'>%s</stream:features>", nsStream, features))
+}
+
+// authenticateSasl check SASL PLAIN credentials, returns login ID
+func (s *Server) authenticateSasl(auth saslAuth) (string, error) {
+	if auth.Mechanism != "PLAIN" {
+		return "", fmt.Errorf("unsupported mechanism [%s]", auth.Mechanism)
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(auth.Value))
+	if err != nil {
+		return "", err
+	}
+	parts := strings.Split(string(data), "\x00")
+	if len(parts) != 3 {
+		return "", errors.New("invalid PLAIN credentials")
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	a, ok := s.agents[parts[1]]
+	if !ok || a.Password != parts[2] {
+		return "", errors.New("not authorized")
+	}
+	return a.LoginId, nil
+}
+
+func (s *Server) streamId() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.nextMsgId()
+}
+
+// nextElement skip to next start element
+func nextElement(d *xml.Decoder) (xml.StartElement, error) {
+	for {
+		t, err := d.Token()
+		if err != nil {
+			return xml.StartElement{}, err
+		}
+		if e, ok := t.(xml.StartElement); ok {
+			return e, nil
+		}
+	}
+}
+
+// register add not authenticated session
+func (s *Server) register(x *xmppSession) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.sessions[""] == nil {
+		s.sessions[""] = make(map[*xmppSession]struct{})
+	}
+	s.sessions[""][x] = struct{}{}
+}
+
+// unregister remove session and close connection
+func (s *Server) unregister(x *xmppSession) {
+	s.mutex.Lock()
+	delete(s.sessions[""], x)
+	delete(s.sessions[x.loginId], x)
+	s.mutex.Unlock()
+	_ = x.conn.Close()
+}
+
+// publish send XMPP message to all sessions of agent
+func (s *Server) publish(loginId string, msg string) {
+	s.mutex.Lock()
+	list := make([]*xmppSession, 0, len(s.sessions[loginId]))
+	for x := range s.sessions[loginId] {
+		list = append(list, x)
+	}
+	s.mutex.Unlock()
+	for _, x := range list {
+		_ = x.write(msg)
+	}
+}
+
+// DropConnections close all XMPP sessions, simulate restart of notification service
+func (s *Server) DropConnections() {
+	s.mutex.Lock()
+	list := make([]*xmppSession, 0)
+	for _, m := range s.sessions {
+		for x := range m {
+			list = append(list, x)
+		}
+	}
+	s.mutex.Unlock()
+	for _, x := range list {
+		_ = x.conn.Close()
+	}
+}
@@ -0,0 +1,119 @@
+package finesse_api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	GroupOperationAdd = "ADD" // GroupOperationAdd operation of AgentGroup.AddBulkAgents
+)
+
+// AgentResult result of group operation for one agent
+type AgentResult struct {
+	LoginName string         // LoginName agent login name
+	Operation string         // Operation requested operation (agent state or GroupOperationAdd)
+	Started   time.Time      // Started time when operation starts
+	Duration  time.Duration  // Duration of operation including wait for notification
+	State     string         // State final agent state after operation, empty when state is unknown
+	Error     OperationError // Error result of operation, Type is TypeErrorNoError for success
+}
+
+// GroupResult results of group operation keyed by agent login name
+type GroupResult map[string]AgentResult
+
+// agentResultReport one row of exported report
+type agentResultReport struct {
+	LoginName  string    `json:"loginName"`
+	Operation  string    `json:"operation"`
+	Started    time.Time `json:"started"`
+	DurationMs int64     `json:"durationMs"`
+	State      string    `json:"state"`
+	ErrorType  int       `json:"errorType"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Success operation finished without error
+func (r AgentResult) Success() bool {
+	return r.Error.Type == TypeErrorNoError
+}
+
+func (r AgentResult) report() agentResultReport {
+	rep := agentResultReport{
+		LoginName:  r.LoginName,
+		Operation:  r.Operation,
+		Started:    r.Started,
+		DurationMs: r.Duration.Milliseconds(),
+		State:      r.State,
+		ErrorType:  r.Error.Type,
+	}
+	if r.Error.Error != nil {
+		rep.Error = r.Error.Error.Error()
+	}
+	return rep
+}
+
+// Names sorted login names of agents in result
+func (g GroupResult) Names() []string {
+	names := make([]string, 0, len(g))
+	for name := range g {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Failed sorted login names of agents with failed operation
+func (g GroupResult) Failed() []string {
+	var names []string
+	for _, name := range g.Names() {
+		if !g[name].Success() {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Errors operation errors of failed agents keyed by login name
+func (g GroupResult) Errors() map[string]OperationError {
+	ret := make(map[string]OperationError)
+	for name, r := range g {
+		if !r.Success() {
+			ret[name] = r.Error
+		}
+	}
+	return ret
+}
+
+// WriteCsv export report as CSV with header, rows are sorted by login name
+func (g GroupResult) WriteCsv(w io.Writer) error {
+	c := csv.NewWriter(w)
+	if err := c.Write([]string{"loginName", "operation", "started", "durationMs", "state", "errorType", "error"}); err != nil {
+		return err
+	}
+	for _, name := range g.Names() {
+		r := g[name].report()
+		err := c.Write([]string{r.LoginName, r.Operation, r.Started.Format(time.RFC3339Nano), strconv.FormatInt(r.DurationMs, 10),
+			r.State, strconv.Itoa(r.ErrorType), r.Error})
+		if err != nil {
+			return err
+		}
+	}
+	c.Flush()
+	return c.Error()
+}
+
+// WriteJson export report as JSON array, items are sorted by login name
+func (g GroupResult) WriteJson(w io.Writer) error {
+	rows := make([]agentResultReport, 0, len(g))
+	for _, name := range g.Names() {
+		rows = append(rows, g[name].report())
+	}
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(rows)
+}
@@ -0,0 +1,47 @@
+//go:build go1.21
+
+package finesse_api
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+)
+
+// LevelTrace slog level of trace records, lower than slog.LevelDebug
+const LevelTrace = slog.LevelDebug - 4
+
+var slogLevels = map[LogLevel]slog.Level{LogLevelTrace: LevelTrace, LogLevelDebug: slog.LevelDebug,
+	LogLevelInfo: slog.LevelInfo, LogLevelWarn: slog.LevelWarn, LogLevelError: slog.LevelError}
+
+// slogLogger adapter for log/slog
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlogLogger logger writes records to slog logger with fields as attributes, nil is slog.Default()
+//
+// Function exists only in build by Go 1.21 and newer, go.mod keeps Go 1.19 for logrus and no-op adapters.
+func NewSlogLogger(l *slog.Logger) Logger {
+	if l == nil {
+		l = slog.Default()
+	}
+	return &slogLogger{logger: l}
+}
+
+func (l *slogLogger) Enabled(level LogLevel) bool {
+	return l.logger.Enabled(context.Background(), slogLevels[level])
+}
+
+func (l *slogLogger) Log(level LogLevel, fields Fields, msg string) {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	attrs := make([]slog.Attr, 0, len(keys))
+	for _, k := range keys {
+		attrs = append(attrs, slog.Any(k, fields[k]))
+	}
+	l.logger.LogAttrs(context.Background(), slogLevels[level], msg, attrs...)
+}
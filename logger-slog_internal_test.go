@@ -0,0 +1,36 @@
+//go:build go1.21
+
+package finesse_api
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewSlogLogger(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: LevelTrace})))
+	entry := logSettings{logger: l}.withFields(Fields{logProc: "doRequest", logBody: "<User/>", "password": "secret", logAgent: "agent1"})
+	entry.Tracef("request %d", 1)
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("parse record %q: %s", buf.String(), err)
+	}
+	for k, exp := range map[string]string{"level": "DEBUG-4", "msg": "request 1", logBody: logRedacted, "password": logRedacted,
+		logAgent: "agent1", logProc: "doRequest"} {
+		if record[k] != exp {
+			t.Errorf("attribute [%s] is [%v], expected [%s]", k, record[k], exp)
+		}
+	}
+
+	info := NewSlogLogger(slog.New(slog.NewJSONHandler(&buf, nil)))
+	if info.Enabled(LogLevelTrace) || info.Enabled(LogLevelDebug) || !info.Enabled(LogLevelInfo) {
+		t.Errorf("enabled levels not follow slog handler level")
+	}
+	if NewSlogLogger(nil).(*slogLogger).logger != slog.Default() {
+		t.Errorf("nil logger is not slog.Default()")
+	}
+}
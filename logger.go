@@ -0,0 +1,248 @@
+package finesse_api
+
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"strings"
+	"sync/atomic"
+)
+
+// LogLevel severity of library log record
+type LogLevel int
+
+const (
+	LogLevelTrace LogLevel = iota // LogLevelTrace detail of requests and notifications
+	LogLevelDebug                 // LogLevelDebug connection and configuration details
+	LogLevelInfo                  // LogLevelInfo important changes (reconnect, failover)
+	LogLevelWarn                  // LogLevelWarn recoverable problems
+	LogLevelError                 // LogLevelError failed requests and operations
+)
+
+const (
+	logRedacted = "[redacted]" // logRedacted replacement of request bodies and passwords in log records
+)
+
+var logLevelNames = map[LogLevel]string{LogLevelTrace: "trace", LogLevelDebug: "debug", LogLevelInfo: "info",
+	LogLevelWarn: "warn", LogLevelError: "error"}
+
+func (l LogLevel) String() string {
+	if name, ok := logLevelNames[l]; ok {
+		return name
+	}
+	return fmt.Sprintf("level(%d)", int(l))
+}
+
+// Fields structured fields of log record, keys are e.g. "proc", "requestId", "agentName", "server"
+type Fields map[string]interface{}
+
+// Logger destination of library log records, set by Server.SetLogger and inherited by Agent and AgentGroup
+//
+// Enabled is called before record is formatted, Log must be safe for concurrent use. Adapters are NewLogrusLogger,
+// NewNopLogger and NewSlogLogger, which is built only by Go 1.21 and newer (build tag go1.21) because module
+// supports Go 1.19 without log/slog.
+type Logger interface {
+	Enabled(level LogLevel) bool
+	Log(level LogLevel, fields Fields, msg string)
+}
+
+// loggerHolder allows store interface in atomic.Value
+type loggerHolder struct {
+	logger Logger
+}
+
+var defaultLogger atomic.Value
+
+// SetDefaultLogger set logger used by servers without own logger, nil restores global logrus logger
+func SetDefaultLogger(l Logger) {
+	if l == nil {
+		l = NewLogrusLogger(nil)
+	}
+	defaultLogger.Store(loggerHolder{logger: l})
+}
+
+// getDefaultLogger logger used by servers without own logger
+func getDefaultLogger() Logger {
+	if h, ok := defaultLogger.Load().(loggerHolder); ok {
+		return h.logger
+	}
+	return NewLogrusLogger(nil)
+}
+
+// loadLogger logger stored in value or nil
+func loadLogger(v *atomic.Value) Logger {
+	if h, ok := v.Load().(loggerHolder); ok {
+		return h.logger
+	}
+	return nil
+}
+
+// logrusLogger adapter for github.com/sirupsen/logrus
+type logrusLogger struct {
+	logger *logrus.Logger
+}
+
+var logrusLevels = map[LogLevel]logrus.Level{LogLevelTrace: logrus.TraceLevel, LogLevelDebug: logrus.DebugLevel,
+	LogLevelInfo: logrus.InfoLevel, LogLevelWarn: logrus.WarnLevel, LogLevelError: logrus.ErrorLevel}
+
+// NewLogrusLogger logger writes records to logrus logger, nil is global logrus logger (default of library)
+func NewLogrusLogger(l *logrus.Logger) Logger {
+	if l == nil {
+		l = logrus.StandardLogger()
+	}
+	return &logrusLogger{logger: l}
+}
+
+func (l *logrusLogger) Enabled(level LogLevel) bool {
+	return l.logger.IsLevelEnabled(logrusLevels[level])
+}
+
+func (l *logrusLogger) Log(level LogLevel, fields Fields, msg string) {
+	l.logger.WithFields(logrus.Fields(fields)).Log(logrusLevels[level], msg)
+}
+
+// nopLogger discards all records
+type nopLogger struct{}
+
+// NewNopLogger logger discards all records
+func NewNopLogger() Logger {
+	return nopLogger{}
+}
+
+func (nopLogger) Enabled(LogLevel) bool        { return false }
+func (nopLogger) Log(LogLevel, Fields, string) {}
+
+// logSettings logger and redaction of records for server, agent or request
+type logSettings struct {
+	logger    Logger // logger nil is default logger
+	logBodies bool   // logBodies log request bodies, otherwise logBody field is redacted
+}
+
+// logEntry log record prepared with fields, message is formatted only for enabled level
+type logEntry struct {
+	logger    Logger
+	fields    Fields
+	logBodies bool
+}
+
+// withFields prepare record with fields
+func (l logSettings) withFields(fields Fields) *logEntry {
+	logger := l.logger
+	if logger == nil {
+		logger = getDefaultLogger()
+	}
+	return &logEntry{logger: logger, fields: fields, logBodies: l.logBodies}
+}
+
+// withFields record for default logger, used where no server is known
+func withFields(fields Fields) *logEntry {
+	return logSettings{}.withFields(fields)
+}
+
+// SetLogger set logger for server and its agents and requests, nil is default logger (SetDefaultLogger)
+func (s *Server) SetLogger(l Logger) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	settings := s.logSettings()
+	settings.logger = l
+	s.logging.Store(settings)
+}
+
+// SetLogBodies log request bodies at trace level, bodies are redacted by default
+func (s *Server) SetLogBodies(enabled bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	settings := s.logSettings()
+	settings.logBodies = enabled
+	s.logging.Store(settings)
+}
+
+// logSettings actual log settings of server
+func (s *Server) logSettings() logSettings {
+	settings, _ := s.logging.Load().(logSettings)
+	return settings
+}
+
+// withFields prepare record for server logger
+func (s *Server) withFields(fields Fields) *logEntry {
+	return s.logSettings().withFields(fields)
+}
+
+// SetLogger set logger for agent, nil is logger of agent server
+func (a *Agent) SetLogger(l Logger) {
+	a.logger.Store(loggerHolder{logger: l})
+}
+
+// logSettings log settings of agent server with agent logger
+func (a *Agent) logSettings() logSettings {
+	settings := a.getServer().logSettings()
+	if l := loadLogger(&a.logger); l != nil {
+		settings.logger = l
+	}
+	return settings
+}
+
+// withFields prepare record for agent logger
+func (a *Agent) withFields(fields Fields) *logEntry {
+	return a.logSettings().withFields(fields)
+}
+
+// SetLogger set logger for group operations, without logger group uses logger of first added agent server
+func (group *AgentGroup) SetLogger(l Logger) {
+	group.logger.Store(loggerHolder{logger: l})
+}
+
+// inheritLogger use server logger for group without own logger
+func (group *AgentGroup) inheritLogger(s *Server) {
+	if l := s.logSettings().logger; l != nil {
+		group.logger.CompareAndSwap(nil, loggerHolder{logger: l})
+	}
+}
+
+// withFields prepare record for group logger
+func (group *AgentGroup) withFields(fields Fields) *logEntry {
+	return logSettings{logger: loadLogger(&group.logger)}.withFields(fields)
+}
+
+func (e *logEntry) Trace(args ...interface{}) { e.log(LogLevelTrace, fmt.Sprint(args...)) }
+func (e *logEntry) Debug(args ...interface{}) { e.log(LogLevelDebug, fmt.Sprint(args...)) }
+func (e *logEntry) Info(args ...interface{})  { e.log(LogLevelInfo, fmt.Sprint(args...)) }
+func (e *logEntry) Warn(args ...interface{})  { e.log(LogLevelWarn, fmt.Sprint(args...)) }
+func (e *logEntry) Error(args ...interface{}) { e.log(LogLevelError, fmt.Sprint(args...)) }
+
+func (e *logEntry) Tracef(format string, args ...interface{}) { e.logf(LogLevelTrace, format, args...) }
+func (e *logEntry) Debugf(format string, args ...interface{}) { e.logf(LogLevelDebug, format, args...) }
+func (e *logEntry) Infof(format string, args ...interface{})  { e.logf(LogLevelInfo, format, args...) }
+func (e *logEntry) Warnf(format string, args ...interface{})  { e.logf(LogLevelWarn, format, args...) }
+func (e *logEntry) Errorf(format string, args ...interface{}) { e.logf(LogLevelError, format, args...) }
+
+func (e *logEntry) logf(level LogLevel, format string, args ...interface{}) {
+	if e.logger.Enabled(level) {
+		e.write(level, fmt.Sprintf(format, args...))
+	}
+}
+
+func (e *logEntry) log(level LogLevel, msg string) {
+	if e.logger.Enabled(level) {
+		e.write(level, msg)
+	}
+}
+
+// write redact fields and send record to logger
+func (e *logEntry) write(level LogLevel, msg string) {
+	fields := make(Fields, len(e.fields))
+	for k, v := range e.fields {
+		if e.redacted(k) {
+			v = logRedacted
+		}
+		fields[k] = v
+	}
+	e.logger.Log(level, fields, msg)
+}
+
+// redacted field value is not logged
+func (e *logEntry) redacted(key string) bool {
+	if key == logBody {
+		return !e.logBodies && e.fields[key] != ""
+	}
+	return strings.Contains(strings.ToLower(key), "password")
+}
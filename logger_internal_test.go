@@ -0,0 +1,108 @@
+package finesse_api
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+// recordLogger logger stores records with level from minimal level
+type recordLogger struct {
+	level   LogLevel
+	records []logRecord
+	mutex   sync.Mutex
+}
+
+type logRecord struct {
+	level  LogLevel
+	fields Fields
+	msg    string
+}
+
+func (l *recordLogger) Enabled(level LogLevel) bool {
+	return level >= l.level
+}
+
+func (l *recordLogger) Log(level LogLevel, fields Fields, msg string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.records = append(l.records, logRecord{level: level, fields: fields, msg: msg})
+}
+
+func TestLogRedaction(t *testing.T) {
+	fields := Fields{logProc: "doRequest", logBody: "<User><state>READY</state></User>", "password": "secret",
+		"agentPassword": "secret", logAgent: "agent1"}
+	for _, tc := range []struct {
+		name      string
+		logBodies bool
+		body      interface{}
+	}{
+		{name: "redacted body", logBodies: false, body: logRedacted},
+		{name: "logged body", logBodies: true, body: fields[logBody]},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			l := &recordLogger{level: LogLevelTrace}
+			logSettings{logger: l, logBodies: tc.logBodies}.withFields(fields).Tracef("request %d", 1)
+			if len(l.records) != 1 {
+				t.Fatalf("logged [%d] records, expected [1]", len(l.records))
+			}
+			r := l.records[0]
+			if r.level != LogLevelTrace || r.msg != "request 1" {
+				t.Errorf("record is [%s] [%s]", r.level, r.msg)
+			}
+			if r.fields[logBody] != tc.body {
+				t.Errorf("body is [%v], expected [%v]", r.fields[logBody], tc.body)
+			}
+			for _, k := range []string{"password", "agentPassword"} {
+				if r.fields[k] != logRedacted {
+					t.Errorf("field [%s] is not redacted [%v]", k, r.fields[k])
+				}
+			}
+			if r.fields[logAgent] != "agent1" || r.fields[logProc] != "doRequest" {
+				t.Errorf("fields are %v", r.fields)
+			}
+			if fields["password"] != "secret" {
+				t.Errorf("redaction changed fields of entry")
+			}
+		})
+	}
+
+	l := &recordLogger{level: LogLevelTrace}
+	logSettings{logger: l}.withFields(Fields{logBody: ""}).Trace("empty body")
+	if body := l.records[0].fields[logBody]; body != "" {
+		t.Errorf("empty body is [%v], expected empty", body)
+	}
+}
+
+func TestLogLevel(t *testing.T) {
+	l := &recordLogger{level: LogLevelWarn}
+	entry := logSettings{logger: l}.withFields(Fields{logProc: "test"})
+	entry.Tracef("trace %d", 1)
+	entry.Debug("debug")
+	entry.Info("info")
+	entry.Warnf("warn %d", 1)
+	entry.Error("error")
+	if len(l.records) != 2 || l.records[0].level != LogLevelWarn || l.records[1].level != LogLevelError {
+		t.Errorf("records are %v, expected warn and error", l.records)
+	}
+	if name := LogLevel(10).String(); name != "level(10)" {
+		t.Errorf("unknown level is [%s]", name)
+	}
+}
+
+func TestServerLogger(t *testing.T) {
+	server := NewServer("finesse.example.com", true)
+	serverLogger := &recordLogger{level: LogLevelTrace}
+	agentLogger := &recordLogger{level: LogLevelTrace}
+	server.SetLogger(serverLogger)
+	a := NewAgentNotify(context.Background(), "agent1", "password", "2001", server)
+	a.withFields(Fields{}).Info("server")
+	a.SetLogger(agentLogger)
+	a.withFields(Fields{}).Info("agent")
+	if len(serverLogger.records) != 1 || serverLogger.records[0].msg != "server" {
+		t.Errorf("server logger records are %v", serverLogger.records)
+	}
+	if len(agentLogger.records) != 1 || agentLogger.records[0].msg != "agent" {
+		t.Errorf("agent logger records are %v", agentLogger.records)
+	}
+}
@@ -0,0 +1,281 @@
+// Package metrics exports measurements of finesse_api library as Prometheus metrics.
+//
+// Metrics implements finesse_api.Observer and collects REST request latency and status codes, XMPP connections,
+// notification throughput, notify timeouts and dropped events. States of agents in AgentGroup are reported
+// as gauges when group is added by AddGroup, REST connection pool of Server when server is added by AddServer.
+//
+//	m := metrics.New()
+//	api.SetObserver(m)
+//	m.AddGroup("morning", group)
+//	m.AddServer(server)
+//	http.Handle("/metrics", m.Handler())
+package metrics
+
+import (
+	api "github.com/pokornyIt/finesse-api"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	Namespace = "finesse" // Namespace prefix of all metric names
+)
+
+// groupStates states always reported for agent group, other states are reported when some agent is in it
+var groupStates = []string{api.AgentStateLogout, api.AgentStateNotReady, api.AgentStateReady, api.AgentStateReserved,
+	api.AgentStateTalking, api.AgentStateHold, api.AgentStateWorkNotReady, api.AgentStateWorkReady, api.AgentStateUnknown}
+
+// Metrics Prometheus collectors for finesse_api library
+type Metrics struct {
+	registry        *prometheus.Registry
+	requestDuration *prometheus.HistogramVec // requestDuration REST latency by server, method and endpoint
+	requests        *prometheus.CounterVec   // requests REST requests by server, method, endpoint and HTTP status
+	xmppConnects    *prometheus.CounterVec   // xmppConnects XMPP connections by server
+	xmppDisconnects *prometheus.CounterVec   // xmppDisconnects lost or closed XMPP connections by server
+	xmppSessions    *prometheus.GaugeVec     // xmppSessions actually connected XMPP sessions by server
+	notifications   *prometheus.CounterVec   // notifications received XMPP notifications by server and event type
+	notifyTimeouts  *prometheus.CounterVec   // notifyTimeouts requests not confirmed by XMPP notification by server
+	eventsDropped   *prometheus.CounterVec   // eventsDropped events dropped from slow subscribers by event type
+	groups          *groupCollector
+	servers         *poolCollector
+}
+
+// groupCollector reports states of agents in groups at scrape time
+type groupCollector struct {
+	desc   *prometheus.Desc
+	groups map[string]*api.AgentGroup
+	mutex  sync.Mutex
+}
+
+// poolCollector reports REST connection pool statistics of servers at scrape time
+type poolCollector struct {
+	open       *prometheus.Desc
+	dialed     *prometheus.Desc
+	requests   *prometheus.Desc
+	reused     *prometheus.Desc
+	handshakes *prometheus.Desc
+	servers    map[string]*api.Server
+	mutex      sync.Mutex
+}
+
+// New create collectors registered in own registry
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Subsystem: "rest",
+			Name:      "request_duration_seconds",
+			Help:      "Latency of Finesse REST requests.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"server", "method", "endpoint"}),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: "rest",
+			Name:      "requests_total",
+			Help:      "Finesse REST requests by HTTP status, status \"error\" when response was not received.",
+		}, []string{"server", "method", "endpoint", "status"}),
+		xmppConnects: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: "xmpp",
+			Name:      "connects_total",
+			Help:      "XMPP notification connections including reconnects.",
+		}, []string{"server"}),
+		xmppDisconnects: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: "xmpp",
+			Name:      "disconnects_total",
+			Help:      "Lost or closed XMPP notification connections.",
+		}, []string{"server"}),
+		xmppSessions: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: "xmpp",
+			Name:      "sessions",
+			Help:      "Connected XMPP notification sessions.",
+		}, []string{"server"}),
+		notifications: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: "xmpp",
+			Name:      "notifications_total",
+			Help:      "Received XMPP notifications by event type.",
+		}, []string{"server", "event"}),
+		notifyTimeouts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: "xmpp",
+			Name:      "notify_timeouts_total",
+			Help:      "Requests not confirmed by XMPP notification in time.",
+		}, []string{"server"}),
+		eventsDropped: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: "events",
+			Name:      "dropped_total",
+			Help:      "Events dropped from queue of slow subscriber with DeliveryDropOldest policy.",
+		}, []string{"event"}),
+		groups: &groupCollector{
+			desc: prometheus.NewDesc(prometheus.BuildFQName(Namespace, "group", "agents"),
+				"Agents of AgentGroup in latest known state.", []string{"group", "state"}, nil),
+			groups: make(map[string]*api.AgentGroup),
+		},
+		servers: &poolCollector{
+			open: prometheus.NewDesc(prometheus.BuildFQName(Namespace, "rest", "pool_open_connections"),
+				"Open connections of REST connection pool.", []string{"server"}, nil),
+			dialed: prometheus.NewDesc(prometheus.BuildFQName(Namespace, "rest", "pool_dialed_total"),
+				"Connections opened by REST connection pool.", []string{"server"}, nil),
+			requests: prometheus.NewDesc(prometheus.BuildFQName(Namespace, "rest", "pool_requests_total"),
+				"REST requests which got connection from pool.", []string{"server"}, nil),
+			reused: prometheus.NewDesc(prometheus.BuildFQName(Namespace, "rest", "pool_reused_total"),
+				"REST requests sent over reused connection.", []string{"server"}, nil),
+			handshakes: prometheus.NewDesc(prometheus.BuildFQName(Namespace, "rest", "pool_tls_handshakes_total"),
+				"TLS handshakes of REST connection pool.", []string{"server"}, nil),
+			servers: make(map[string]*api.Server),
+		},
+	}
+	m.registry.MustRegister(m.collectors()...)
+	return m
+}
+
+func (m *Metrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{m.requestDuration, m.requests, m.xmppConnects, m.xmppDisconnects, m.xmppSessions,
+		m.notifications, m.notifyTimeouts, m.eventsDropped, m.groups, m.servers}
+}
+
+// Register register collectors also into other registry (e.g. prometheus.DefaultRegisterer)
+func (m *Metrics) Register(registerer prometheus.Registerer) error {
+	for _, c := range m.collectors() {
+		if err := registerer.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Registry own registry with library collectors
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// Handler HTTP handler exposes metrics from own registry
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// AddGroup report states of group agents with label group=name, group with the same name is replaced
+func (m *Metrics) AddGroup(name string, group *api.AgentGroup) {
+	m.groups.mutex.Lock()
+	m.groups.groups[name] = group
+	m.groups.mutex.Unlock()
+}
+
+// RemoveGroup stop reporting states of group
+func (m *Metrics) RemoveGroup(name string) {
+	m.groups.mutex.Lock()
+	delete(m.groups.groups, name)
+	m.groups.mutex.Unlock()
+}
+
+// AddServer report REST connection pool statistics of server with label server=name
+func (m *Metrics) AddServer(server *api.Server) {
+	m.servers.mutex.Lock()
+	m.servers.servers[server.Name()] = server
+	m.servers.mutex.Unlock()
+}
+
+// RemoveServer stop reporting connection pool statistics of server
+func (m *Metrics) RemoveServer(server *api.Server) {
+	m.servers.mutex.Lock()
+	delete(m.servers.servers, server.Name())
+	m.servers.mutex.Unlock()
+}
+
+// ObserveRequest implements finesse_api.Observer
+func (m *Metrics) ObserveRequest(server string, method string, endpoint string, status int, duration time.Duration) {
+	code := "error"
+	if status > 0 {
+		code = strconv.Itoa(status)
+	}
+	m.requestDuration.WithLabelValues(server, method, endpoint).Observe(duration.Seconds())
+	m.requests.WithLabelValues(server, method, endpoint, code).Inc()
+}
+
+// ObserveXmppConnection implements finesse_api.Observer
+func (m *Metrics) ObserveXmppConnection(server string, connected bool) {
+	if connected {
+		m.xmppConnects.WithLabelValues(server).Inc()
+		m.xmppSessions.WithLabelValues(server).Inc()
+		return
+	}
+	m.xmppDisconnects.WithLabelValues(server).Inc()
+	m.xmppSessions.WithLabelValues(server).Dec()
+}
+
+// ObserveNotify implements finesse_api.Observer
+func (m *Metrics) ObserveNotify(server string, event string) {
+	m.notifications.WithLabelValues(server, event).Inc()
+}
+
+// ObserveNotifyTimeout implements finesse_api.Observer
+func (m *Metrics) ObserveNotifyTimeout(server string) {
+	m.notifyTimeouts.WithLabelValues(server).Inc()
+}
+
+// ObserveEventDropped implements finesse_api.Observer
+func (m *Metrics) ObserveEventDropped(event string) {
+	m.eventsDropped.WithLabelValues(event).Inc()
+}
+
+// Describe implements prometheus.Collector
+func (c *groupCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+// Collect implements prometheus.Collector
+func (c *groupCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mutex.Lock()
+	groups := make(map[string]*api.AgentGroup, len(c.groups))
+	for name, g := range c.groups {
+		groups[name] = g
+	}
+	c.mutex.Unlock()
+	for name, g := range groups {
+		states := g.States()
+		for _, s := range groupStates {
+			if _, ok := states[s]; !ok {
+				states[s] = 0
+			}
+		}
+		for s, n := range states {
+			ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(n), name, s)
+		}
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.open
+	ch <- c.dialed
+	ch <- c.requests
+	ch <- c.reused
+	ch <- c.handshakes
+}
+
+// Collect implements prometheus.Collector
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mutex.Lock()
+	servers := make(map[string]*api.Server, len(c.servers))
+	for name, s := range c.servers {
+		servers[name] = s
+	}
+	c.mutex.Unlock()
+	for name, s := range servers {
+		stats := s.PoolStats()
+		ch <- prometheus.MustNewConstMetric(c.open, prometheus.GaugeValue, float64(stats.Open), name)
+		ch <- prometheus.MustNewConstMetric(c.dialed, prometheus.CounterValue, float64(stats.Dialed), name)
+		ch <- prometheus.MustNewConstMetric(c.requests, prometheus.CounterValue, float64(stats.Requests), name)
+		ch <- prometheus.MustNewConstMetric(c.reused, prometheus.CounterValue, float64(stats.Reused), name)
+		ch <- prometheus.MustNewConstMetric(c.handshakes, prometheus.CounterValue, float64(stats.TLSHandshakes), name)
+	}
+}
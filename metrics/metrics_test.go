@@ -0,0 +1,185 @@
+package metrics_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	api "github.com/pokornyIt/finesse-api"
+	"github.com/pokornyIt/finesse-api/finessetest"
+	"github.com/pokornyIt/finesse-api/metrics"
+)
+
+// value of metric with labels from registry of m, found is false for missing metric
+func value(t *testing.T, m *metrics.Metrics, name string, labels map[string]string) (v float64, found bool) {
+	t.Helper()
+	families, err := m.Registry().Gather()
+	if err != nil {
+		t.Fatalf("gather: %s", err)
+	}
+	for _, f := range families {
+		if f.GetName() != name {
+			continue
+		}
+		for _, metric := range f.GetMetric() {
+			matched := 0
+			for _, l := range metric.GetLabel() {
+				if labels[l.GetName()] == l.GetValue() {
+					matched++
+				}
+			}
+			if matched != len(labels) {
+				continue
+			}
+			switch {
+			case metric.GetCounter() != nil:
+				return metric.GetCounter().GetValue(), true
+			case metric.GetGauge() != nil:
+				return metric.GetGauge().GetValue(), true
+			case metric.GetHistogram() != nil:
+				return float64(metric.GetHistogram().GetSampleCount()), true
+			}
+		}
+	}
+	return 0, false
+}
+
+// checkValue metric with labels has expected value
+func checkValue(t *testing.T, m *metrics.Metrics, name string, labels map[string]string, exp float64) {
+	t.Helper()
+	v, found := value(t, m, name, labels)
+	if !found {
+		t.Errorf("metric %s%v not found", name, labels)
+		return
+	}
+	if v != exp {
+		t.Errorf("metric %s%v is [%g], expected [%g]", name, labels, v, exp)
+	}
+}
+
+// startMock start mock server with agent1 stopped at the end of test
+func startMock(t *testing.T) *finessetest.Server {
+	t.Helper()
+	mock, err := finessetest.NewServer()
+	if err != nil {
+		t.Fatalf("start mock server: %s", err)
+	}
+	t.Cleanup(mock.Close)
+	mock.AddAgent("agent1", "1001", "password", "2001")
+	return mock
+}
+
+func TestMetricsObserver(t *testing.T) {
+	m := metrics.New()
+	m.ObserveRequest("finesse-a", "GET", "User/{id}", 200, 10*time.Millisecond)
+	m.ObserveRequest("finesse-a", "GET", "User/{id}", 200, 20*time.Millisecond)
+	m.ObserveRequest("finesse-a", "PUT", "User/{id}", 0, time.Second)
+	m.ObserveXmppConnection("finesse-a", true)
+	m.ObserveXmppConnection("finesse-a", true)
+	m.ObserveXmppConnection("finesse-a", false)
+	m.ObserveNotify("finesse-a", "UserEvent")
+	m.ObserveNotifyTimeout("finesse-a")
+	m.ObserveEventDropped("DialogEvent")
+
+	for _, tc := range []struct {
+		name   string
+		labels map[string]string
+		exp    float64
+	}{
+		{name: "finesse_rest_requests_total", labels: map[string]string{"server": "finesse-a", "method": "GET", "endpoint": "User/{id}", "status": "200"}, exp: 2},
+		{name: "finesse_rest_requests_total", labels: map[string]string{"server": "finesse-a", "method": "PUT", "endpoint": "User/{id}", "status": "error"}, exp: 1},
+		{name: "finesse_rest_request_duration_seconds", labels: map[string]string{"server": "finesse-a", "method": "GET", "endpoint": "User/{id}"}, exp: 2},
+		{name: "finesse_xmpp_connects_total", labels: map[string]string{"server": "finesse-a"}, exp: 2},
+		{name: "finesse_xmpp_disconnects_total", labels: map[string]string{"server": "finesse-a"}, exp: 1},
+		{name: "finesse_xmpp_sessions", labels: map[string]string{"server": "finesse-a"}, exp: 1},
+		{name: "finesse_xmpp_notifications_total", labels: map[string]string{"server": "finesse-a", "event": "UserEvent"}, exp: 1},
+		{name: "finesse_xmpp_notify_timeouts_total", labels: map[string]string{"server": "finesse-a"}, exp: 1},
+		{name: "finesse_events_dropped_total", labels: map[string]string{"event": "DialogEvent"}, exp: 1},
+	} {
+		checkValue(t, m, tc.name, tc.labels, tc.exp)
+	}
+}
+
+func TestMetricsRequestEndpoint(t *testing.T) {
+	mock := startMock(t)
+	m := metrics.New()
+	api.SetObserver(m)
+	defer api.SetObserver(nil)
+	server := mock.Finesse(true)
+	server.SetLogger(api.NewNopLogger())
+	if _, err := server.SystemInfo(); err != nil {
+		t.Fatalf("system info: %s", err)
+	}
+	agent := api.NewAgentNotify(context.Background(), "agent1", "password", "2001", server)
+	if _, err := agent.GetStatus(); err != nil {
+		t.Fatalf("agent status: %s", err)
+	}
+
+	checkValue(t, m, "finesse_rest_requests_total", map[string]string{"server": mock.Host, "method": "GET", "endpoint": "SystemInfo", "status": "200"}, 1)
+	checkValue(t, m, "finesse_rest_requests_total", map[string]string{"server": mock.Host, "method": "GET", "endpoint": "User/{id}", "status": "200"}, 1)
+	if _, found := value(t, m, "finesse_rest_requests_total", map[string]string{"endpoint": "User/agent1"}); found {
+		t.Errorf("agent ID is used as endpoint label")
+	}
+}
+
+func TestMetricsGroup(t *testing.T) {
+	mock := startMock(t)
+	mock.AddAgent("agent2", "1002", "password", "2002")
+	server := mock.Finesse(true)
+	server.SetLogger(api.NewNopLogger())
+	group := api.NewAgentGroup()
+	defer group.CancelFunction()
+	agents := []api.BulkAgent{{Name: "agent1", Password: "password", Line: "2001"}, {Name: "agent2", Password: "password", Line: "2002"}}
+	if failed := group.AddBulkAgents(agents, server).Failed(); len(failed) > 0 {
+		t.Fatalf("add agents failed %v", failed)
+	}
+	defer func() {
+		for _, a := range group.Agents {
+			a.StopXmpp()
+		}
+	}()
+	if failed := group.Login().Failed(); len(failed) > 0 {
+		t.Fatalf("login failed %v", failed)
+	}
+	if errOp := group.Agents[0].Ready(); errOp.Type != api.TypeErrorNoError {
+		t.Fatalf("ready: %s", errOp.Error)
+	}
+
+	m := metrics.New()
+	m.AddGroup("morning", group)
+	checkValue(t, m, "finesse_group_agents", map[string]string{"group": "morning", "state": api.AgentStateReady}, 1)
+	checkValue(t, m, "finesse_group_agents", map[string]string{"group": "morning", "state": api.AgentStateNotReady}, 1)
+	// always reported states
+	checkValue(t, m, "finesse_group_agents", map[string]string{"group": "morning", "state": api.AgentStateTalking}, 0)
+	checkValue(t, m, "finesse_group_agents", map[string]string{"group": "morning", "state": api.AgentStateUnknown}, 0)
+
+	m.RemoveGroup("morning")
+	if _, found := value(t, m, "finesse_group_agents", map[string]string{"group": "morning"}); found {
+		t.Errorf("removed group is reported")
+	}
+}
+
+func TestMetricsPool(t *testing.T) {
+	mock := startMock(t)
+	server := mock.Finesse(true)
+	server.SetLogger(api.NewNopLogger())
+	for i := 0; i < 3; i++ {
+		if _, err := server.SystemInfo(); err != nil {
+			t.Fatalf("system info: %s", err)
+		}
+	}
+
+	m := metrics.New()
+	m.AddServer(server)
+	labels := map[string]string{"server": mock.Host}
+	checkValue(t, m, "finesse_rest_pool_open_connections", labels, 1)
+	checkValue(t, m, "finesse_rest_pool_dialed_total", labels, 1)
+	checkValue(t, m, "finesse_rest_pool_requests_total", labels, 3)
+	checkValue(t, m, "finesse_rest_pool_reused_total", labels, 2)
+	checkValue(t, m, "finesse_rest_pool_tls_handshakes_total", labels, 1)
+
+	m.RemoveServer(server)
+	if _, found := value(t, m, "finesse_rest_pool_requests_total", labels); found {
+		t.Errorf("removed server is reported")
+	}
+}
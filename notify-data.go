@@ -7,10 +7,20 @@ type XmppUpdate struct {
 	Data      struct {
 		User        XmppUser        `xml:"user,omitempty"`
 		Error       XmppErrors      `xml:"apiErrors,omitempty"`
-		Dialogs     XmppDialogs     `xml:"Dialog,omitempty"`
+		Dialogs     XmppDialogs     `xml:"dialogs,omitempty"`
+		Dialog      XmppDialog      `xml:"Dialog,omitempty"`
 		Devices     XmppDevices     `xml:"Devices,omitempty"`
 		Queue       XmppQueue       `xml:"Queue,omitempty"`
 		Team        XmppTeam        `xml:"Team,omitempty"`
 		TeamMessage XmppTeamMessage `xml:"TeamMessage,omitempty"`
 	} `xml:"data"`
 }
+
+// dialogs return all dialogs from update, single dialog updates (PUT) and dialog lists (POST, DELETE)
+func (u *XmppUpdate) dialogs() []XmppDialog {
+	ret := u.Data.Dialogs.Dialogs
+	if len(u.Data.Dialog.URI) > 0 {
+		ret = append(ret, u.Data.Dialog)
+	}
+	return ret
+}
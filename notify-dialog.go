@@ -1,5 +1,10 @@
 package finesse_api
 
+import (
+	"encoding/xml"
+	"path"
+)
+
 type XmppDialogs struct {
 	Dialogs []XmppDialog `xml:"Dialog"`
 }
@@ -16,11 +21,9 @@ type XmppDialog struct {
 		DialedNumber           string `xml:"dialedNumber"`
 		OutboundClassification string `xml:"outboundClassification"`
 		CallVariables          struct {
-			CallVariable []struct {
-				Name  string `xml:"name"`
-				Value string `xml:"value"`
-			} `xml:"CallVariable"`
+			CallVariable []XmppCallVariable `xml:"CallVariable"`
 		} `xml:"callvariables"`
+		WrapUpReason       string `xml:"wrapUpReason"`
 		QueueNumber        string `xml:"queueNumber"`
 		QueueName          string `xml:"queueName"`
 		CallKeyCallId      string `xml:"callKeyCallId"`
@@ -29,19 +32,82 @@ type XmppDialog struct {
 	} `xml:"mediaProperties"`
 	MediaType    string `xml:"mediaType"`
 	Participants struct {
-		Participant struct {
-			Actions struct {
-				Action []string `xml:"action"`
-			} `xml:"actions"`
-			MediaAddress     string `xml:"mediaAddress"`
-			MediaAddressType string `xml:"mediaAddressType"`
-			StartTime        string `xml:"startTime"`
-			State            string `xml:"state"`
-			StateCause       string `xml:"stateCause"`
-			StateChangeTime  string `xml:"stateChangeTime"`
-		} `xml:"Participant"`
+		Participant []XmppParticipant `xml:"Participant"`
 	} `xml:"participants"`
 	State     string `xml:"state"`
 	ToAddress string `xml:"toAddress"`
 	URI       string `xml:"uri"`
 }
+
+type XmppCallVariable struct {
+	Name  string `xml:"name"`
+	Value string `xml:"value"`
+}
+
+type XmppParticipant struct {
+	Actions struct {
+		Action []string `xml:"action"`
+	} `xml:"actions"`
+	MediaAddress     string `xml:"mediaAddress"`
+	MediaAddressType string `xml:"mediaAddressType"`
+	StartTime        string `xml:"startTime"`
+	State            string `xml:"state"`
+	StateCause       string `xml:"stateCause"`
+	StateChangeTime  string `xml:"stateChangeTime"`
+}
+
+// participant find dialog participant by media address (agent extension), returns nil if not exists
+func (d *XmppDialog) participant(mediaAddress string) *XmppParticipant {
+	for i := range d.Participants.Participant {
+		if d.Participants.Participant[i].MediaAddress == mediaAddress {
+			return &d.Participants.Participant[i]
+		}
+	}
+	return nil
+}
+
+// callVariable find call or ECC variable in dialog by name
+func (d *XmppDialog) callVariable(name string) (string, bool) {
+	for _, v := range d.MediaProperties.CallVariables.CallVariable {
+		if v.Name == name {
+			return v.Value, true
+		}
+	}
+	return "", false
+}
+
+// associatedDialogId get dialog ID from associated dialog URI
+func (d *XmppDialog) associatedDialogId() string {
+	if len(d.AssociatedDialogUri) == 0 {
+		return ""
+	}
+	return path.Base(d.AssociatedDialogUri)
+}
+
+// allowAction check if participant can process required dialog action
+func (p *XmppParticipant) allowAction(action string) bool {
+	for _, a := range p.Actions.Action {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+func newXmppDialog(data string) (*XmppDialog, error) {
+	var d XmppDialog
+	err := xml.Unmarshal([]byte(data), &d)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func newXmppDialogs(data string) (*XmppDialogs, error) {
+	var d XmppDialogs
+	err := xml.Unmarshal([]byte(data), &d)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
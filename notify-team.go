@@ -1,31 +1,37 @@
 package finesse_api
 
+import "encoding/xml"
+
 type XmppTeam struct {
 	URI   string `xml:"uri"`
 	ID    string `xml:"id"`
 	Name  string `xml:"name"`
 	Users struct {
-		User []struct {
-			URI             string `xml:"uri"`
-			LoginId         string `xml:"loginId"`
-			FirstName       string `xml:"firstName"`
-			LastName        string `xml:"lastName"`
-			Dialogs         string `xml:"dialogs"`
-			Extension       string `xml:"extension"`
-			PendingState    string `xml:"pendingState"`
-			State           string `xml:"state"`
-			StateChangeTime string `xml:"stateChangeTime"`
-			ReasonCode      struct {
-				Category string `xml:"category"`
-				Code     string `xml:"code"`
-				Label    string `xml:"label"`
-				ID       string `xml:"id"`
-				URI      string `xml:"uri"`
-			} `xml:"reasonCode"`
-		} `xml:"User"`
+		User []XmppTeamUser `xml:"User"`
 	} `xml:"users"`
 }
 
+// XmppTeamUser one user in team
+type XmppTeamUser struct {
+	URI             string `xml:"uri"`
+	LoginId         string `xml:"loginId"`
+	LoginName       string `xml:"loginName"`
+	FirstName       string `xml:"firstName"`
+	LastName        string `xml:"lastName"`
+	Dialogs         string `xml:"dialogs"`
+	Extension       string `xml:"extension"`
+	PendingState    string `xml:"pendingState"`
+	State           string `xml:"state"`
+	StateChangeTime string `xml:"stateChangeTime"`
+	ReasonCode      struct {
+		Category string `xml:"category"`
+		Code     string `xml:"code"`
+		Label    string `xml:"label"`
+		ID       string `xml:"id"`
+		URI      string `xml:"uri"`
+	} `xml:"reasonCode"`
+}
+
 type XmppTeamMessage struct {
 	URI       string `xml:"uri"`
 	ID        string `xml:"id"`
@@ -41,3 +47,12 @@ type XmppTeamMessage struct {
 		Team []string `xml:"team"`
 	} `xml:"teams"`
 }
+
+func newXmppTeam(data string) (*XmppTeam, error) {
+	var t XmppTeam
+	err := xml.Unmarshal([]byte(data), &t)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
@@ -0,0 +1,97 @@
+package finesse_api
+
+import (
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Observer receives measurements of REST requests, XMPP connections and notifications (e.g. package metrics)
+//
+// Methods are called synchronously from request and notification processing and must not block.
+type Observer interface {
+	ObserveRequest(server string, method string, endpoint string, status int, duration time.Duration) // ObserveRequest finished REST request, status 0 when response was not received
+	ObserveXmppConnection(server string, connected bool)                                              // ObserveXmppConnection XMPP connection of agent is up or down
+	ObserveNotify(server string, event string)                                                        // ObserveNotify received XMPP notification with event type name
+	ObserveNotifyTimeout(server string)                                                               // ObserveNotifyTimeout request was not confirmed by XMPP notification in time
+	ObserveEventDropped(event string)                                                                 // ObserveEventDropped event dropped from subscriber queue with DeliveryDropOldest policy
+}
+
+// observerHolder allows store interface in atomic.Value
+type observerHolder struct {
+	observer Observer
+}
+
+var observer atomic.Value
+
+// SetObserver set observer for all servers and agents, nil stops observation
+func SetObserver(o Observer) {
+	observer.Store(observerHolder{observer: o})
+}
+
+// currentObserver actual observer or nil
+func currentObserver() Observer {
+	if h, ok := observer.Load().(observerHolder); ok {
+		return h.observer
+	}
+	return nil
+}
+
+// observeRequest report finished REST request
+func observeRequest(server string, method string, rawUrl string, status int, started time.Time) {
+	if o := currentObserver(); o != nil {
+		o.ObserveRequest(server, method, endpointName(rawUrl), status, time.Since(started))
+	}
+}
+
+// observeXmppConnection report change of XMPP connection
+func observeXmppConnection(server string, connected bool) {
+	if o := currentObserver(); o != nil {
+		o.ObserveXmppConnection(server, connected)
+	}
+}
+
+// observeNotify report received XMPP notification, event nil is unknown notification
+func observeNotify(server string, e Event) {
+	if o := currentObserver(); o != nil {
+		name := "Unknown"
+		if e != nil {
+			name = e.Type().String()
+		}
+		o.ObserveNotify(server, name)
+	}
+}
+
+// observeNotifyTimeout report request not confirmed by XMPP notification
+func observeNotifyTimeout(server string) {
+	if o := currentObserver(); o != nil {
+		o.ObserveNotifyTimeout(server)
+	}
+}
+
+// observeEventDropped report event dropped from subscriber queue
+func observeEventDropped(e Event) {
+	if o := currentObserver(); o != nil {
+		o.ObserveEventDropped(e.Type().String())
+	}
+}
+
+// endpointName API path with IDs replaced by {id} for low cardinality of labels
+//
+// Example:
+//   - endpointName("https://finesse:8445/finesse/api/User/1001/Dialogs") => User/{id}/Dialogs
+//   - endpointName("https://finesse:8445/finesse/api/SystemInfo") => SystemInfo
+func endpointName(rawUrl string) string {
+	p := rawUrl
+	if u, err := url.Parse(rawUrl); err == nil {
+		p = u.Path
+	}
+	p = strings.TrimPrefix(strings.Trim(p, "/"), "finesse/api/")
+	parts := strings.Split(p, "/")
+	// resources alternate with IDs: Resource/{id}/SubResource/{id}
+	for i := 1; i < len(parts); i += 2 {
+		parts[i] = "{id}"
+	}
+	return strings.Join(parts, "/")
+}
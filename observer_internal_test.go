@@ -0,0 +1,79 @@
+package finesse_api
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// recordObserver observer collecting measurements as text
+type recordObserver struct {
+	records []string
+}
+
+func (o *recordObserver) ObserveRequest(server string, method string, endpoint string, status int, _ time.Duration) {
+	o.records = append(o.records, fmt.Sprintf("request %s %s %s %d", server, method, endpoint, status))
+}
+
+func (o *recordObserver) ObserveXmppConnection(server string, connected bool) {
+	o.records = append(o.records, fmt.Sprintf("xmpp %s %t", server, connected))
+}
+
+func (o *recordObserver) ObserveNotify(server string, event string) {
+	o.records = append(o.records, fmt.Sprintf("notify %s %s", server, event))
+}
+
+func (o *recordObserver) ObserveNotifyTimeout(server string) {
+	o.records = append(o.records, fmt.Sprintf("timeout %s", server))
+}
+
+func (o *recordObserver) ObserveEventDropped(event string) {
+	o.records = append(o.records, fmt.Sprintf("dropped %s", event))
+}
+
+func TestEndpointName(t *testing.T) {
+	for _, tc := range []struct {
+		url string
+		exp string
+	}{
+		{url: "https://finesse:8445/finesse/api/SystemInfo", exp: "SystemInfo"},
+		{url: "https://finesse:8445/finesse/api/User/1001", exp: "User/{id}"},
+		{url: "https://finesse:8445/finesse/api/User/1001/Dialogs", exp: "User/{id}/Dialogs"},
+		{url: "https://finesse:8445/finesse/api/User/1001/Dialogs?mediaType=1", exp: "User/{id}/Dialogs"},
+		{url: "https://finesse:8445/finesse/api/Dialog/32459874/", exp: "Dialog/{id}"},
+		{url: "https://finesse:8445/finesse/api/Team/5000/TeamMessages", exp: "Team/{id}/TeamMessages"},
+		{url: "https://finesse:8445/finesse/api/Team/5000/TeamMessage/abc-123", exp: "Team/{id}/TeamMessage/{id}"},
+		{url: "https://finesse:8445/finesse/api/Queue/7", exp: "Queue/{id}"},
+		{url: "/finesse/api/User/agent1/ReasonCodes", exp: "User/{id}/ReasonCodes"},
+	} {
+		if name := endpointName(tc.url); name != tc.exp {
+			t.Errorf("endpoint of [%s] is [%s], expected [%s]", tc.url, name, tc.exp)
+		}
+	}
+}
+
+func TestObserve(t *testing.T) {
+	// without observer nothing is reported
+	SetObserver(nil)
+	observeRequest("finesse", "GET", "https://finesse:8445/finesse/api/SystemInfo", 200, time.Now())
+
+	o := &recordObserver{}
+	SetObserver(o)
+	defer SetObserver(nil)
+	observeRequest("finesse", "PUT", "https://finesse:8445/finesse/api/User/1001", 202, time.Now())
+	observeRequest("finesse", "GET", "https://finesse:8445/finesse/api/User/1001/Dialogs", 0, time.Now())
+	observeXmppConnection("finesse", true)
+	observeNotify("finesse", nil)
+	observeNotifyTimeout("finesse")
+
+	exp := []string{
+		"request finesse PUT User/{id} 202",
+		"request finesse GET User/{id}/Dialogs 0",
+		"xmpp finesse true",
+		"notify finesse Unknown",
+		"timeout finesse",
+	}
+	if fmt.Sprint(o.records) != fmt.Sprint(exp) {
+		t.Errorf("records are %q, expected %q", o.records, exp)
+	}
+}
@@ -0,0 +1,161 @@
+package finesse_api
+
+import (
+	"encoding/xml"
+	"fmt"
+	"path"
+	"strconv"
+	"time"
+)
+
+// Queue Finesse queue (skill group / CSQ) assigned to agent
+type Queue struct {
+	Id         string     // Id queue ID
+	Name       string     // Name queue name
+	agent      *Agent     // agent used for requests
+	lastStatus *XmppQueue // latest queue response
+}
+
+// QueueStatistics queue statistics converted from Finesse strings
+type QueueStatistics struct {
+	CallsInQueue                  int       // CallsInQueue number of calls waiting in queue
+	StartTimeOfLongestCallInQueue time.Time // StartTimeOfLongestCallInQueue zero if no call is waiting
+	AgentsReady                   int
+	AgentsNotReady                int
+	AgentsBusyOther               int
+	AgentsLoggedOn                int
+	AgentsTalkingInbound          int
+	AgentsTalkingOutbound         int
+	AgentsTalkingInternal         int
+	AgentsWrapUpNotReady          int
+	AgentsWrapUpReady             int
+}
+
+type xmppQueues struct {
+	Queues []XmppQueue `xml:"Queue"`
+}
+
+// LongestWait wait time of longest call in queue at time now, zero if no call is waiting
+func (q QueueStatistics) LongestWait(now time.Time) time.Duration {
+	if q.StartTimeOfLongestCallInQueue.IsZero() || now.Before(q.StartTimeOfLongestCallInQueue) {
+		return 0
+	}
+	return now.Sub(q.StartTimeOfLongestCallInQueue)
+}
+
+// Id queue ID from URI
+func (x *XmppQueue) Id() string {
+	if len(x.URI) == 0 {
+		return ""
+	}
+	return path.Base(x.URI)
+}
+
+// statistics convert statistics, empty and invalid values are zero
+func (x *XmppQueue) statistics() QueueStatistics {
+	s := x.Statistics
+	ret := QueueStatistics{
+		CallsInQueue:          atoi(s.CallsInQueue),
+		AgentsReady:           atoi(s.AgentsReady),
+		AgentsNotReady:        atoi(s.AgentsNotReady),
+		AgentsBusyOther:       atoi(s.AgentsBusyOther),
+		AgentsLoggedOn:        atoi(s.AgentsLoggedOn),
+		AgentsTalkingInbound:  atoi(s.AgentsTalkingInbound),
+		AgentsTalkingOutbound: atoi(s.AgentsTalkingOutbound),
+		AgentsTalkingInternal: atoi(s.AgentsTalkingInternal),
+		AgentsWrapUpNotReady:  atoi(s.AgentsWrapUpNotReady),
+		AgentsWrapUpReady:     atoi(s.AgentsWrapUpReady),
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s.StartTimeOfLongestCallInQueue); err == nil {
+		ret.StartTimeOfLongestCallInQueue = t
+	}
+	return ret
+}
+
+func atoi(value string) int {
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return 0
+	}
+	return i
+}
+
+func newXmppQueues(data string) ([]XmppQueue, error) {
+	var q xmppQueues
+	err := xml.Unmarshal([]byte(data), &q)
+	if err != nil {
+		return nil, err
+	}
+	return q.Queues, nil
+}
+
+func newXmppQueue(data string) (*XmppQueue, error) {
+	var q XmppQueue
+	err := xml.Unmarshal([]byte(data), &q)
+	if err != nil {
+		return nil, err
+	}
+	return &q, nil
+}
+
+// Queues get queues assigned to agent with actual statistics
+func (s *Server) Queues(a *Agent) ([]*Queue, error) {
+	request := a.newAgentRequest()
+	response := request.doRequest("GET", request.server.urlString(request.id, "User", a.LoginId, "Queues"), nil)
+	defer response.close()
+	msg, err := response.responseError()
+	if err != nil {
+		s.withFields(Fields{logProc: "Queues", logId: response.id, logAgent: a.LoginName}).Error(msg)
+		return nil, err
+	}
+	data, err := newXmppQueues(response.GetResponseBody())
+	if err != nil {
+		s.withFields(Fields{logProc: "Queues", logId: response.id, logAgent: a.LoginName}).Error(err)
+		return nil, err
+	}
+	var ret []*Queue
+	for i := range data {
+		ret = append(ret, &Queue{Id: data[i].Id(), Name: data[i].Name, agent: a, lastStatus: &data[i]})
+	}
+	s.withFields(Fields{logProc: "Queues", logId: response.id, logAgent: a.LoginName}).Tracef("collect [%d] queues", len(ret))
+	return ret, nil
+}
+
+// Statistics get actual queue statistics from Finesse server
+func (q *Queue) Statistics() (QueueStatistics, error) {
+	request := q.agent.newAgentRequest()
+	response := request.doRequest("GET", request.server.urlString(request.id, "Queue", q.Id), nil)
+	defer response.close()
+	msg, err := response.responseError()
+	if err != nil {
+		q.agent.withFields(Fields{logProc: "QueueStatistics", logId: response.id, logAgent: q.agent.LoginName}).Error(msg)
+		return QueueStatistics{}, err
+	}
+	data, err := newXmppQueue(response.GetResponseBody())
+	if err != nil {
+		q.agent.withFields(Fields{logProc: "QueueStatistics", logId: response.id, logAgent: q.agent.LoginName}).Error(err)
+		return QueueStatistics{}, err
+	}
+	q.lastStatus = data
+	return data.statistics(), nil
+}
+
+// LastStatistics statistics from latest Queues or Statistics response, notifications are delivered only by Subscribe
+func (q *Queue) LastStatistics() QueueStatistics {
+	if q.lastStatus == nil {
+		return QueueStatistics{}
+	}
+	return q.lastStatus.statistics()
+}
+
+func (q *Queue) String() string {
+	return fmt.Sprintf("%s (%s)", q.Name, q.Id)
+}
+
+// Subscribe subscribe live statistics of queue, subscription deliver QueueEvent
+func (q *Queue) Subscribe(policy DeliveryPolicy) *Subscription {
+	return q.agent.Subscribe(func(e Event) bool {
+		qe, ok := e.(QueueEvent)
+		return ok && qe.QueueId == q.Id
+	}, policy)
+}
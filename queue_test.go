@@ -0,0 +1,57 @@
+package finesse_api_test
+
+import (
+	"testing"
+	"time"
+
+	api "github.com/pokornyIt/finesse-api"
+)
+
+func TestQueues(t *testing.T) {
+	mock := startMock(t)
+	mock.AddAgent("agent1", "1001", "password", "2001")
+	mock.AddQueue("7", "Sales")
+	agent := loginAgent(t, mock, "agent1", "2001")
+	checkOperation(t, "ready", agent.Ready())
+	server := mock.Finesse(true)
+	server.SetLogger(api.NewNopLogger())
+
+	queues, err := server.Queues(agent)
+	if err != nil || len(queues) != 1 {
+		t.Fatalf("queues are %v (%v)", queues, err)
+	}
+	q := queues[0]
+	if q.Id != "7" || q.Name != "Sales" {
+		t.Errorf("queue is [%s]", q)
+	}
+	if stats := q.LastStatistics(); stats.AgentsReady != 1 || stats.AgentsLoggedOn != 1 || stats.CallsInQueue != 0 {
+		t.Errorf("queue statistics are %+v", stats)
+	}
+
+	sub := q.Subscribe(api.DeliveryLossless)
+	defer sub.Close()
+	start := time.Now().Add(-30 * time.Second).UTC().Truncate(time.Millisecond)
+	if err = mock.SetQueueCalls("7", 3, start); err != nil {
+		t.Fatalf("set queue calls: %s", err)
+	}
+	select {
+	case e := <-sub.C:
+		qe := e.(api.QueueEvent)
+		if qe.Statistics.CallsInQueue != 3 || !qe.Statistics.StartTimeOfLongestCallInQueue.Equal(start) {
+			t.Errorf("notified statistics are %+v", qe.Statistics)
+		}
+		if wait := qe.Statistics.LongestWait(start.Add(time.Minute)); wait != time.Minute {
+			t.Errorf("longest wait is [%s]", wait)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("queue event not received")
+	}
+
+	stats, err := q.Statistics()
+	if err != nil || stats.CallsInQueue != 3 {
+		t.Errorf("actual statistics are %+v (%v)", stats, err)
+	}
+	if err = mock.SetQueueCalls("8", 1, start); err == nil {
+		t.Errorf("calls set for unknown queue")
+	}
+}
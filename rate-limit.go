@@ -0,0 +1,87 @@
+package finesse_api
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// rateLimiter token bucket, tokens are refilled with rate per second up to burst
+type rateLimiter struct {
+	rate   float64 // rate tokens per second
+	burst  float64 // burst maximal number of tokens
+	tokens float64
+	last   time.Time
+	mutex  sync.Mutex
+}
+
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// reserve take one token and return delay until token is available
+func (l *rateLimiter) reserve(now time.Time) time.Duration {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// release return token not used after canceled wait
+func (l *rateLimiter) release() {
+	l.mutex.Lock()
+	l.tokens++
+	l.mutex.Unlock()
+}
+
+// wait block until token is available or context is done
+func (l *rateLimiter) wait(ctx context.Context) error {
+	delay := l.reserve(time.Now())
+	if delay == 0 {
+		return nil
+	}
+	t := time.NewTimer(delay)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		l.release()
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// SetRateLimit limit REST requests sent to server to requestsPerSecond with burst, zero or negative rate disable limit
+//
+// Limit is shared by all agents of server, requests wait for free token (context cancels wait).
+func (s *Server) SetRateLimit(requestsPerSecond float64, burst int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if requestsPerSecond <= 0 {
+		s.limiter = nil
+		return
+	}
+	s.limiter = newRateLimiter(requestsPerSecond, burst)
+}
+
+// waitRate wait for rate limit token, without limit returns immediately
+func (s *Server) waitRate(ctx context.Context) error {
+	s.mutex.Lock()
+	l := s.limiter
+	s.mutex.Unlock()
+	if l == nil {
+		return nil
+	}
+	return l.wait(ctx)
+}
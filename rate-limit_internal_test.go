@@ -0,0 +1,80 @@
+package finesse_api
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterReserve(t *testing.T) {
+	l := newRateLimiter(10, 2)
+	now := l.last
+	for i, tc := range []struct {
+		after time.Duration // after time from start
+		delay time.Duration
+	}{
+		{after: 0, delay: 0},
+		{after: 0, delay: 0},                                           // burst
+		{after: 0, delay: 100 * time.Millisecond},                      // bucket is empty
+		{after: 100 * time.Millisecond, delay: 100 * time.Millisecond}, // refilled token is reserved by previous request
+		{after: time.Second, delay: 0},                                 // refill up to burst only
+		{after: time.Second, delay: 0},
+		{after: time.Second, delay: 100 * time.Millisecond},
+	} {
+		if delay := l.reserve(now.Add(tc.after)); delay != tc.delay {
+			t.Errorf("request [%d]: delay is [%s], expected [%s]", i+1, delay, tc.delay)
+		}
+	}
+	l.release()
+	if delay := l.reserve(now.Add(time.Second)); delay != 100*time.Millisecond {
+		t.Errorf("released token: delay is [%s], expected [%s]", delay, 100*time.Millisecond)
+	}
+	if l = newRateLimiter(1, 0); l.burst != 1 {
+		t.Errorf("burst is [%g], expected minimal burst [1]", l.burst)
+	}
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	l := newRateLimiter(5, 1)
+	if err := l.wait(context.Background()); err != nil {
+		t.Fatalf("wait with token: %s", err)
+	}
+	start := time.Now()
+	if err := l.wait(context.Background()); err != nil {
+		t.Fatalf("wait for token: %s", err)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("waited [%s], expected about 200ms", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := l.wait(ctx); err != context.Canceled {
+		t.Errorf("canceled wait: error is [%v]", err)
+	}
+	l.mutex.Lock()
+	tokens := l.tokens
+	l.mutex.Unlock()
+	if tokens < -0.1 || tokens > 0.1 {
+		t.Errorf("canceled wait not released token, tokens [%g]", tokens)
+	}
+}
+
+func TestServerRateLimit(t *testing.T) {
+	s := NewServer("finesse.example.com", true)
+	if err := s.waitRate(context.Background()); err != nil {
+		t.Errorf("wait without limit: %s", err)
+	}
+	s.SetRateLimit(10, 3)
+	if s.limiter == nil || s.limiter.rate != 10 || s.limiter.burst != 3 {
+		t.Fatalf("limiter is %+v", s.limiter)
+	}
+	d := s.derive("finesse-b.example.com", 8445)
+	if d.limiter == nil || d.limiter == s.limiter || d.limiter.rate != 10 || d.limiter.burst != 3 {
+		t.Errorf("derived server limiter is %+v", d.limiter)
+	}
+	s.SetRateLimit(0, 3)
+	if s.limiter != nil {
+		t.Errorf("limiter is not disabled")
+	}
+}
@@ -0,0 +1,161 @@
+package finesse_api
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	ReasonCategoryNotReady = "NOT_READY" // ReasonCategoryNotReady reason codes for Not Ready state
+	ReasonCategoryLogout   = "LOGOUT"    // ReasonCategoryLogout reason codes for Logout state
+)
+
+// ReasonCode one Not Ready or Logout reason code defined on Finesse server
+type ReasonCode struct {
+	Id       int    `xml:"id"`
+	Category string `xml:"category"`
+	Code     string `xml:"code"`
+	Label    string `xml:"label"`
+	ForAll   bool   `xml:"forAll"`
+	URI      string `xml:"uri"`
+}
+
+// ReasonCodes list of reason codes for one category
+type ReasonCodes []ReasonCode
+
+type xmppReasonCodes struct {
+	ReasonCodes ReasonCodes `xml:"ReasonCode"`
+}
+
+func (r ReasonCode) String() string {
+	return fmt.Sprintf("%s (%s/%d)", r.Label, r.Code, r.Id)
+}
+
+// ByLabel find reason code by label, case-insensitive
+func (r ReasonCodes) ByLabel(label string) (ReasonCode, bool) {
+	for _, c := range r {
+		if strings.EqualFold(c.Label, label) {
+			return c, true
+		}
+	}
+	return ReasonCode{}, false
+}
+
+// ById find reason code by Finesse ID (used in state change requests)
+func (r ReasonCodes) ById(id int) (ReasonCode, bool) {
+	for _, c := range r {
+		if c.Id == id {
+			return c, true
+		}
+	}
+	return ReasonCode{}, false
+}
+
+// ByCode find reason code by code number (reported to UCCE)
+func (r ReasonCodes) ByCode(code string) (ReasonCode, bool) {
+	for _, c := range r {
+		if c.Code == code {
+			return c, true
+		}
+	}
+	return ReasonCode{}, false
+}
+
+// Labels all labels in list
+func (r ReasonCodes) Labels() []string {
+	var ret []string
+	for _, c := range r {
+		ret = append(ret, c.Label)
+	}
+	return ret
+}
+
+func newReasonCodes(data string) (ReasonCodes, error) {
+	var r xmppReasonCodes
+	err := xml.Unmarshal([]byte(data), &r)
+	if err != nil {
+		return nil, err
+	}
+	return r.ReasonCodes, nil
+}
+
+// ReasonCodes get reason codes valid for agent in category (ReasonCategoryNotReady or ReasonCategoryLogout)
+//
+// Reason codes are read from Finesse server once and cached on server
+func (s *Server) ReasonCodes(a *Agent, category string) (ReasonCodes, error) {
+	if category != ReasonCategoryNotReady && category != ReasonCategoryLogout {
+		return nil, fmt.Errorf("unknown reason code category [%s]", category)
+	}
+	key := a.LoginId + "/" + category
+	s.mutex.Lock()
+	codes, ok := s.reasonCodes[key]
+	s.mutex.Unlock()
+	if ok {
+		return codes, nil
+	}
+
+	request := a.newAgentRequest()
+	response := request.doRequest("GET", request.server.urlQueryString(request.id, url.Values{"category": {category}}, "User", a.LoginId, "ReasonCodes"), nil)
+	defer response.close()
+	msg, err := response.responseError()
+	if err != nil {
+		s.withFields(Fields{logProc: "ReasonCodes", logId: response.id, logAgent: a.LoginName}).Error(msg)
+		return nil, err
+	}
+	codes, err = newReasonCodes(response.GetResponseBody())
+	if err != nil {
+		s.withFields(Fields{logProc: "ReasonCodes", logId: response.id, logAgent: a.LoginName}).Error(err)
+		return nil, err
+	}
+	s.withFields(Fields{logProc: "ReasonCodes", logId: response.id, logAgent: a.LoginName}).
+		Tracef("collect [%d] reason codes in category [%s]", len(codes), category)
+	s.mutex.Lock()
+	if s.reasonCodes == nil {
+		s.reasonCodes = make(map[string]ReasonCodes)
+	}
+	s.reasonCodes[key] = codes
+	s.mutex.Unlock()
+	return codes, nil
+}
+
+// ClearReasonCodes remove all cached reason codes, next request read it again from Finesse server
+func (s *Server) ClearReasonCodes() {
+	s.mutex.Lock()
+	s.reasonCodes = nil
+	s.mutex.Unlock()
+}
+
+// ReasonCode resolve reason code for agent in category by label, code or Finesse ID
+func (s *Server) ReasonCode(a *Agent, category string, label string) (ReasonCode, error) {
+	codes, err := s.ReasonCodes(a, category)
+	if err != nil {
+		return ReasonCode{}, err
+	}
+	if c, ok := codes.ByLabel(label); ok {
+		return c, nil
+	}
+	if c, ok := codes.ByCode(label); ok {
+		return c, nil
+	}
+	if id, e := strconv.Atoi(label); e == nil {
+		if c, ok := codes.ById(id); ok {
+			return c, nil
+		}
+	}
+	return ReasonCode{}, fmt.Errorf("reason code [%s] not exists in category [%s] for agent [%s]", label, category, a.LoginName)
+}
+
+// ReasonCodeLabel resolve reason code label for agent in category from Finesse ID
+func (s *Server) ReasonCodeLabel(a *Agent, category string, id int) (string, error) {
+	codes, err := s.ReasonCodes(a, category)
+	if err != nil {
+		return "", err
+	}
+	if c, ok := codes.ById(id); ok {
+		return c.Label, nil
+	}
+	return "", fmt.Errorf("reason code ID [%d] not exists in category [%s] for agent [%s]", id, category, a.LoginName)
+}
@@ -0,0 +1,362 @@
+package finesse_api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.opentelemetry.io/otel/trace"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	GroupOperationReconcile  = "RECONCILE" // GroupOperationReconcile operation of AgentGroup.Reconcile
+	ReconcileRetries         = 3           // ReconcileRetries number of retries of transient failure for one agent
+	ReconcileRetryDelay      = 2           // ReconcileRetryDelay delay between retries in seconds
+	DefaultReconcileInterval = 60          // DefaultReconcileInterval period of full check in continuous reconciliation in seconds
+)
+
+// DesiredState target state of agent for AgentGroup.Reconcile
+type DesiredState struct {
+	State  string // State AgentStateReady, AgentStateNotReady or AgentStateLogout
+	Reason string // Reason optional not-ready or logout reason code label, code or ID
+}
+
+// ReconcileResult result of reconciliation for one agent
+type ReconcileResult struct {
+	AgentResult              // AgentResult Operation is GroupOperationReconcile, State is final agent state
+	Desired     DesiredState // Desired target state
+	Initial     string       // Initial agent state before reconciliation
+	Path        []string     // Path executed transitions (requested states)
+	Attempts    int          // Attempts number of attempts, more than 1 when transient failure was retried
+	Drifted     bool         // Drifted agent was not in desired state before reconciliation
+	Busy        bool         // Busy agent works (call, reservation, wrap-up) and state is not changed now
+}
+
+// ReconcileReport results of reconciliation keyed by agent login name
+type ReconcileReport map[string]ReconcileResult
+
+// Reached agent is in desired state after reconciliation
+func (r ReconcileResult) Reached() bool {
+	return r.Success() && !r.Busy
+}
+
+// Drifted sorted login names of agents which were not in desired state
+func (r ReconcileReport) Drifted() []string {
+	var names []string
+	for name, res := range r {
+		if res.Drifted {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Failed sorted login names of agents not reconciled to desired state
+func (r ReconcileReport) Failed() []string {
+	var names []string
+	for name, res := range r {
+		if !res.Reached() {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Result reconciliation as GroupResult, e.g. for export with WriteCsv or WriteJson
+func (r ReconcileReport) Result() GroupResult {
+	ret := make(GroupResult, len(r))
+	for name, res := range r {
+		ret[name] = res.AgentResult
+	}
+	return ret
+}
+
+// Reconcile move agents into desired state keyed by login name, agents without desired state are not changed
+func (group *AgentGroup) Reconcile(desired map[string]DesiredState) ReconcileReport {
+	return group.ReconcileCtx(context.Background(), desired)
+}
+
+// ReconcileCtx move agents into desired state, after context cancel are not started reconciliations for remaining agents
+func (group *AgentGroup) ReconcileCtx(ctx context.Context, desired map[string]DesiredState) ReconcileReport {
+	group.mutex.Lock()
+	agents := make(map[string]*Agent, len(group.Agents))
+	for _, a := range group.Agents {
+		agents[a.LoginName] = a
+	}
+	group.mutex.Unlock()
+
+	names := make([]string, 0, len(desired))
+	for name := range desired {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	ctx, span := startSpan(ctx, "group "+GroupOperationReconcile, trace.SpanKindInternal, attrOperation.String(GroupOperationReconcile), attrAgents.Int(len(names)))
+	defer span.End()
+	res := make(chan ReconcileResult, len(names))
+	group.dispatch(ctx, len(names), func(i int) {
+		a, ok := agents[names[i]]
+		if !ok {
+			res <- ReconcileResult{
+				AgentResult: AgentResult{
+					LoginName: names[i],
+					Operation: GroupOperationReconcile,
+					Started:   time.Now(),
+					Error: OperationError{
+						Type:  TypeErrorRequest,
+						Error: fmt.Errorf("agent [%s] is not member of group", names[i]),
+					},
+				},
+				Desired: desired[names[i]],
+			}
+			return
+		}
+		ctx, agentSpan := startSpan(ctx, "group agent "+GroupOperationReconcile, trace.SpanKindInternal, attrOperation.String(GroupOperationReconcile), attrAgent.String(a.LoginName))
+		r := group.reconcileAgent(ctx, a, desired[names[i]])
+		endSpan(agentSpan, r.Error)
+		res <- r
+	}, func(i int) {
+		res <- ReconcileResult{
+			AgentResult: AgentResult{
+				LoginName: names[i],
+				Operation: GroupOperationReconcile,
+				Started:   time.Now(),
+				Error: OperationError{
+					Type:  TypeErrorCanceled,
+					Error: fmt.Errorf("reconciliation of agent [%s] not started: %w", names[i], ctx.Err()),
+				},
+			},
+			Desired: desired[names[i]],
+		}
+	})
+	report := make(ReconcileReport, len(names))
+	for len(res) > 0 {
+		r := <-res
+		report[r.LoginName] = r
+	}
+	span.SetAttributes(attrFailed.Int(len(report.Failed())))
+	group.withFields(Fields{logProc: "Reconcile"}).Tracef("reconciled [%d] agents, drifted [%d], failed [%d]",
+		len(report), len(report.Drifted()), len(report.Failed()))
+	return report
+}
+
+// ReconcileContinuous keep agents in desired state until context is done
+//
+// Agent is reconciled after its state notification, after XMPP reconnect and every interval (0 is DefaultReconcileInterval).
+// Handler is called for every reconciliation of drifted agent. Returns context error.
+func (group *AgentGroup) ReconcileContinuous(ctx context.Context, desired map[string]DesiredState, interval time.Duration, handler func(ReconcileResult)) error {
+	if interval <= 0 {
+		interval = DefaultReconcileInterval * time.Second
+	}
+	var handlerMutex sync.Mutex
+	report := func(r ReconcileResult) {
+		if handler == nil || !r.Drifted {
+			return
+		}
+		handlerMutex.Lock()
+		defer handlerMutex.Unlock()
+		handler(r)
+	}
+
+	group.mutex.Lock()
+	agents := append([]*Agent(nil), group.Agents...)
+	group.mutex.Unlock()
+	var wg sync.WaitGroup
+	var triggers []chan struct{}
+	for _, a := range agents {
+		d, ok := desired[a.LoginName]
+		if !ok {
+			continue
+		}
+		trigger := make(chan struct{}, 1)
+		trigger <- struct{}{}
+		triggers = append(triggers, trigger)
+		sub := a.Subscribe(EventTypes(EventUser, EventConnection), DeliveryDropOldest)
+		wg.Add(2)
+		go func(a *Agent, sub *Subscription, trigger chan struct{}) {
+			defer wg.Done()
+			for e := range sub.C {
+				switch ev := e.(type) {
+				case UserEvent:
+					if ev.User.LoginId != a.LoginId {
+						continue // supervisor receives changes of team members
+					}
+				case ConnectionEvent:
+					if !ev.Connected {
+						continue
+					}
+				}
+				notifyTrigger(trigger)
+			}
+		}(a, sub, trigger)
+		go func(a *Agent, d DesiredState, sub *Subscription, trigger chan struct{}) {
+			defer wg.Done()
+			defer sub.Close()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-trigger:
+				}
+				report(group.reconcileAgent(ctx, a, d))
+			}
+		}(a, d, sub, trigger)
+	}
+	group.withFields(Fields{logProc: "ReconcileContinuous"}).Tracef("start continuous reconciliation of [%d] agents", len(triggers))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return ctx.Err()
+		case <-ticker.C:
+			for _, t := range triggers {
+				notifyTrigger(t)
+			}
+		}
+	}
+}
+
+// notifyTrigger request reconciliation, pending request is not repeated
+func notifyTrigger(trigger chan struct{}) {
+	select {
+	case trigger <- struct{}{}:
+	default:
+	}
+}
+
+// reconcileAgent read actual agent state and run transitions to desired state, transient failures are retried
+func (group *AgentGroup) reconcileAgent(ctx context.Context, a *Agent, d DesiredState) (r ReconcileResult) {
+	r = ReconcileResult{
+		AgentResult: AgentResult{LoginName: a.LoginName, Operation: GroupOperationReconcile, Started: time.Now()},
+		Desired:     d,
+	}
+	defer func() {
+		r.Duration = time.Since(r.Started)
+		r.State = agentState(a)
+	}()
+	if d.State != AgentStateReady && d.State != AgentStateNotReady && d.State != AgentStateLogout {
+		r.Error = OperationError{Type: TypeErrorRequest, Error: fmt.Errorf("state [%s] is not possible desired state", d.State)}
+		return r
+	}
+	var reason *ReasonCode
+	if len(d.Reason) > 0 && d.State != AgentStateReady {
+		code, err := a.getServer().ReasonCode(a, d.State, d.Reason)
+		if err != nil {
+			r.Error = OperationError{Type: TypeErrorRequest, Error: err}
+			return r
+		}
+		reason = &code
+	}
+
+	for r.Attempts = 1; ; r.Attempts++ {
+		status, err := a.GetStatusCtx(ctx)
+		if err != nil {
+			r.Error = OperationError{Type: TypeErrorNoStatus, Error: err}
+		} else {
+			if r.Attempts == 1 {
+				r.Initial = status.State
+			}
+			path, busy := reconcilePath(status, d.State, reason)
+			r.Drifted = r.Drifted || busy || len(path) > 0
+			r.Busy = busy
+			r.Error = group.reconcileSteps(ctx, a, path, reason, &r.Path)
+			if r.Error.Type == TypeErrorNoError {
+				if r.Drifted {
+					group.withFields(Fields{logProc: "reconcileAgent", logAgent: a.LoginName}).
+						Debugf("agent [%s] reconciled from [%s] by %v to [%s] busy [%t]", a.LoginName, r.Initial, r.Path, d.State, r.Busy)
+				}
+				return r
+			}
+		}
+		if r.Attempts > ReconcileRetries || !transientError(r.Error) {
+			group.withFields(Fields{logProc: "reconcileAgent", logAgent: a.LoginName}).
+				Warnf("agent [%s] not reconciled to [%s] after [%d] attempts: %s", a.LoginName, d.State, r.Attempts, r.Error.Error)
+			return r
+		}
+		select {
+		case <-ctx.Done():
+			r.Error = OperationError{Type: TypeErrorCanceled, Error: ctx.Err()}
+			return r
+		case <-time.After(ReconcileRetryDelay * time.Second):
+		}
+	}
+}
+
+// reconcileSteps run transitions, executed transitions are appended to executed
+func (group *AgentGroup) reconcileSteps(ctx context.Context, a *Agent, path []string, reason *ReasonCode, executed *[]string) OperationError {
+	for _, step := range path {
+		var op OperationError
+		switch {
+		case step == AgentStateLogin:
+			op = a.LoginCtx(ctx)
+		case step == AgentStateReady:
+			op = a.ReadyCtx(ctx)
+		case step == AgentStateNotReady && reason != nil && reason.Category == ReasonCategoryNotReady:
+			op = a.NotReadyWithReasonCtx(ctx, *reason)
+		case step == AgentStateNotReady:
+			op = a.NotReadyCtx(ctx)
+		case step == AgentStateLogout && reason != nil && reason.Category == ReasonCategoryLogout:
+			op = a.LogoutWithReasonCtx(ctx, *reason)
+		case step == AgentStateLogout:
+			op = a.LogoutCtx(ctx)
+		}
+		*executed = append(*executed, step)
+		if op.Type != TypeErrorNoError {
+			return op
+		}
+	}
+	return OperationError{Type: TypeErrorNoError, Error: nil}
+}
+
+// reconcilePath transitions (requested states) planned by state machine from actual status to desired state
+//
+// Busy is true when agent works (call, reservation, wrap-up) and transition is not possible now.
+func reconcilePath(status *XmppUser, desired string, reason *ReasonCode) (path []string, busy bool) {
+	state := status.State
+	switch {
+	case desired == AgentStateReady && AgentReadyStates.Contains(state) && len(status.PendingState) == 0:
+		return nil, false // agent is ready or works
+	case desired == AgentStateNotReady && state == AgentStateNotReady:
+		if reason != nil && status.ReasonCodeId != strconv.Itoa(reason.Id) {
+			return []string{AgentStateNotReady}, false
+		}
+		return nil, false
+	}
+	plan, err := NewStateMachine(status.MediaType, RoleAgent).PlanFor(status, desired)
+	if err != nil {
+		return nil, true
+	}
+	for _, t := range plan {
+		path = append(path, t.To)
+	}
+	if desired == AgentStateNotReady && reason != nil && len(path) > 0 && path[len(path)-1] == AgentStateLogin {
+		path = append(path, AgentStateNotReady) // login ends in not-ready without reason code
+	}
+	return path, false
+}
+
+// transientError failure which can pass when operation is repeated (timeout, connection, server problem, changed state)
+//
+// TypeErrorWrongState is permanent, transition is rejected by state machine for state read in the same attempt.
+func transientError(op OperationError) bool {
+	switch op.Type {
+	case TypeErrorNotifyTimeout, TypeErrorNotConnected:
+		return true
+	case TypeErrorNoStatus:
+		return !errors.Is(op.Error, ErrUnauthorized) && !errors.Is(op.Error, ErrNotFound)
+	case TypeErrorResponse:
+		var fe *FinesseError
+		if errors.As(op.Error, &fe) {
+			return fe.HttpStatus >= 500 || errors.Is(fe, ErrInvalidState)
+		}
+		return !errors.Is(op.Error, context.Canceled) && !errors.Is(op.Error, context.DeadlineExceeded)
+	}
+	return false
+}
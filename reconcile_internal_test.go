@@ -0,0 +1,31 @@
+package finesse_api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestTransientError(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		op   OperationError
+		exp  bool
+	}{
+		{name: "notify timeout", op: OperationError{Type: TypeErrorNotifyTimeout, Error: errors.New("timeout")}, exp: true},
+		{name: "not connected", op: OperationError{Type: TypeErrorNotConnected, Error: ErrNotConnected}, exp: true},
+		{name: "wrong state", op: OperationError{Type: TypeErrorWrongState, Error: ErrInvalidState}, exp: false},
+		{name: "invalid device", op: OperationError{Type: TypeErrorAnalyzeResponse, Error: ErrInvalidDevice}, exp: false},
+		{name: "status", op: OperationError{Type: TypeErrorNoStatus, Error: errors.New("connection refused")}, exp: true},
+		{name: "status unauthorized", op: OperationError{Type: TypeErrorNoStatus, Error: fmt.Errorf("status: %w", ErrUnauthorized)}, exp: false},
+		{name: "server error", op: OperationError{Type: TypeErrorResponse, Error: &FinesseError{HttpStatus: 503}}, exp: true},
+		{name: "changed state", op: OperationError{Type: TypeErrorResponse, Error: &FinesseError{HttpStatus: 400, ErrorType: "Invalid State"}}, exp: true},
+		{name: "bad request", op: OperationError{Type: TypeErrorResponse, Error: &FinesseError{HttpStatus: 400, ErrorType: "Parameter Missing"}}, exp: false},
+		{name: "canceled", op: OperationError{Type: TypeErrorResponse, Error: context.Canceled}, exp: false},
+	} {
+		if r := transientError(tc.op); r != tc.exp {
+			t.Errorf("%s: transient is [%t], expected [%t]", tc.name, r, tc.exp)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package finesse_api_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	api "github.com/pokornyIt/finesse-api"
+	"github.com/pokornyIt/finesse-api/finessetest"
+)
+
+// mockGroup create group of agents connected to mock over plain XMPP, agents must have password "password"
+func mockGroup(t *testing.T, mock *finessetest.Server, agents ...api.BulkAgent) *api.AgentGroup {
+	t.Helper()
+	server := mock.Finesse(true)
+	server.SetLogger(api.NewNopLogger())
+	group := api.NewAgentGroup()
+	t.Cleanup(group.CancelFunction)
+	for i := range agents {
+		agents[i].Password = "password"
+	}
+	if failed := group.AddBulkAgents(agents, server).Failed(); len(failed) > 0 {
+		t.Fatalf("add agents failed %v", failed)
+	}
+	return group
+}
+
+func TestReconcile(t *testing.T) {
+	mock := startMock(t)
+	mock.AddAgent("agent1", "1001", "password", "2001")
+	mock.AddAgent("agent2", "1002", "password", "2002")
+	mock.AddAgent("agent3", "1003", "password", "2003")
+	mock.AddAgent("agent4", "1004", "password", "2004")
+	group := mockGroup(t, mock,
+		api.BulkAgent{Name: "agent1", Line: "2001"},
+		api.BulkAgent{Name: "agent2", Line: "2002"},
+		api.BulkAgent{Name: "agent3", Line: "9999"}, // invalid device
+		api.BulkAgent{Name: "agent4", Line: "2004"},
+	)
+	desired := map[string]api.DesiredState{
+		"agent1":  {State: api.AgentStateReady},
+		"agent2":  {State: api.AgentStateNotReady, Reason: "Break"},
+		"agent3":  {State: api.AgentStateReady},
+		"agent4":  {State: api.AgentStateLogout},
+		"unknown": {State: api.AgentStateReady},
+	}
+
+	report := group.Reconcile(desired)
+	for _, tc := range []struct {
+		name      string
+		errorType int
+		path      []string
+		drifted   bool
+		state     string
+	}{
+		{name: "agent1", path: []string{api.AgentStateLogin, api.AgentStateReady}, drifted: true, state: api.AgentStateReady},
+		{name: "agent2", path: []string{api.AgentStateLogin, api.AgentStateNotReady}, drifted: true, state: api.AgentStateNotReady},
+		{name: "agent3", errorType: api.TypeErrorAnalyzeResponse, path: []string{api.AgentStateLogin}, drifted: true, state: api.AgentStateLogout},
+		{name: "agent4", state: api.AgentStateLogout},
+		{name: "unknown", errorType: api.TypeErrorRequest},
+	} {
+		r, ok := report[tc.name]
+		if !ok {
+			t.Errorf("%s: missing result", tc.name)
+			continue
+		}
+		if r.Error.Type != tc.errorType {
+			t.Errorf("%s: error type is [%d], expected [%d]: %s", tc.name, r.Error.Type, tc.errorType, r.Error.Error)
+		}
+		if !reflect.DeepEqual(r.Path, tc.path) {
+			t.Errorf("%s: path is %v, expected %v", tc.name, r.Path, tc.path)
+		}
+		if r.Drifted != tc.drifted {
+			t.Errorf("%s: drifted is [%t], expected [%t]", tc.name, r.Drifted, tc.drifted)
+		}
+		if r.State != tc.state {
+			t.Errorf("%s: state is [%s], expected [%s]", tc.name, r.State, tc.state)
+		}
+		if r.Attempts > 1 {
+			t.Errorf("%s: permanent result retried [%d] attempts", tc.name, r.Attempts)
+		}
+	}
+	if failed := report.Failed(); !reflect.DeepEqual(failed, []string{"agent3", "unknown"}) {
+		t.Errorf("failed agents are %v", failed)
+	}
+	if state := mock.AgentState("1002"); state != api.AgentStateNotReady {
+		t.Errorf("agent2: mock state is [%s], expected [%s]", state, api.AgentStateNotReady)
+	}
+
+	// the second pass finds agents in desired state
+	delete(desired, "agent3")
+	delete(desired, "unknown")
+	report = group.Reconcile(desired)
+	if drifted := report.Drifted(); len(drifted) > 0 {
+		t.Errorf("second pass: drifted agents %v", drifted)
+	}
+	if failed := report.Failed(); len(failed) > 0 {
+		t.Errorf("second pass: failed agents %v", failed)
+	}
+}
+
+func TestReconcileContinuous(t *testing.T) {
+	mock := startMock(t)
+	mock.AddAgent("agent1", "1001", "password", "2001")
+	mock.AddAgent("agent2", "1002", "password", "2002")
+	group := mockGroup(t, mock,
+		api.BulkAgent{Name: "agent1", Line: "2001"},
+		api.BulkAgent{Name: "agent2", Line: "9999"}, // invalid device
+	)
+	desired := map[string]api.DesiredState{
+		"agent1": {State: api.AgentStateReady},
+		"agent2": {State: api.AgentStateReady},
+	}
+
+	results := make(chan api.ReconcileResult, 100)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- group.ReconcileContinuous(ctx, desired, time.Hour, func(r api.ReconcileResult) {
+			results <- r
+		})
+	}()
+	defer func() {
+		cancel()
+		if err := <-done; !errors.Is(err, context.Canceled) {
+			t.Errorf("continuous reconciliation ends with [%v], expected [%s]", err, context.Canceled)
+		}
+	}()
+
+	// await reconciled agent, agent2 with invalid device is reported once without retry
+	await := func(step string) {
+		t.Helper()
+		for {
+			select {
+			case r := <-results:
+				if r.LoginName == "agent2" {
+					if r.Error.Type != api.TypeErrorAnalyzeResponse || r.Attempts != 1 {
+						t.Errorf("%s: agent2 error type [%d] after [%d] attempts", step, r.Error.Type, r.Attempts)
+					}
+					continue
+				}
+				if !r.Reached() {
+					t.Fatalf("%s: agent1 not reconciled: %s", step, r.Error.Error)
+				}
+				return
+			case <-time.After(5 * time.Second):
+				t.Fatalf("%s: agent1 not reconciled in time", step)
+			}
+		}
+	}
+	await("initial")
+	if state := mock.AgentState("1001"); state != api.AgentStateReady {
+		t.Fatalf("initial: mock state is [%s], expected [%s]", state, api.AgentStateReady)
+	}
+
+	// change by supervisor is reverted after notification
+	if err := mock.SetAgentState("1001", api.AgentStateNotReady); err != nil {
+		t.Fatalf("set mock state: %s", err)
+	}
+	await("drift")
+	if state := mock.AgentState("1001"); state != api.AgentStateReady {
+		t.Errorf("drift: mock state is [%s], expected [%s]", state, api.AgentStateReady)
+	}
+}
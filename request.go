@@ -2,9 +2,13 @@ package finesse_api
 
 import (
 	"bytes"
-	"crypto/tls"
+	"context"
 	"fmt"
-	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
 	"net/http"
 	"time"
 )
@@ -18,6 +22,7 @@ type AgentRequest struct {
 	client    *http.Client
 	server    *Server
 	request   *http.Request
+	logging   logSettings // logging log settings of agent or server
 }
 
 // setHeader create request header
@@ -25,43 +30,73 @@ func (f *AgentRequest) setHeader() {
 	if f.request.Method != "GET" {
 		f.request.Header.Set("Content-Type", "application/xml")
 	}
-	f.request.Header.Set("User-Agent", "Finesse/1.0")
+	f.request.Header.Set("User-Agent", f.server.userAgent)
 	f.request.Header.Set("Accept", "*/*")
 	f.request.Header.Set("Cache-Control", "no-cache")
 	//f.request.Header.Set("Pragma", "no-cache")
 	f.request.Header.Set("RequestId", f.id)
 	f.request.Host = f.server.name
-	f.request.SetBasicAuth(f.loginName, f.password)
+	if len(f.loginName) > 0 {
+		f.request.SetBasicAuth(f.loginName, f.password)
+	}
 }
 
-// httpClient prepare httpClient for request
+// httpClient use shared HTTP client of server when request has no client
 func (f *AgentRequest) httpClient() {
 	if f.client == nil {
-		tr := &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		}
-		f.client = &http.Client{Timeout: time.Duration(f.server.timeOut) * time.Second, Transport: tr}
-		log.WithFields(log.Fields{logProc: "httpClient", logId: f.id}).Debugf("prepare HTTP client for server [%s] in request", f.server.name)
+		f.client = f.server.getHttpClient()
+		f.logging.withFields(Fields{logProc: "httpClient", logId: f.id}).Tracef("use shared HTTP client of server [%s] in request", f.server.name)
 	}
 }
 
 // doRequest process one request
 func (f *AgentRequest) doRequest(method string, url string, data []byte) *AgentResponse {
-	log.WithFields(log.Fields{logProc: "doRequest", logId: f.id, logRequestType: method, logBody: string(data)}).Tracef("start process request [%s %s]", method, url)
-	request, err := http.NewRequest(method, url, bytes.NewBuffer(data))
+	return f.doRequestCtx(context.Background(), method, url, data)
+}
+
+// doRequestCtx process one request, request is canceled with context
+//
+// Span of request includes wait for rate limit, trace context is propagated in request header.
+func (f *AgentRequest) doRequestCtx(ctx context.Context, method string, url string, data []byte) *AgentResponse {
+	f.logging.withFields(Fields{logProc: "doRequest", logId: f.id, logRequestType: method, logBody: string(data)}).Tracef("start process request [%s %s]", method, url)
+	ctx, span := startSpan(ctx, fmt.Sprintf("%s %s", method, endpointName(url)), trace.SpanKindClient,
+		attrAgent.String(f.loginName), attrServer.String(f.server.name), attrRequestId.String(f.id),
+		semconv.HTTPMethodKey.String(method), semconv.HTTPURLKey.String(url))
+	defer span.End()
+	if err := f.server.waitRate(ctx); err != nil {
+		r := fmt.Sprintf("request [%s %s] not sent, wait for rate limit canceled", method, url)
+		f.logging.withFields(Fields{logProc: "doRequest", logId: f.id}).Error(r)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, r)
+		return f.newResponse(nil, err, r)
+	}
+	request, err := http.NewRequestWithContext(f.server.pool.withTrace(ctx), method, url, bytes.NewBuffer(data))
 	if err != nil {
-		log.WithFields(log.Fields{logProc: "doRequest", logId: f.id}).Errorf(
+		f.logging.withFields(Fields{logProc: "doRequest", logId: f.id}).Errorf(
 			"problem create [%s %s] request for [%s] agent with error %s", method, url, f.loginName, err)
 	}
 	f.request = request
 	f.setHeader()
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(f.request.Header))
 	f.httpClient()
+	started := time.Now()
 	resp, err := f.client.Do(f.request)
+	status := 0
+	if resp != nil {
+		status = resp.StatusCode
+	}
+	observeRequest(f.server.name, method, url, status, started)
 	if err != nil {
 		r := fmt.Sprintf("problem request [%s %s]", f.request.Method, f.request.URL)
-		log.WithFields(log.Fields{logProc: "doRequest", logId: f.id}).Error(r)
+		f.logging.withFields(Fields{logProc: "doRequest", logId: f.id}).Error(r)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, r)
 		return f.newResponse(resp, err, r)
 	}
+	span.SetAttributes(semconv.HTTPStatusCodeKey.Int(status))
+	if status >= 400 {
+		span.SetStatus(codes.Error, resp.Status)
+	}
 	return f.newResponse(resp, nil, "")
 }
 
@@ -72,6 +107,7 @@ func (f *AgentRequest) newResponse(response *http.Response, e error, message str
 	r.response = response
 	r.err = e
 	r.lastMessage = message
+	r.logging = f.logging
 	if response != nil {
 		r.statusCode = response.StatusCode
 		r.statusMessage = response.Status
@@ -79,6 +115,6 @@ func (f *AgentRequest) newResponse(response *http.Response, e error, message str
 		r.statusCode = 500
 		r.statusMessage = "500 Problem Connect to server"
 	}
-	log.WithFields(log.Fields{logProc: "NewResponse", logId: r.id}).Tracef("response with status [%s]", r.statusMessage)
+	f.logging.withFields(Fields{logProc: "NewResponse", logId: r.id}).Tracef("response with status [%s]", r.statusMessage)
 	return r
 }
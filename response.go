@@ -2,7 +2,6 @@ package finesse_api
 
 import (
 	"fmt"
-	log "github.com/sirupsen/logrus"
 	"io"
 	"net/http"
 )
@@ -14,8 +13,10 @@ type AgentResponse struct {
 	err           error
 	lastMessage   string
 	body          string
+	bodyRead      bool
 	statusCode    int
 	statusMessage string
+	logging       logSettings // logging log settings of request
 }
 
 func (f *AgentResponse) close() {
@@ -32,16 +33,21 @@ func (f *AgentResponse) responseError() (string, error) {
 		return "", nil
 	}
 	if len(f.lastMessage) > 0 {
+		if f.err != nil {
+			return f.lastMessage, fmt.Errorf("%s: %w", f.lastMessage, f.err)
+		}
 		return f.lastMessage, fmt.Errorf(f.lastMessage)
 	}
-	return f.lastMessage, fmt.Errorf("reponse with error [%s]", f.statusMessage)
+	e := newRestError(f.statusCode, f.statusMessage, f.id, f.GetResponseBody())
+	return e.Error(), e
 }
 
 // GetResponseBody Read API response body
 func (f *AgentResponse) GetResponseBody() string {
-	if f.response == nil {
+	if f.response == nil || f.bodyRead {
 		return f.body
 	}
+	f.bodyRead = true
 	err := f.responseReturnData()
 	if err != nil {
 		f.err = err
@@ -50,18 +56,18 @@ func (f *AgentResponse) GetResponseBody() string {
 }
 
 func (f *AgentResponse) responseReturnData() error {
-	log.WithFields(log.Fields{logProc: "responseReturnData", logId: f.id, logHttpStatus: f.response.Status}).
+	f.logging.withFields(Fields{logProc: "responseReturnData", logId: f.id, logHttpStatus: f.response.Status}).
 		Debugf("response status is [%s]", f.response.Status)
 	bodies, err := io.ReadAll(f.response.Body)
 	_ = f.response.Body.Close()
 	f.body = ""
 
 	if err != nil {
-		log.WithFields(log.Fields{logProc: "responseReturnData", logId: f.id}).Errorf("problem get body from response [%s]", err)
+		f.logging.withFields(Fields{logProc: "responseReturnData", logId: f.id}).Errorf("problem get body from response [%s]", err)
 		return err
 	}
 	f.body = string(bodies)
-	log.WithFields(log.Fields{logProc: "responseReturnData", logId: f.id}).Tracef("body read success [%s %s]", f.response.Request.Method, f.response.Request.URL)
+	f.logging.withFields(Fields{logProc: "responseReturnData", logId: f.id}).Tracef("body read success [%s %s]", f.response.Request.Method, f.response.Request.URL)
 	if f.statusCode > 299 {
 		return fmt.Errorf(f.statusMessage)
 	}
@@ -0,0 +1,311 @@
+package finesse_api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// Option configure Server created by NewServerWithOptions
+type Option func(s *Server) error
+
+// NewServerWithOptions create Finesse server structure with standard ports and timeouts changed by options
+//
+// Example:
+//
+//	server, err := NewServerWithOptions("finesse.example.com",
+//		WithRootCAFile("/etc/finesse/ca.pem"),
+//		WithRestTimeout(10*time.Second),
+//		WithUserAgent("dialer/2.1"))
+func NewServerWithOptions(name string, options ...Option) (*Server, error) {
+	s := NewServerDetail(name, DefaultServerHttpsPort, false, 0, false, DefaultServerTimeout)
+	for _, option := range options {
+		if err := option(s); err != nil {
+			return nil, fmt.Errorf("server [%s] option: %w", name, err)
+		}
+	}
+	if s.xmppPort == 0 {
+		s.xmppPort = DefaultServerXmppPort
+		if s.insecureXmpp {
+			s.xmppPort = DefaultServerDirectXmppPort
+		}
+	}
+	return s, nil
+}
+
+// WithPort Finesse REST API port
+func WithPort(port int) Option {
+	return func(s *Server) error {
+		if port < 1 || port > 65535 {
+			return fmt.Errorf("invalid port [%d]", port)
+		}
+		s.port = port
+		return nil
+	}
+}
+
+// WithXmppPort XMPP notification port, default is DefaultServerXmppPort or DefaultServerDirectXmppPort for plain XMPP
+func WithXmppPort(port int) Option {
+	return func(s *Server) error {
+		if port < 1 || port > 65535 {
+			return fmt.Errorf("invalid XMPP port [%d]", port)
+		}
+		s.xmppPort = port
+		return nil
+	}
+}
+
+// WithInsecureXmpp connect plain XMPP instead of XMPP over WSS
+func WithInsecureXmpp() Option {
+	return func(s *Server) error {
+		s.insecureXmpp = true
+		return nil
+	}
+}
+
+// WithInsecureSkipVerify ignore invalid server certificate, pinned keys are still verified
+func WithInsecureSkipVerify() Option {
+	return func(s *Server) error {
+		s.ignore = true
+		return nil
+	}
+}
+
+// WithRootCAs verify server certificate with certificate pool instead of system trust store
+func WithRootCAs(pool *x509.CertPool) Option {
+	return func(s *Server) error {
+		if pool == nil {
+			return fmt.Errorf("root CA pool is nil")
+		}
+		s.baseTLSConfig().RootCAs = pool
+		return nil
+	}
+}
+
+// WithRootCAFile verify server certificate with PEM certificates from file (e.g. downloaded Finesse tomcat certificate)
+func WithRootCAFile(path string) Option {
+	return func(s *Server) error {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return fmt.Errorf("no PEM certificate in file [%s]", path)
+		}
+		s.baseTLSConfig().RootCAs = pool
+		return nil
+	}
+}
+
+// WithClientCertificates certificates presented to server for mutual TLS
+func WithClientCertificates(certificates ...tls.Certificate) Option {
+	return func(s *Server) error {
+		c := s.baseTLSConfig()
+		c.Certificates = append(c.Certificates, certificates...)
+		return nil
+	}
+}
+
+// WithClientCertificateFile PEM certificate and key files presented to server for mutual TLS
+func WithClientCertificateFile(certFile string, keyFile string) Option {
+	return func(s *Server) error {
+		certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return err
+		}
+		c := s.baseTLSConfig()
+		c.Certificates = append(c.Certificates, certificate)
+		return nil
+	}
+}
+
+// WithPinnedSPKI accept only server certificate chain with public key of base64 SHA-256 hash of SubjectPublicKeyInfo
+//
+// Pin is checked in verified chain, with WithInsecureSkipVerify only server (leaf) certificate can be pinned.
+//
+// Hash is created e.g. by:
+//
+//	openssl x509 -in finesse.pem -pubkey -noout | openssl pkey -pubin -outform der | openssl dgst -sha256 -binary | base64
+func WithPinnedSPKI(hashes ...string) Option {
+	return func(s *Server) error {
+		if len(hashes) == 0 {
+			return fmt.Errorf("no pinned SPKI hash")
+		}
+		for _, h := range hashes {
+			pin, err := base64.StdEncoding.DecodeString(h)
+			if err != nil || len(pin) != sha256.Size {
+				return fmt.Errorf("invalid SPKI SHA-256 hash [%s]", h)
+			}
+			s.pins = append(s.pins, pin)
+		}
+		return nil
+	}
+}
+
+// WithProxy send REST requests through HTTP proxy, default is proxy from environment (HTTPS_PROXY, NO_PROXY)
+func WithProxy(proxyUrl string) Option {
+	return func(s *Server) error {
+		u, err := url.Parse(proxyUrl)
+		if err != nil {
+			return err
+		}
+		if len(u.Host) == 0 {
+			return fmt.Errorf("invalid proxy URL [%s]", proxyUrl)
+		}
+		s.proxy = http.ProxyURL(u)
+		return nil
+	}
+}
+
+// WithRoundTripper send REST requests by custom transport, TLS and proxy options are not used for REST requests
+func WithRoundTripper(roundTripper http.RoundTripper) Option {
+	return func(s *Server) error {
+		if roundTripper == nil {
+			return fmt.Errorf("round tripper is nil")
+		}
+		s.roundTripper = roundTripper
+		return nil
+	}
+}
+
+// WithUserAgent User-Agent header of REST requests
+func WithUserAgent(userAgent string) Option {
+	return func(s *Server) error {
+		s.userAgent = userAgent
+		return nil
+	}
+}
+
+// WithRestTimeout timeout of REST requests
+func WithRestTimeout(timeout time.Duration) Option {
+	return func(s *Server) error {
+		if timeout <= 0 {
+			return fmt.Errorf("invalid REST timeout [%s]", timeout)
+		}
+		s.restTimeout = timeout
+		return nil
+	}
+}
+
+// WithXmppTimeout timeout of XMPP connect and wait for notification confirming request
+func WithXmppTimeout(timeout time.Duration) Option {
+	return func(s *Server) error {
+		if timeout <= 0 {
+			return fmt.Errorf("invalid XMPP timeout [%s]", timeout)
+		}
+		s.xmppTimeout = timeout
+		return nil
+	}
+}
+
+// WithMaxIdleConnsPerHost kept-alive connections to Finesse node shared by agents, default is DefaultMaxIdleConnsPerHost
+func WithMaxIdleConnsPerHost(n int) Option {
+	return func(s *Server) error {
+		if n < 1 {
+			return fmt.Errorf("invalid number of idle connections [%d]", n)
+		}
+		s.maxIdleConnsPerHost = n
+		return nil
+	}
+}
+
+// WithLogger logger of server, its agents and requests
+func WithLogger(l Logger) Option {
+	return func(s *Server) error {
+		s.SetLogger(l)
+		return nil
+	}
+}
+
+// WithRateLimit limit REST requests per second with burst, see Server.SetRateLimit
+func WithRateLimit(requestsPerSecond float64, burst int) Option {
+	return func(s *Server) error {
+		s.SetRateLimit(requestsPerSecond, burst)
+		return nil
+	}
+}
+
+// baseTLSConfig TLS configuration modified by options
+func (s *Server) baseTLSConfig() *tls.Config {
+	if s.tlsConfig == nil {
+		s.tlsConfig = &tls.Config{}
+	}
+	return s.tlsConfig
+}
+
+// TLSConfig TLS configuration of REST and XMPP connections with root CA, client certificates and pinned keys
+func (s *Server) TLSConfig() *tls.Config {
+	c := &tls.Config{}
+	if s.tlsConfig != nil {
+		c = s.tlsConfig.Clone()
+	}
+	c.InsecureSkipVerify = s.ignore
+	if len(s.pins) > 0 {
+		pins, skipVerify := s.pins, s.ignore
+		c.VerifyConnection = func(state tls.ConnectionState) error {
+			return verifyPins(state, pins, skipVerify)
+		}
+	}
+	return c
+}
+
+// verifyPins check server certificate chain has pinned public key
+//
+// Only verified chains are trusted, with skipVerify presented certificates are not verified
+// and only server (leaf) certificate is checked.
+func verifyPins(state tls.ConnectionState, pins [][]byte, skipVerify bool) error {
+	if skipVerify {
+		if len(state.PeerCertificates) > 0 && pinned(state.PeerCertificates[0], pins) {
+			return nil
+		}
+		return fmt.Errorf("server certificate public key is not pinned")
+	}
+	for _, chain := range state.VerifiedChains {
+		for _, certificate := range chain {
+			if pinned(certificate, pins) {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("server certificate public key is not pinned")
+}
+
+// pinned certificate public key is one of pinned keys
+func pinned(certificate *x509.Certificate, pins [][]byte) bool {
+	hash := sha256.Sum256(certificate.RawSubjectPublicKeyInfo)
+	for _, pin := range pins {
+		if bytes.Equal(hash[:], pin) {
+			return true
+		}
+	}
+	return false
+}
+
+// derive copy of server settings for other host and port, used for agents with own server in AgentGroup
+func (s *Server) derive(name string, port int) *Server {
+	d := NewServerDetail(name, port, s.ignore, s.xmppPort, s.insecureXmpp, 0)
+	d.restTimeout = s.restTimeout
+	d.xmppTimeout = s.xmppTimeout
+	d.tlsConfig = s.tlsConfig
+	d.pins = s.pins
+	d.proxy = s.proxy
+	d.roundTripper = s.roundTripper
+	d.userAgent = s.userAgent
+	d.maxIdleConnsPerHost = s.maxIdleConnsPerHost
+	d.logging.Store(s.logSettings())
+	// derived server is other Finesse node with own limit of the same rate
+	s.mutex.Lock()
+	if s.limiter != nil {
+		d.limiter = newRateLimiter(s.limiter.rate, int(s.limiter.burst))
+	}
+	s.mutex.Unlock()
+	return d
+}
@@ -0,0 +1,278 @@
+package finesse_api
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultPairCheckInterval = 10 // DefaultPairCheckInterval health check interval of server pair in seconds
+)
+
+// FailoverEvent active node of server pair changed
+type FailoverEvent struct {
+	From   *Server   // From previous active node
+	To     *Server   // To new active node
+	Reason string    // Reason why previous node is not usable
+	Time   time.Time // Time of change
+}
+
+func (e FailoverEvent) String() string {
+	return fmt.Sprintf("failover from [%s] to [%s] (%s)", e.From.name, e.To.name, e.Reason)
+}
+
+// ServerPair Finesse side A/side B deployment
+//
+// REST requests and XMPP notifications of agents in pair use active node. Pair checks health of both nodes
+// with SystemInfo and moves agents to other node, when active node fails or reports OUT_OF_SERVICE.
+type ServerPair struct {
+	Primary   *Server // Primary side A node
+	Secondary *Server // Secondary side B node
+
+	active   *Server
+	agents   []*Agent
+	pending  map[*Agent]struct{} // pending agents not moved to active node yet
+	interval int
+	handler  func(FailoverEvent)
+	cancel   context.CancelFunc
+	done     chan struct{}
+	mutex    sync.Mutex
+	moves    sync.Mutex // moves serializes moves of agents between nodes
+}
+
+// NewServerPair create server pair, primary node is active until first health check
+func NewServerPair(primary *Server, secondary *Server) *ServerPair {
+	return &ServerPair{
+		Primary:   primary,
+		Secondary: secondary,
+		active:    primary,
+		pending:   make(map[*Agent]struct{}),
+		interval:  DefaultPairCheckInterval,
+	}
+}
+
+// Active actual active node
+func (p *ServerPair) Active() *Server {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.active
+}
+
+// SetCheckInterval set health check interval in seconds, used after next Start
+func (p *ServerPair) SetCheckInterval(seconds int) {
+	p.mutex.Lock()
+	p.interval = seconds
+	p.mutex.Unlock()
+}
+
+// SetFailoverHandler set callback called after active node change
+func (p *ServerPair) SetFailoverHandler(handler func(FailoverEvent)) {
+	p.mutex.Lock()
+	p.handler = handler
+	p.mutex.Unlock()
+}
+
+// CreateAgent create agent on active node and add it into pair
+//
+// When active node fails, pair checks both nodes and repeat create on new active node.
+func (p *ServerPair) CreateAgent(ctx context.Context, name string, pwd string, line string) (*Agent, error) {
+	node := p.Active()
+	a, err := node.CreateAgent(ctx, name, pwd, line)
+	if err != nil {
+		if active := p.Check(); active == node {
+			return nil, err
+		}
+		p.Primary.withFields(Fields{logProc: "PairCreateAgent", logAgent: name}).Debugf("repeat create agent [%s] on other node", name)
+		if a, err = p.Active().CreateAgent(ctx, name, pwd, line); err != nil {
+			return nil, err
+		}
+	}
+	p.mutex.Lock()
+	p.agents = append(p.agents, a)
+	p.mutex.Unlock()
+	return a, nil
+}
+
+// AddAgent add existing agent into pair, agent from other node is moved to active node
+func (p *ServerPair) AddAgent(a *Agent) error {
+	p.mutex.Lock()
+	p.agents = append(p.agents, a)
+	p.mutex.Unlock()
+	return p.moveAgent(a)
+}
+
+// RemoveAgent remove agent from pair, agent stays on actual node
+func (p *ServerPair) RemoveAgent(a *Agent) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	delete(p.pending, a)
+	for i, v := range p.agents {
+		if v == a {
+			p.agents = append(p.agents[:i], p.agents[i+1:]...)
+			return
+		}
+	}
+}
+
+// Check health of active node, if active node is not in service and other node is, switch agents to other node
+//
+// Returns active node after check.
+func (p *ServerPair) Check() *Server {
+	p.mutex.Lock()
+	active := p.active
+	p.mutex.Unlock()
+	other := p.Secondary
+	if active == p.Secondary {
+		other = p.Primary
+	}
+
+	reason := nodeProblem(active)
+	if reason == "" {
+		p.movePending()
+		return active
+	}
+	p.Primary.withFields(Fields{logProc: "PairCheck", logServer: active.name}).Warnf("active node [%s] problem: %s", active.name, reason)
+	if problem := nodeProblem(other); problem != "" {
+		p.Primary.withFields(Fields{logProc: "PairCheck", logServer: other.name}).Errorf("both nodes are not usable, other node [%s] problem: %s", other.name, problem)
+		return active
+	}
+	p.failover(active, other, reason)
+	return other
+}
+
+// Start periodic health check of both nodes
+func (p *ServerPair) Start(ctx context.Context) {
+	p.mutex.Lock()
+	if p.cancel != nil {
+		p.mutex.Unlock()
+		return
+	}
+	ctx, p.cancel = context.WithCancel(ctx)
+	p.done = make(chan struct{})
+	interval := time.Duration(p.interval) * time.Second
+	done := p.done
+	p.mutex.Unlock()
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			p.Check()
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop periodic health check
+func (p *ServerPair) Stop() {
+	p.mutex.Lock()
+	cancel := p.cancel
+	done := p.done
+	p.cancel = nil
+	p.done = nil
+	p.mutex.Unlock()
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+}
+
+// nodeProblem check node SystemInfo, returns empty string for node in service
+func nodeProblem(s *Server) string {
+	info, err := s.SystemInfo()
+	if err != nil {
+		return err.Error()
+	}
+	if !info.InService() {
+		return fmt.Sprintf("status %s", info.Status)
+	}
+	return ""
+}
+
+// failover switch active node and move all agents
+func (p *ServerPair) failover(from *Server, to *Server, reason string) {
+	p.mutex.Lock()
+	p.active = to
+	agents := make([]*Agent, len(p.agents))
+	copy(agents, p.agents)
+	handler := p.handler
+	p.mutex.Unlock()
+
+	p.Primary.withFields(Fields{logProc: "PairFailover", logServer: to.name}).Infof("failover from [%s] to [%s]", from.name, to.name)
+	for _, a := range agents {
+		_ = p.moveAgent(a)
+	}
+	if handler != nil {
+		handler(FailoverEvent{From: from, To: to, Reason: reason, Time: time.Now()})
+	}
+}
+
+// movePending repeat move of agents, which fails in last failover
+func (p *ServerPair) movePending() {
+	p.mutex.Lock()
+	agents := make([]*Agent, 0, len(p.pending))
+	for a := range p.pending {
+		agents = append(agents, a)
+	}
+	p.mutex.Unlock()
+	for _, a := range agents {
+		_ = p.moveAgent(a)
+	}
+}
+
+// moveAgent switch agent REST requests and XMPP notification to active node, failed agents are repeated in next check
+//
+// Moves are serialized, agent already moved to active node or removed from pair is not moved again.
+func (p *ServerPair) moveAgent(a *Agent) error {
+	p.moves.Lock()
+	defer p.moves.Unlock()
+	p.mutex.Lock()
+	to := p.active
+	_, pending := p.pending[a]
+	member := p.hasAgent(a)
+	p.mutex.Unlock()
+	if !member || (a.getServer() == to && !pending) {
+		return nil
+	}
+
+	a.StopXmpp()
+	a.setServer(to)
+	err := a.StartXmpp()
+	p.mutex.Lock()
+	if err != nil && p.hasAgent(a) {
+		p.pending[a] = struct{}{}
+	} else {
+		delete(p.pending, a)
+	}
+	p.mutex.Unlock()
+	if err != nil {
+		p.Primary.withFields(Fields{logProc: "PairMoveAgent", logAgent: a.LoginName, logServer: to.name}).
+			Errorf("agent [%s] XMPP not started on node [%s]: %s", a.LoginName, to.name, err)
+		return err
+	}
+	// notifications during move are lost, resync agent state
+	if _, err = a.GetStatus(); err != nil {
+		p.Primary.withFields(Fields{logProc: "PairMoveAgent", logAgent: a.LoginName, logServer: to.name}).
+			Warnf("resync state for agent [%s] fails %s", a.LoginName, err)
+	}
+	p.Primary.withFields(Fields{logProc: "PairMoveAgent", logAgent: a.LoginName, logServer: to.name}).
+		Debugf("agent [%s] moved to node [%s]", a.LoginName, to.name)
+	return nil
+}
+
+// hasAgent agent is in pair, must be called with locked mutex
+func (p *ServerPair) hasAgent(a *Agent) bool {
+	for _, v := range p.agents {
+		if v == a {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+package finesse_api_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	api "github.com/pokornyIt/finesse-api"
+	"github.com/pokornyIt/finesse-api/finessetest"
+)
+
+// startPair start two mock nodes and server pair, agent1 is configured on primary node
+func startPair(t *testing.T) (*finessetest.Server, *finessetest.Server, *api.ServerPair) {
+	t.Helper()
+	mockA, mockB := startMock(t), startMock(t)
+	mockA.AddAgent("agent1", "1001", "password", "2001")
+	return mockA, mockB, api.NewServerPair(pairNode(t, mockA), pairNode(t, mockB))
+}
+
+// pairNode server connected to mock with short timeouts, failed XMPP login waits for XMPP timeout
+func pairNode(t *testing.T, mock *finessetest.Server) *api.Server {
+	t.Helper()
+	s, err := api.NewServerWithOptions(mock.Host,
+		api.WithPort(mock.RestPort()),
+		api.WithXmppPort(mock.XmppPort()),
+		api.WithInsecureXmpp(),
+		api.WithInsecureSkipVerify(),
+		api.WithRestTimeout(time.Second),
+		api.WithXmppTimeout(time.Second),
+		api.WithLogger(api.NewNopLogger()))
+	if err != nil {
+		t.Fatalf("pair node: %s", err)
+	}
+	return s
+}
+
+// checkLoginOn agent login is processed by mock node
+func checkLoginOn(t *testing.T, a *api.Agent, mock *finessetest.Server) {
+	t.Helper()
+	checkOperation(t, "login", a.Login())
+	if state := mock.AgentState(a.LoginId); state != api.AgentStateNotReady {
+		t.Errorf("login: state on node is [%s], expected [%s]", state, api.AgentStateNotReady)
+	}
+}
+
+func TestServerPairFailover(t *testing.T) {
+	mockA, mockB, pair := startPair(t)
+	mockB.AddAgent("agent1", "1001", "password", "2001")
+	var events []api.FailoverEvent
+	pair.SetFailoverHandler(func(e api.FailoverEvent) {
+		events = append(events, e)
+	})
+	a, err := pair.CreateAgent(context.Background(), "agent1", "password", "2001")
+	if err != nil {
+		t.Fatalf("create agent: %s", err)
+	}
+	defer a.StopXmpp()
+	if active := pair.Check(); active != pair.Primary {
+		t.Fatalf("active node is not primary")
+	}
+
+	mockA.SetSystemStatus(api.SystemStatusOutOfService)
+	if active := pair.Check(); active != pair.Secondary || pair.Active() != pair.Secondary {
+		t.Fatalf("active node is not secondary after failover")
+	}
+	if len(events) != 1 || events[0].From != pair.Primary || events[0].To != pair.Secondary || events[0].Reason != "status OUT_OF_SERVICE" {
+		t.Errorf("failover events are %v", events)
+	}
+	if !a.IsConnected() {
+		t.Errorf("agent XMPP is not connected after failover")
+	}
+	checkLoginOn(t, a, mockB)
+	if state := mockA.AgentState("1001"); state != api.AgentStateLogout {
+		t.Errorf("state on primary node is [%s], expected [%s]", state, api.AgentStateLogout)
+	}
+
+	// both nodes are not usable, active node is not changed
+	mockB.SetSystemStatus(api.SystemStatusOutOfService)
+	if active := pair.Check(); active != pair.Secondary || len(events) != 1 {
+		t.Errorf("failover without usable node")
+	}
+}
+
+func TestServerPairPendingMove(t *testing.T) {
+	mockA, mockB, pair := startPair(t)
+	a, err := pair.CreateAgent(context.Background(), "agent1", "password", "2001")
+	if err != nil {
+		t.Fatalf("create agent: %s", err)
+	}
+	defer a.StopXmpp()
+
+	// agent is not configured on secondary node yet, XMPP login fails
+	mockA.SetSystemStatus(api.SystemStatusOutOfService)
+	if active := pair.Check(); active != pair.Secondary {
+		t.Fatalf("active node is not secondary after failover")
+	}
+	if a.IsConnected() {
+		t.Fatalf("agent XMPP is connected to node without agent")
+	}
+
+	mockB.AddAgent("agent1", "1001", "password", "2001")
+	pair.Check()
+	if !a.IsConnected() {
+		t.Fatalf("pending agent is not moved in next check")
+	}
+	checkLoginOn(t, a, mockB)
+}
+
+func TestServerPairConcurrentMove(t *testing.T) {
+	for i := 0; i < 5; i++ {
+		mockA, mockB, pair := startPair(t)
+		mockB.AddAgent("agent1", "1001", "password", "2001")
+		a, err := pair.Primary.CreateAgent(context.Background(), "agent1", "password", "2001")
+		if err != nil {
+			t.Fatalf("create agent: %s", err)
+		}
+
+		// agent is added during failover
+		mockA.SetSystemStatus(api.SystemStatusOutOfService)
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pair.Check()
+		}()
+		if err = pair.AddAgent(a); err != nil {
+			t.Errorf("add agent: %s", err)
+		}
+		wg.Wait()
+		if pair.Active() != pair.Secondary {
+			t.Fatalf("active node is not secondary after failover")
+		}
+		if !a.IsConnected() {
+			t.Fatalf("agent XMPP is not connected after concurrent move")
+		}
+		checkLoginOn(t, a, mockB)
+		a.StopXmpp()
+	}
+}
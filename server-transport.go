@@ -0,0 +1,131 @@
+package finesse_api
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	DefaultMaxIdleConnsPerHost = 100              // DefaultMaxIdleConnsPerHost kept-alive connections to Finesse node reused by all agents of server
+	DefaultIdleConnTimeout     = 90 * time.Second // DefaultIdleConnTimeout idle connection is closed after timeout
+)
+
+// PoolStats statistics of server REST connection pool
+type PoolStats struct {
+	Open          int64 // Open actually open connections
+	Dialed        int64 // Dialed opened connections
+	Closed        int64 // Closed closed connections
+	Requests      int64 // Requests requests which got connection
+	Reused        int64 // Reused requests sent over reused connection (keep-alive or HTTP/2)
+	TLSHandshakes int64 // TLSHandshakes successful TLS handshakes
+}
+
+// connPool counters of connections opened by server transport
+type connPool struct {
+	dialed     atomic.Int64
+	closed     atomic.Int64
+	requests   atomic.Int64
+	reused     atomic.Int64
+	handshakes atomic.Int64
+}
+
+// poolConn connection counted when closed
+type poolConn struct {
+	net.Conn
+	pool *connPool
+	once sync.Once
+}
+
+func (c *poolConn) Close() error {
+	c.once.Do(func() { c.pool.closed.Add(1) })
+	return c.Conn.Close()
+}
+
+// dialContext dial connection counted in pool
+func (p *connPool) dialContext(dialer *net.Dialer) func(ctx context.Context, network string, address string) (net.Conn, error) {
+	return func(ctx context.Context, network string, address string) (net.Conn, error) {
+		conn, err := dialer.DialContext(ctx, network, address)
+		if err != nil {
+			return nil, err
+		}
+		p.dialed.Add(1)
+		return &poolConn{Conn: conn, pool: p}, nil
+	}
+}
+
+// withTrace count connection reuse and TLS handshakes of request
+func (p *connPool) withTrace(ctx context.Context) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			p.requests.Add(1)
+			if info.Reused {
+				p.reused.Add(1)
+			}
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			if err == nil {
+				p.handshakes.Add(1)
+			}
+		},
+	})
+}
+
+// getHttpClient HTTP client shared by all requests of server, created with first request
+func (s *Server) getHttpClient() *http.Client {
+	s.clientOnce.Do(func() {
+		s.client = s.newHttpClient()
+	})
+	return s.client
+}
+
+// newHttpClient create client with transport tuned for many agents on one Finesse node
+func (s *Server) newHttpClient() *http.Client {
+	if s.roundTripper != nil {
+		return &http.Client{Transport: s.roundTripper, Timeout: s.restTimeout}
+	}
+	proxy := s.proxy
+	if proxy == nil {
+		proxy = http.ProxyFromEnvironment
+	}
+	maxIdle := s.maxIdleConnsPerHost
+	if maxIdle == 0 {
+		maxIdle = DefaultMaxIdleConnsPerHost
+	}
+	transport := &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           s.pool.dialContext(&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}),
+		TLSClientConfig:       s.TLSConfig(),
+		ForceAttemptHTTP2:     true,
+		MaxIdleConnsPerHost:   maxIdle,
+		IdleConnTimeout:       DefaultIdleConnTimeout,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+	s.withFields(Fields{logProc: "newHttpClient", logServer: s.name}).
+		Debugf("prepare shared HTTP client for server [%s] with [%d] idle connections", s.name, maxIdle)
+	return &http.Client{Transport: transport, Timeout: s.restTimeout}
+}
+
+// PoolStats statistics of REST connection pool, custom round tripper reports only requests and reuse
+func (s *Server) PoolStats() PoolStats {
+	dialed, closed := s.pool.dialed.Load(), s.pool.closed.Load()
+	return PoolStats{
+		Open:          dialed - closed,
+		Dialed:        dialed,
+		Closed:        closed,
+		Requests:      s.pool.requests.Load(),
+		Reused:        s.pool.reused.Load(),
+		TLSHandshakes: s.pool.handshakes.Load(),
+	}
+}
+
+// CloseIdleConnections close idle connections of REST connection pool
+func (s *Server) CloseIdleConnections() {
+	s.getHttpClient().CloseIdleConnections()
+}
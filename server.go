@@ -4,28 +4,48 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
-	log "github.com/sirupsen/logrus"
 	"net/http"
+	"net/url"
 	"path"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // Server Structure for finesse server data
 type Server struct {
-	name         string // name is FQDN of server or IP address
-	port         int    // port for finesse API
-	ignore       bool   // ignore invalid certificate
-	xmppPort     int    // port for XMPP notification
-	insecureXmpp bool   // insecureXmpp for connect insecure direct XMPP instead of WSS
-	timeOut      int    // timeOut for API requests default is 30 sec
+	name         string        // name is FQDN of server or IP address
+	port         int           // port for finesse API
+	ignore       bool          // ignore invalid certificate
+	xmppPort     int           // port for XMPP notification
+	insecureXmpp bool          // insecureXmpp for connect insecure direct XMPP instead of WSS
+	restTimeout  time.Duration // restTimeout for API requests default is 30 sec
+	xmppTimeout  time.Duration // xmppTimeout for XMPP connect and wait for notification, default is XmppTimeout sec
+
+	tlsConfig    *tls.Config                           // tlsConfig base TLS configuration (root CA, client certificates)
+	pins         [][]byte                              // pins SHA-256 hashes of accepted server public keys (SPKI)
+	proxy        func(*http.Request) (*url.URL, error) // proxy for REST requests, default from environment
+	roundTripper http.RoundTripper                     // roundTripper custom transport for REST requests
+	userAgent    string                                // userAgent User-Agent header of REST requests
+
+	client              *http.Client // client shared by all requests of server
+	clientOnce          sync.Once
+	pool                connPool // pool statistics of REST connections
+	maxIdleConnsPerHost int      // maxIdleConnsPerHost kept-alive connections, 0 is DefaultMaxIdleConnsPerHost
+
+	reasonCodes map[string]ReasonCodes // reasonCodes cache of reason codes per agent and category
+	limiter     *rateLimiter           // limiter optional rate limit of REST requests
+	logging     atomic.Value           // logging logSettings of server, agents and requests
+	mutex       sync.Mutex
 }
 
 const (
-	DefaultServerHttpsPort      = 8445 // DefaultServerHttpsPort standard Finesse API port
-	DefaultServerXmppPort       = 7443 // DefaultServerXmppPort standard secure XMPP over WSS port (secure XMPP communication)
-	DefaultServerDirectXmppPort = 5222 // DefaultServerDirectXmppPort insecure XMPP port for direct communication (by default disabled on Finesse server)
-	DefaultServerTimeout        = 30   // DefaultServerTimeout define timeout for API and Notify communication in seconds
+	DefaultServerHttpsPort      = 8445          // DefaultServerHttpsPort standard Finesse API port
+	DefaultServerXmppPort       = 7443          // DefaultServerXmppPort standard secure XMPP over WSS port (secure XMPP communication)
+	DefaultServerDirectXmppPort = 5222          // DefaultServerDirectXmppPort insecure XMPP port for direct communication (by default disabled on Finesse server)
+	DefaultServerTimeout        = 30            // DefaultServerTimeout define timeout for API and Notify communication in seconds
+	DefaultUserAgent            = "Finesse/1.0" // DefaultUserAgent User-Agent header of REST requests
 )
 
 // NewServer Creating new Finesse server structure connect on standard ports and manage if ignore certificate problems
@@ -49,34 +69,36 @@ func NewServerDetail(server string, port int, ignore bool, xmppPort int, insecur
 		ignore:       ignore,
 		xmppPort:     xmppPort,
 		insecureXmpp: insecureXmpp,
-		timeOut:      timeOut,
+		restTimeout:  time.Duration(timeOut) * time.Second,
+		xmppTimeout:  time.Duration(XmppTimeout) * time.Second,
+		userAgent:    DefaultUserAgent,
 	}
 }
 
+// Name FQDN or IP address of server
+func (s *Server) Name() string {
+	return s.name
+}
+
 // CreateAgent create new agent, read agent ID from Finesse server, start XMPP notify connection and return Agent or error if problem
 //
 //   - ctx context.Context - used for graceful shutdown of XMPP connection
 func (s *Server) CreateAgent(ctx context.Context, name string, pwd string, line string) (*Agent, error) {
-	log.WithFields(log.Fields{logProc: "AddAgent", logAgent: name}).Tracef("prepare agent and try collect it's ID")
+	s.withFields(Fields{logProc: "AddAgent", logAgent: name}).Tracef("prepare agent and try collect it's ID")
 	a := NewAgentNotify(ctx, name, pwd, line, s)
-	err := a.getId()
+	err := a.getId(ctx)
 	if err != nil {
-		log.WithFields(log.Fields{logProc: "AddAgent", logAgent: name}).Tracef("can't get actual agent state")
+		s.withFields(Fields{logProc: "AddAgent", logAgent: name}).Tracef("can't get actual agent state")
+		return nil, err
+	}
+	if err = a.StartXmpp(); err != nil {
+		s.withFields(Fields{logProc: "AddAgent", logAgent: name}).Tracef("can't start XMPP notification")
 		return nil, err
 	}
 
 	return a, nil
 }
 
-// getHttpClient create httpclient with setup from server configuration
-func (s *Server) getHttpClient() *http.Client {
-	customTransport := http.DefaultTransport.(*http.Transport).Clone()
-	customTransport.TLSClientConfig = &tls.Config{InsecureSkipVerify: s.ignore}
-	client := &http.Client{Transport: customTransport, Timeout: time.Duration(s.timeOut) * time.Second}
-
-	return client
-}
-
 // urlString create full API request path
 //
 // Expect:
@@ -87,22 +109,37 @@ func (s *Server) getHttpClient() *http.Client {
 //   - urlString("xmp", "6350") => https://{server:port}/finesse/api/6350
 //   - urlString("xmp", "6350", "Dialogs) => https://{server:port}/finesse/api/6350/Dialogs
 func (s *Server) urlString(rId string, pathPart ...string) string {
-	restPath := "/finesse/api"
-	if len(pathPart) > 0 {
-		for _, s := range pathPart {
-			restPath = path.Join(restPath, s)
-		}
-	}
+	restPath := s.apiPath(pathPart...)
 	var url string
 	if s.port != 80 {
 		url = fmt.Sprintf("https://%s:%d%s", s.name, s.port, restPath)
 	} else {
 		url = fmt.Sprintf("https://%s%s", s.name, restPath)
 	}
-	log.WithFields(log.Fields{logProc: "urlString", logServer: s.name, logId: rId}).Tracef("Request URI: %s", url)
+	s.withFields(Fields{logProc: "urlString", logServer: s.name, logId: rId}).Tracef("Request URI: %s", url)
 	return url
 }
 
+// urlQueryString create full API request path with encoded query
+//
+// Example:
+//   - urlQueryString("xmp", url.Values{"category": {"NOT_READY"}}, "User", "6350", "ReasonCodes") => https://{server:port}/finesse/api/User/6350/ReasonCodes?category=NOT_READY
+func (s *Server) urlQueryString(rId string, query url.Values, pathPart ...string) string {
+	return s.urlString(rId, pathPart...) + "?" + query.Encode()
+}
+
+// apiPath create API path without server, used also as source of XMPP notifications
+//
+// Example:
+//   - apiPath("User", "6350") => /finesse/api/User/6350
+func (s *Server) apiPath(pathPart ...string) string {
+	restPath := "/finesse/api"
+	for _, p := range pathPart {
+		restPath = path.Join(restPath, p)
+	}
+	return restPath
+}
+
 // getDomain get only domain from Finesse server FQDN, for IP address or only host returns empty string
 func (s *Server) getDomain() string {
 	if validIpAddress(s.name) {
@@ -0,0 +1,250 @@
+package finesse_api
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	MediaTypeVoice    = "voice"    // MediaTypeVoice voice (CTI) media, Finesse reports media type 1
+	MediaTypeNonVoice = "nonvoice" // MediaTypeNonVoice non-voice media (Digital Routing)
+)
+
+// Transition one legal change of agent state requested by user
+type Transition struct {
+	From    string // From actual agent state
+	To      string // To requested state
+	Result  string // Result agent state after transition (e.g. LOGIN results in NOT_READY)
+	Pending bool   // Pending requested state is applied after end of call and reported in XmppUser.PendingState
+}
+
+// TransitionError requested transition is not legal in state machine
+type TransitionError struct {
+	From      string // From actual agent state
+	To        string // To requested state
+	MediaType string // MediaType media type of state machine
+	Role      string // Role role of requesting user
+	Reason    string // Reason why transition is not legal
+}
+
+// StateMachine legal transitions of agent state for media type and role of user requesting change
+//
+// Role RoleAgent is used when agent changes own state, RoleSupervisor when supervisor changes state of team member.
+type StateMachine struct {
+	MediaType   string
+	Role        string
+	transitions map[string][]Transition // transitions by actual state
+}
+
+// transitionRule requested state allowed from list of states
+type transitionRule struct {
+	from    []string
+	to      string
+	result  string // result state, empty is the same as requested state
+	pending bool
+}
+
+var callStates = []string{AgentStateTalking, AgentStateHold}
+
+var voiceAgentRules = []transitionRule{
+	{from: []string{AgentStateLogout}, to: AgentStateLogin, result: AgentStateNotReady},
+	{from: []string{AgentStateNotReady, AgentStateWorkNotReady, AgentStateWorkReady}, to: AgentStateReady},
+	{from: []string{AgentStateReady, AgentStateNotReady, AgentStateWorkNotReady, AgentStateWorkReady}, to: AgentStateNotReady},
+	{from: []string{AgentStateNotReady}, to: AgentStateLogout},
+	{from: callStates, to: AgentStateReady, pending: true},
+	{from: callStates, to: AgentStateNotReady, pending: true},
+	{from: callStates, to: AgentStateWork, result: AgentStateWorkNotReady, pending: true},
+	{from: callStates, to: AgentStateWorkReady, pending: true},
+}
+
+var voiceSupervisorRules = []transitionRule{
+	{from: []string{AgentStateNotReady}, to: AgentStateReady},
+	{from: []string{AgentStateReady, AgentStateNotReady}, to: AgentStateNotReady},
+	{from: []string{AgentStateReady, AgentStateNotReady}, to: AgentStateLogout},
+	{from: callStates, to: AgentStateNotReady, pending: true},
+	{from: callStates, to: AgentStateLogout, pending: true},
+}
+
+var nonVoiceAgentRules = []transitionRule{
+	{from: []string{AgentStateLogout}, to: AgentStateLogin, result: AgentStateNotReady},
+	{from: []string{AgentStateNotReady, AgentStateWorkNotReady, AgentStateWorkReady}, to: AgentStateReady},
+	{from: []string{AgentStateReady, AgentStateNotReady, AgentStateWorkNotReady, AgentStateWorkReady}, to: AgentStateNotReady},
+	{from: []string{AgentStateNotReady}, to: AgentStateLogout},
+	{from: []string{AgentStateActive, AgentStatePaused, AgentStateInterrupted}, to: AgentStateReady, pending: true},
+	{from: []string{AgentStateActive, AgentStatePaused, AgentStateInterrupted}, to: AgentStateNotReady, pending: true},
+}
+
+var nonVoiceSupervisorRules = []transitionRule{
+	{from: []string{AgentStateNotReady}, to: AgentStateReady},
+	{from: []string{AgentStateReady, AgentStateNotReady}, to: AgentStateNotReady},
+	{from: []string{AgentStateReady, AgentStateNotReady}, to: AgentStateLogout},
+}
+
+// stateNotes explanation for states without transitions
+var stateNotes = map[string]string{
+	AgentStateLogin:    "login is in progress",
+	AgentStateReserved: "agent is reserved for incoming call",
+	AgentStateUnknown:  "agent state is unknown",
+}
+
+// transitionNotes explanation for common illegal transitions
+var transitionNotes = map[[2]string]string{
+	{AgentStateReady, AgentStateLogout}:    "agent must be in NOT_READY state before logout",
+	{AgentStateLogout, AgentStateReady}:    "agent must login first",
+	{AgentStateLogout, AgentStateNotReady}: "agent must login first",
+	{AgentStateLogout, AgentStateLogout}:   "agent is logged out",
+	{AgentStateReady, AgentStateReady}:     "agent is already ready",
+}
+
+var stateMachines = map[string]map[string]*StateMachine{
+	MediaTypeVoice: {
+		RoleAgent:      newStateMachine(MediaTypeVoice, RoleAgent, voiceAgentRules),
+		RoleSupervisor: newStateMachine(MediaTypeVoice, RoleSupervisor, voiceSupervisorRules),
+	},
+	MediaTypeNonVoice: {
+		RoleAgent:      newStateMachine(MediaTypeNonVoice, RoleAgent, nonVoiceAgentRules),
+		RoleSupervisor: newStateMachine(MediaTypeNonVoice, RoleSupervisor, nonVoiceSupervisorRules),
+	},
+}
+
+func newStateMachine(mediaType string, role string, rules []transitionRule) *StateMachine {
+	m := &StateMachine{MediaType: mediaType, Role: role, transitions: make(map[string][]Transition)}
+	for _, r := range rules {
+		result := r.result
+		if len(result) == 0 {
+			result = r.to
+		}
+		for _, from := range r.from {
+			m.transitions[from] = append(m.transitions[from], Transition{From: from, To: r.to, Result: result, Pending: r.pending})
+		}
+	}
+	return m
+}
+
+// NewStateMachine state machine for media type (MediaTypeVoice, MediaTypeNonVoice or Finesse media type) and role
+//
+// Finesse media type "1" and empty value are voice, role other than RoleSupervisor is RoleAgent.
+func NewStateMachine(mediaType string, role string) *StateMachine {
+	mediaType = normalizeMediaType(mediaType)
+	if role != RoleSupervisor {
+		role = RoleAgent
+	}
+	return stateMachines[mediaType][role]
+}
+
+// normalizeMediaType convert Finesse media type to MediaTypeVoice or MediaTypeNonVoice
+func normalizeMediaType(mediaType string) string {
+	switch strings.ToLower(mediaType) {
+	case "", "1", MediaTypeVoice, "cisco_voice":
+		return MediaTypeVoice
+	}
+	return MediaTypeNonVoice
+}
+
+// Transitions legal transitions from state
+func (m *StateMachine) Transitions(from string) []Transition {
+	return append([]Transition(nil), m.transitions[from]...)
+}
+
+// Requestable state can be requested at least from one state
+func (m *StateMachine) Requestable(to string) bool {
+	for _, list := range m.transitions {
+		for _, t := range list {
+			if t.To == to {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Transition find legal transition, error explains why transition is not legal
+func (m *StateMachine) Transition(from string, to string) (Transition, error) {
+	for _, t := range m.transitions[from] {
+		if t.To == to {
+			return t, nil
+		}
+	}
+	return Transition{}, m.transitionError(from, to, "")
+}
+
+// PathTo plan transitions from state to target state (or requested state e.g. LOGIN, WORK) with minimal number of steps
+//
+// Pending transition can be only the last step, empty path means agent is already in target state.
+func (m *StateMachine) PathTo(from string, target string) ([]Transition, error) {
+	if from == target {
+		return nil, nil
+	}
+	previous := map[string]Transition{from: {}}
+	queue := []string{from}
+	for len(queue) > 0 {
+		state := queue[0]
+		queue = queue[1:]
+		for _, t := range m.transitions[state] {
+			if t.To == target || t.Result == target {
+				path := []Transition{t}
+				for s := state; s != from; s = previous[s].From {
+					path = append([]Transition{previous[s]}, path...)
+				}
+				return path, nil
+			}
+			if _, visited := previous[t.Result]; visited || t.Pending {
+				continue
+			}
+			previous[t.Result] = t
+			queue = append(queue, t.Result)
+		}
+	}
+	return nil, m.transitionError(from, target, "no path to target state")
+}
+
+// PlanFor plan transitions from actual user status, requested pending state is not requested again
+func (m *StateMachine) PlanFor(status *XmppUser, target string) ([]Transition, error) {
+	if status == nil {
+		return nil, m.transitionError(AgentStateUnknown, target, "")
+	}
+	if len(status.PendingState) > 0 && status.PendingState == target {
+		return nil, nil
+	}
+	return m.PathTo(status.State, target)
+}
+
+// transitionError explain illegal transition
+func (m *StateMachine) transitionError(from string, to string, reason string) *TransitionError {
+	e := &TransitionError{From: from, To: to, MediaType: m.MediaType, Role: m.Role, Reason: reason}
+	if note, ok := transitionNotes[[2]string{from, to}]; ok {
+		e.Reason = note
+		return e
+	}
+	if note, ok := stateNotes[from]; ok && len(m.transitions[from]) == 0 {
+		e.Reason = note
+		return e
+	}
+	if !m.Requestable(to) {
+		e.Reason = fmt.Sprintf("state [%s] can't be requested", to)
+		return e
+	}
+	var allowed []string
+	for _, t := range m.transitions[from] {
+		allowed = append(allowed, t.To)
+	}
+	sort.Strings(allowed)
+	if len(e.Reason) == 0 {
+		if len(allowed) == 0 {
+			e.Reason = "no state can be requested"
+		} else {
+			e.Reason = fmt.Sprintf("allowed requested states are %v", allowed)
+		}
+	}
+	return e
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("transition [%s -> %s] is not allowed for %s %s: %s", e.From, e.To, e.MediaType, strings.ToLower(e.Role), e.Reason)
+}
+
+// Is transition error match ErrInvalidState
+func (e *TransitionError) Is(target error) bool {
+	return target == ErrInvalidState
+}
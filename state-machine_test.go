@@ -0,0 +1,199 @@
+package finesse_api_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	api "github.com/pokornyIt/finesse-api"
+)
+
+// requested states of path
+func pathStates(path []api.Transition) []string {
+	var ret []string
+	for _, t := range path {
+		ret = append(ret, t.To)
+	}
+	return ret
+}
+
+func TestNewStateMachine(t *testing.T) {
+	for _, tc := range []struct {
+		mediaType string
+		role      string
+		expMedia  string
+		expRole   string
+	}{
+		{mediaType: "", role: "", expMedia: api.MediaTypeVoice, expRole: api.RoleAgent},
+		{mediaType: "1", role: api.RoleAgent, expMedia: api.MediaTypeVoice, expRole: api.RoleAgent},
+		{mediaType: "Cisco_Voice", role: api.RoleSupervisor, expMedia: api.MediaTypeVoice, expRole: api.RoleSupervisor},
+		{mediaType: "chat", role: "Admin", expMedia: api.MediaTypeNonVoice, expRole: api.RoleAgent},
+	} {
+		m := api.NewStateMachine(tc.mediaType, tc.role)
+		if m.MediaType != tc.expMedia || m.Role != tc.expRole {
+			t.Errorf("[%s/%s]: state machine is [%s/%s], expected [%s/%s]", tc.mediaType, tc.role, m.MediaType, m.Role, tc.expMedia, tc.expRole)
+		}
+	}
+}
+
+func TestStateMachineTransitions(t *testing.T) {
+	m := api.NewStateMachine(api.MediaTypeVoice, api.RoleAgent)
+	if states := pathStates(m.Transitions(api.AgentStateNotReady)); !reflect.DeepEqual(states, []string{api.AgentStateReady, api.AgentStateNotReady, api.AgentStateLogout}) {
+		t.Errorf("NOT_READY: transitions are %v", states)
+	}
+	if states := pathStates(m.Transitions(api.AgentStateReserved)); len(states) > 0 {
+		t.Errorf("RESERVED: transitions are %v, expected none", states)
+	}
+	list := m.Transitions(api.AgentStateLogout)
+	list[0].To = api.AgentStateReady
+	if states := pathStates(m.Transitions(api.AgentStateLogout)); !reflect.DeepEqual(states, []string{api.AgentStateLogin}) {
+		t.Errorf("LOGOUT: transitions changed by caller %v", states)
+	}
+}
+
+func TestStateMachineRequestable(t *testing.T) {
+	for _, tc := range []struct {
+		mediaType string
+		role      string
+		state     string
+		exp       bool
+	}{
+		{mediaType: api.MediaTypeVoice, role: api.RoleAgent, state: api.AgentStateLogin, exp: true},
+		{mediaType: api.MediaTypeVoice, role: api.RoleAgent, state: api.AgentStateWork, exp: true},
+		{mediaType: api.MediaTypeVoice, role: api.RoleAgent, state: api.AgentStateTalking, exp: false},
+		{mediaType: api.MediaTypeVoice, role: api.RoleSupervisor, state: api.AgentStateLogin, exp: false},
+		{mediaType: api.MediaTypeVoice, role: api.RoleSupervisor, state: api.AgentStateLogout, exp: true},
+		{mediaType: api.MediaTypeNonVoice, role: api.RoleAgent, state: api.AgentStateWork, exp: false},
+		{mediaType: api.MediaTypeNonVoice, role: api.RoleAgent, state: api.AgentStateReady, exp: true},
+	} {
+		if r := api.NewStateMachine(tc.mediaType, tc.role).Requestable(tc.state); r != tc.exp {
+			t.Errorf("%s %s [%s]: requestable is [%t], expected [%t]", tc.mediaType, tc.role, tc.state, r, tc.exp)
+		}
+	}
+}
+
+func TestStateMachineTransition(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		role    string
+		from    string
+		to      string
+		result  string
+		pending bool
+		reason  string // reason of TransitionError, empty for legal transition
+	}{
+		{name: "login", role: api.RoleAgent, from: api.AgentStateLogout, to: api.AgentStateLogin, result: api.AgentStateNotReady},
+		{name: "ready", role: api.RoleAgent, from: api.AgentStateNotReady, to: api.AgentStateReady, result: api.AgentStateReady},
+		{name: "pending wrap-up", role: api.RoleAgent, from: api.AgentStateTalking, to: api.AgentStateWork, result: api.AgentStateWorkNotReady, pending: true},
+		{name: "logout from ready", role: api.RoleAgent, from: api.AgentStateReady, to: api.AgentStateLogout, reason: "agent must be in NOT_READY state before logout"},
+		{name: "ready when logged out", role: api.RoleAgent, from: api.AgentStateLogout, to: api.AgentStateReady, reason: "agent must login first"},
+		{name: "reserved", role: api.RoleAgent, from: api.AgentStateReserved, to: api.AgentStateNotReady, reason: "agent is reserved for incoming call"},
+		{name: "not requestable", role: api.RoleAgent, from: api.AgentStateNotReady, to: api.AgentStateTalking, reason: "state [TALKING] can't be requested"},
+		{name: "allowed states", role: api.RoleAgent, from: api.AgentStateWorkReady, to: api.AgentStateLogout, reason: "allowed requested states are [NOT_READY READY]"},
+		{name: "supervisor logout", role: api.RoleSupervisor, from: api.AgentStateReady, to: api.AgentStateLogout, result: api.AgentStateLogout},
+		{name: "supervisor login", role: api.RoleSupervisor, from: api.AgentStateLogout, to: api.AgentStateLogin, reason: "state [LOGIN] can't be requested"},
+		{name: "supervisor ready", role: api.RoleSupervisor, from: api.AgentStateLogout, to: api.AgentStateReady, reason: "agent must login first"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			m := api.NewStateMachine(api.MediaTypeVoice, tc.role)
+			tr, err := m.Transition(tc.from, tc.to)
+			if len(tc.reason) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error %s", err)
+				}
+				if tr.From != tc.from || tr.To != tc.to || tr.Result != tc.result || tr.Pending != tc.pending {
+					t.Errorf("transition is %+v", tr)
+				}
+				return
+			}
+			var errTransition *api.TransitionError
+			if !errors.As(err, &errTransition) {
+				t.Fatalf("error [%v] is not TransitionError", err)
+			}
+			if errTransition.Reason != tc.reason {
+				t.Errorf("reason is [%s], expected [%s]", errTransition.Reason, tc.reason)
+			}
+			if errTransition.From != tc.from || errTransition.To != tc.to || errTransition.Role != tc.role {
+				t.Errorf("transition error is %+v", errTransition)
+			}
+			if !errors.Is(err, api.ErrInvalidState) {
+				t.Errorf("error [%s] is not ErrInvalidState", err)
+			}
+		})
+	}
+}
+
+func TestStateMachinePathTo(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		mediaType string
+		role      string
+		from      string
+		target    string
+		exp       []string // requested states of path
+		fail      bool
+	}{
+		{name: "same state", mediaType: api.MediaTypeVoice, role: api.RoleAgent, from: api.AgentStateReady, target: api.AgentStateReady},
+		{name: "login result", mediaType: api.MediaTypeVoice, role: api.RoleAgent, from: api.AgentStateLogout, target: api.AgentStateNotReady, exp: []string{api.AgentStateLogin}},
+		{name: "login and ready", mediaType: api.MediaTypeVoice, role: api.RoleAgent, from: api.AgentStateLogout, target: api.AgentStateReady, exp: []string{api.AgentStateLogin, api.AgentStateReady}},
+		{name: "not-ready before logout", mediaType: api.MediaTypeVoice, role: api.RoleAgent, from: api.AgentStateReady, target: api.AgentStateLogout, exp: []string{api.AgentStateNotReady, api.AgentStateLogout}},
+		{name: "wrap-up to logout", mediaType: api.MediaTypeVoice, role: api.RoleAgent, from: api.AgentStateWorkReady, target: api.AgentStateLogout, exp: []string{api.AgentStateNotReady, api.AgentStateLogout}},
+		{name: "requested work", mediaType: api.MediaTypeVoice, role: api.RoleAgent, from: api.AgentStateTalking, target: api.AgentStateWork, exp: []string{api.AgentStateWork}},
+		{name: "pending is last step", mediaType: api.MediaTypeVoice, role: api.RoleAgent, from: api.AgentStateTalking, target: api.AgentStateLogout, fail: true},
+		{name: "not requestable", mediaType: api.MediaTypeVoice, role: api.RoleAgent, from: api.AgentStateLogout, target: api.AgentStateReserved, fail: true},
+		{name: "reserved", mediaType: api.MediaTypeVoice, role: api.RoleAgent, from: api.AgentStateReserved, target: api.AgentStateReady, fail: true},
+		{name: "supervisor ready to logout", mediaType: api.MediaTypeVoice, role: api.RoleSupervisor, from: api.AgentStateReady, target: api.AgentStateLogout, exp: []string{api.AgentStateLogout}},
+		{name: "supervisor can't login", mediaType: api.MediaTypeVoice, role: api.RoleSupervisor, from: api.AgentStateLogout, target: api.AgentStateReady, fail: true},
+		{name: "non-voice pending", mediaType: api.MediaTypeNonVoice, role: api.RoleAgent, from: api.AgentStateActive, target: api.AgentStateNotReady, exp: []string{api.AgentStateNotReady}},
+		{name: "non-voice without work", mediaType: api.MediaTypeNonVoice, role: api.RoleAgent, from: api.AgentStateNotReady, target: api.AgentStateWork, fail: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path, err := api.NewStateMachine(tc.mediaType, tc.role).PathTo(tc.from, tc.target)
+			if tc.fail {
+				if err == nil {
+					t.Fatalf("path %v found, expected error", pathStates(path))
+				}
+				if !errors.Is(err, api.ErrInvalidState) {
+					t.Errorf("error [%s] is not ErrInvalidState", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %s", err)
+			}
+			if states := pathStates(path); !reflect.DeepEqual(states, tc.exp) {
+				t.Errorf("path is %v, expected %v", states, tc.exp)
+			}
+			for i, tr := range path {
+				if tr.Pending && i < len(path)-1 {
+					t.Errorf("pending transition [%s] is not the last step", tr.To)
+				}
+				if i > 0 && path[i-1].Result != tr.From {
+					t.Errorf("step [%s -> %s] not follows result [%s]", tr.From, tr.To, path[i-1].Result)
+				}
+			}
+		})
+	}
+}
+
+func TestStateMachinePlanFor(t *testing.T) {
+	m := api.NewStateMachine(api.MediaTypeVoice, api.RoleAgent)
+	_, err := m.PlanFor(nil, api.AgentStateReady)
+	var errTransition *api.TransitionError
+	if !errors.As(err, &errTransition) || errTransition.From != api.AgentStateUnknown {
+		t.Errorf("without status: error is [%v], expected transition from [%s]", err, api.AgentStateUnknown)
+	}
+
+	path, err := m.PlanFor(&api.XmppUser{State: api.AgentStateTalking, PendingState: api.AgentStateNotReady}, api.AgentStateNotReady)
+	if err != nil || len(path) > 0 {
+		t.Errorf("pending state: path is %v with error [%v], expected empty path", pathStates(path), err)
+	}
+
+	path, err = m.PlanFor(&api.XmppUser{State: api.AgentStateReady, PendingState: api.AgentStateNotReady}, api.AgentStateLogout)
+	if err != nil {
+		t.Fatalf("other pending state: unexpected error %s", err)
+	}
+	if states := pathStates(path); !reflect.DeepEqual(states, []string{api.AgentStateNotReady, api.AgentStateLogout}) {
+		t.Errorf("other pending state: path is %v", states)
+	}
+}
@@ -0,0 +1,195 @@
+package finesse_api
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	RoleAgent      = "Agent"      // RoleAgent role of every agent
+	RoleSupervisor = "Supervisor" // RoleSupervisor role of team supervisor
+)
+
+// Supervisor agent with Supervisor role, reads roster of supervised teams and changes state of team members
+type Supervisor struct {
+	*Agent
+}
+
+// HasRole user has role in last known status
+func (a *Agent) HasRole(role string) bool {
+	if a.lastStatus == nil {
+		return false
+	}
+	for _, r := range a.lastStatus.Roles.Role {
+		if strings.EqualFold(r, role) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewSupervisor create supervisor from agent, agent must have Supervisor role
+func NewSupervisor(a *Agent) (*Supervisor, error) {
+	if a.lastStatus == nil {
+		if _, err := a.GetStatus(); err != nil {
+			return nil, err
+		}
+	}
+	if !a.HasRole(RoleSupervisor) {
+		a.withFields(Fields{logProc: "NewSupervisor", logAgent: a.LoginName}).Errorf("user [%s] has not role [%s]", a.LoginName, RoleSupervisor)
+		return nil, fmt.Errorf("user [%s] has not role [%s]: %w", a.LoginName, RoleSupervisor, ErrUnauthorized)
+	}
+	return &Supervisor{Agent: a}, nil
+}
+
+// TeamIds IDs of teams supervised by supervisor
+func (s *Supervisor) TeamIds() []string {
+	var ret []string
+	for _, t := range s.lastStatus.Teams.Team {
+		ret = append(ret, strconv.Itoa(t.Id))
+	}
+	return ret
+}
+
+// Team get team with all members including logged out agents
+func (s *Supervisor) Team(teamId string) (*XmppTeam, error) {
+	return s.TeamCtx(context.Background(), teamId)
+}
+
+// TeamCtx get team with all members including logged out agents, context cancel request
+func (s *Supervisor) TeamCtx(ctx context.Context, teamId string) (*XmppTeam, error) {
+	request := s.newAgentRequest()
+	response := request.doRequestCtx(ctx, "GET", request.server.urlQueryString(request.id, url.Values{"includeLoggedOutAgents": {"true"}}, "Team", url.PathEscape(teamId)), nil)
+	defer response.close()
+	msg, err := response.responseError()
+	if err != nil {
+		s.withFields(Fields{logProc: "Team", logId: response.id, logAgent: s.LoginName}).Error(msg)
+		return nil, err
+	}
+	team, err := newXmppTeam(response.GetResponseBody())
+	if err != nil {
+		s.withFields(Fields{logProc: "Team", logId: response.id, logAgent: s.LoginName}).Error(err)
+		return nil, err
+	}
+	s.withFields(Fields{logProc: "Team", logId: response.id, logAgent: s.LoginName}).
+		Tracef("team [%s] has [%d] users", team.Name, len(team.Users.User))
+	return team, nil
+}
+
+// StateMachine transitions allowed for supervisor changing state of team member, media type is from member status
+func (s *Supervisor) StateMachine(member *XmppUser) *StateMachine {
+	mediaType := ""
+	if member != nil {
+		mediaType = member.MediaType
+	}
+	return NewStateMachine(mediaType, RoleSupervisor)
+}
+
+// SetAgentState change state of team member to READY, NOT_READY or LOGOUT with optional reason code
+func (s *Supervisor) SetAgentState(loginId string, state string, reason ...ReasonCode) OperationError {
+	return s.SetAgentStateCtx(context.Background(), loginId, state, reason...)
+}
+
+// SetAgentStateCtx change state of team member, context cancel request and wait for notification
+func (s *Supervisor) SetAgentStateCtx(ctx context.Context, loginId string, state string, reason ...ReasonCode) OperationError {
+	member, err := s.agentStatus(ctx, loginId)
+	if err != nil {
+		return OperationError{
+			Type:  TypeErrorNoStatus,
+			Error: err,
+		}
+	}
+	var body userRequest
+	machine := s.StateMachine(member)
+	_, errTransition := machine.Transition(member.State, state)
+	switch {
+	case !machine.Requestable(state):
+		return OperationError{
+			Type:  TypeErrorRequest,
+			Error: fmt.Errorf("supervisor can't change agent [%s] into state [%s]", loginId, state),
+		}
+	case errTransition != nil:
+		return OperationError{
+			Type:  TypeErrorWrongState,
+			Error: fmt.Errorf("supervisor [%s] can't change agent [%s]: %w", s.LoginName, loginId, errTransition),
+		}
+	case len(reason) > 0 && reason[0].Category != state:
+		return OperationError{
+			Type:  TypeErrorRequest,
+			Error: fmt.Errorf("reason code [%s] is in category [%s] and not usable for state [%s]", reason[0].Label, reason[0].Category, state),
+		}
+	case len(reason) > 0:
+		body = &userStateWithReasonRequest{State: state, ReasonCodeId: reason[0].Id}
+	default:
+		body = &userStateRequest{State: state}
+	}
+	request := s.newAgentRequest()
+	requestBody, err := body.getUserRequest()
+	if err != nil {
+		return OperationError{
+			Type:  TypeErrorRequest,
+			Error: err,
+		}
+	}
+	if errOp := s.checkConnected(request.id); errOp.Type != TypeErrorNoError {
+		return errOp
+	}
+	w := s.expectNotify(request.id, request.server.apiPath("User", loginId), userStateMatcher(loginId, state))
+	defer s.dropNotify(w)
+
+	response := request.doRequestCtx(ctx, "PUT", request.server.urlString(request.id, "User", loginId), requestBody)
+	defer response.close()
+	msg, err := response.responseError()
+	if err != nil {
+		s.withFields(Fields{logProc: "SetAgentState", logId: response.id, logAgent: s.LoginName, logNewState: state}).Error(msg)
+		return OperationError{
+			Type:  TypeErrorResponse,
+			Error: err,
+		}
+	}
+	s.withFields(Fields{logProc: "SetAgentState", logId: response.id, logAgent: s.LoginName, logNewState: state}).
+		Tracef("supervisor [%s] change state of agent [%s]", s.LoginName, loginId)
+	_, errOp := s.awaitNotify(ctx, w)
+	return errOp
+}
+
+// SignOutAgent force logout of team member with optional logout reason code
+func (s *Supervisor) SignOutAgent(loginId string, reason ...ReasonCode) OperationError {
+	return s.SetAgentStateCtx(context.Background(), loginId, AgentStateLogout, reason...)
+}
+
+// SignOutAgentCtx force logout of team member, context cancel request and wait for notification
+func (s *Supervisor) SignOutAgentCtx(ctx context.Context, loginId string, reason ...ReasonCode) OperationError {
+	return s.SetAgentStateCtx(ctx, loginId, AgentStateLogout, reason...)
+}
+
+// SignOutTeam force logout of all logged-in team members except supervisor, result is by agent login ID
+func (s *Supervisor) SignOutTeam(teamId string, reason ...ReasonCode) (map[string]OperationError, error) {
+	return s.SignOutTeamCtx(context.Background(), teamId, reason...)
+}
+
+// SignOutTeamCtx force logout of all logged-in team members, after context cancel are not processed remaining agents
+func (s *Supervisor) SignOutTeamCtx(ctx context.Context, teamId string, reason ...ReasonCode) (map[string]OperationError, error) {
+	team, err := s.TeamCtx(ctx, teamId)
+	if err != nil {
+		return nil, err
+	}
+	ret := make(map[string]OperationError)
+	for _, u := range team.Users.User {
+		if u.State == AgentStateLogout || u.LoginId == s.LoginId {
+			continue
+		}
+		if ctx.Err() != nil {
+			ret[u.LoginId] = OperationError{
+				Type:  TypeErrorCanceled,
+				Error: fmt.Errorf("sign out agent [%s] not started: %w", u.LoginId, ctx.Err()),
+			}
+			continue
+		}
+		ret[u.LoginId] = s.SignOutAgentCtx(ctx, u.LoginId, reason...)
+	}
+	return ret, nil
+}
@@ -0,0 +1,35 @@
+package finesse_api_test
+
+import (
+	"errors"
+	"testing"
+
+	api "github.com/pokornyIt/finesse-api"
+)
+
+func TestSupervisorTeam(t *testing.T) {
+	mock := startMock(t)
+	mock.AddSupervisor("super", "1000", "password", "2000")
+	mock.AddAgent("agent1", "1001", "password", "2001")
+	supervisor, err := api.NewSupervisor(mockAgent(t, mock, "super", "2000"))
+	if err != nil {
+		t.Fatalf("create supervisor: %s", err)
+	}
+	if ids := supervisor.TeamIds(); len(ids) != 1 || ids[0] != "5000" {
+		t.Fatalf("team IDs are %v, expected [5000]", ids)
+	}
+
+	team, err := supervisor.Team("5000")
+	if err != nil {
+		t.Fatalf("team: %s", err)
+	}
+	if len(team.Users.User) != 2 {
+		t.Errorf("team has [%d] users, expected logged out users too", len(team.Users.User))
+	}
+
+	// team ID is path segment, query in ID is not passed to server
+	_, err = supervisor.Team("5000?includeLoggedOutAgents=false")
+	if !errors.Is(err, api.ErrUnauthorized) {
+		t.Errorf("team with query in ID: error is [%v], expected [%s]", err, api.ErrUnauthorized)
+	}
+}
@@ -0,0 +1,67 @@
+package finesse_api
+
+import (
+	"encoding/xml"
+)
+
+const (
+	SystemStatusInService    = "IN_SERVICE"     // SystemStatusInService Finesse node is ready for agents
+	SystemStatusOutOfService = "OUT_OF_SERVICE" // SystemStatusOutOfService Finesse node is not usable (failover, maintenance)
+)
+
+// SystemInfo Finesse node system information
+type SystemInfo struct {
+	CurrentTimestamp string `xml:"currentTimestamp"`
+	DeploymentType   string `xml:"deploymentType"`
+	PrimaryNode      string `xml:"primaryNode>host"`
+	SecondaryNode    string `xml:"secondaryNode>host"`
+	Status           string `xml:"status"`
+	TimezoneOffset   string `xml:"timezoneOffset"`
+	XmppDomain       string `xml:"xmppDomain"`
+	PubsubDomain     string `xml:"pubsubDomain"`
+	URI              string `xml:"uri"`
+}
+
+// InService node reports IN_SERVICE status
+func (i *SystemInfo) InService() bool {
+	return i.Status == SystemStatusInService
+}
+
+func newSystemInfo(data string) (*SystemInfo, error) {
+	var i SystemInfo
+	err := xml.Unmarshal([]byte(data), &i)
+	if err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+// newRequest request without agent credentials
+func (s *Server) newRequest() *AgentRequest {
+	return &AgentRequest{
+		id:      randomString(),
+		client:  s.getHttpClient(),
+		server:  s,
+		logging: s.logSettings(),
+	}
+}
+
+// SystemInfo get system information and status of Finesse node, request not require agent
+func (s *Server) SystemInfo() (*SystemInfo, error) {
+	request := s.newRequest()
+	response := request.doRequest("GET", s.urlString(request.id, "SystemInfo"), nil)
+	defer response.close()
+	msg, err := response.responseError()
+	if err != nil {
+		s.withFields(Fields{logProc: "SystemInfo", logId: response.id, logServer: s.name}).Error(msg)
+		return nil, err
+	}
+	info, err := newSystemInfo(response.GetResponseBody())
+	if err != nil {
+		s.withFields(Fields{logProc: "SystemInfo", logId: response.id, logServer: s.name}).Error(err)
+		return nil, err
+	}
+	s.withFields(Fields{logProc: "SystemInfo", logId: response.id, logServer: s.name}).
+		Tracef("server [%s] status [%s]", s.name, info.Status)
+	return info, nil
+}
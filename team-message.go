@@ -0,0 +1,162 @@
+package finesse_api
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"path"
+	"strconv"
+	"time"
+)
+
+const (
+	TeamMessageMaxDuration = 24 * time.Hour // TeamMessageMaxDuration maximal time of displaying team message
+)
+
+// TeamMessage team message (broadcast) converted from Finesse strings
+type TeamMessage struct {
+	Id            string        // Id message ID
+	Content       string        // Content text of message
+	CreatedBy     string        // CreatedBy ID of supervisor
+	CreatedByName string        // CreatedByName first and last name of supervisor
+	CreatedAt     time.Time     // CreatedAt time of creation
+	Duration      time.Duration // Duration how long is message displayed
+	Teams         []string      // Teams IDs of target teams
+}
+
+// teamMessageRequest body for create team message
+type teamMessageRequest struct {
+	XMLName  xml.Name `xml:"TeamMessage"`
+	Duration int      `xml:"duration"`
+	Content  string   `xml:"content"`
+	Teams    struct {
+		Team []string `xml:"team"`
+	} `xml:"teams"`
+}
+
+type xmppTeamMessages struct {
+	TeamMessages []XmppTeamMessage `xml:"TeamMessage"`
+}
+
+// ExpiresAt time when message is removed from agent desktops
+func (m TeamMessage) ExpiresAt() time.Time {
+	return m.CreatedAt.Add(m.Duration)
+}
+
+// Expired message is not displayed at time now
+func (m TeamMessage) Expired(now time.Time) bool {
+	return !m.CreatedAt.IsZero() && !now.Before(m.ExpiresAt())
+}
+
+// teamMessage convert team message, duration is in seconds, creation time is RFC 3339 or epoch milliseconds
+func (x *XmppTeamMessage) teamMessage() TeamMessage {
+	m := TeamMessage{
+		Id:            x.ID,
+		Content:       x.Content,
+		CreatedBy:     x.CreatedBy.ID,
+		CreatedByName: x.CreatedBy.FirstName + " " + x.CreatedBy.LastName,
+		Duration:      time.Duration(atoi(x.Duration)) * time.Second,
+		Teams:         x.Teams.Team,
+	}
+	if len(m.Id) == 0 && len(x.URI) > 0 {
+		m.Id = path.Base(x.URI)
+	}
+	if t, err := time.Parse(time.RFC3339Nano, x.CreatedAt); err == nil {
+		m.CreatedAt = t
+	} else if ms, err := strconv.ParseInt(x.CreatedAt, 10, 64); err == nil {
+		m.CreatedAt = time.UnixMilli(ms)
+	}
+	return m
+}
+
+func newXmppTeamMessages(data string) ([]XmppTeamMessage, error) {
+	var m xmppTeamMessages
+	err := xml.Unmarshal([]byte(data), &m)
+	if err != nil {
+		return nil, err
+	}
+	return m.TeamMessages, nil
+}
+
+// TeamMessages get active team messages for agent teams
+func (a *Agent) TeamMessages() ([]TeamMessage, error) {
+	request := a.newAgentRequest()
+	response := request.doRequest("GET", request.server.urlString(request.id, "User", a.LoginId, "TeamMessages"), nil)
+	defer response.close()
+	msg, err := response.responseError()
+	if err != nil {
+		a.withFields(Fields{logProc: "TeamMessages", logId: response.id, logAgent: a.LoginName}).Error(msg)
+		return nil, err
+	}
+	data, err := newXmppTeamMessages(response.GetResponseBody())
+	if err != nil {
+		a.withFields(Fields{logProc: "TeamMessages", logId: response.id, logAgent: a.LoginName}).Error(err)
+		return nil, err
+	}
+	var ret []TeamMessage
+	for i := range data {
+		ret = append(ret, data[i].teamMessage())
+	}
+	a.withFields(Fields{logProc: "TeamMessages", logId: response.id, logAgent: a.LoginName}).Tracef("collect [%d] team messages", len(ret))
+	return ret, nil
+}
+
+// SendTeamMessage send message to teams, without teams is message sent to all supervised teams
+//
+// Returns ID of created message, if Finesse server returns it.
+func (s *Supervisor) SendTeamMessage(content string, duration time.Duration, teamIds ...string) (string, error) {
+	return s.SendTeamMessageCtx(context.Background(), content, duration, teamIds...)
+}
+
+// SendTeamMessageCtx send message to teams, context cancel request
+func (s *Supervisor) SendTeamMessageCtx(ctx context.Context, content string, duration time.Duration, teamIds ...string) (string, error) {
+	if len(content) == 0 {
+		return "", fmt.Errorf("team message is empty: %w", ErrInvalidInput)
+	}
+	if duration < time.Second || duration > TeamMessageMaxDuration {
+		return "", fmt.Errorf("team message duration [%s] is out of range 1s - %s: %w", duration, TeamMessageMaxDuration, ErrInvalidInput)
+	}
+	if len(teamIds) == 0 {
+		teamIds = s.TeamIds()
+	}
+	if len(teamIds) == 0 {
+		return "", fmt.Errorf("supervisor [%s] has no team for message: %w", s.LoginName, ErrInvalidInput)
+	}
+	body := teamMessageRequest{Duration: int(duration / time.Second), Content: content}
+	body.Teams.Team = teamIds
+	data, err := xml.Marshal(body)
+	if err != nil {
+		return "", err
+	}
+	request := s.newAgentRequest()
+	response := request.doRequestCtx(ctx, "POST", request.server.urlString(request.id, "TeamMessage"), data)
+	defer response.close()
+	msg, err := response.responseError()
+	if err != nil {
+		s.withFields(Fields{logProc: "SendTeamMessage", logId: response.id, logAgent: s.LoginName}).Error(msg)
+		return "", err
+	}
+	id := ""
+	if response.response != nil {
+		if location := response.response.Header.Get("Location"); len(location) > 0 {
+			id = path.Base(location)
+		}
+	}
+	s.withFields(Fields{logProc: "SendTeamMessage", logId: response.id, logAgent: s.LoginName}).
+		Tracef("team message [%s] sent to teams %v", id, teamIds)
+	return id, nil
+}
+
+// DeleteTeamMessage remove team message from agent desktops before expiration
+func (s *Supervisor) DeleteTeamMessage(id string) error {
+	request := s.newAgentRequest()
+	response := request.doRequest("DELETE", request.server.urlString(request.id, "TeamMessage", id), nil)
+	defer response.close()
+	msg, err := response.responseError()
+	if err != nil {
+		s.withFields(Fields{logProc: "DeleteTeamMessage", logId: response.id, logAgent: s.LoginName}).Error(msg)
+		return err
+	}
+	s.withFields(Fields{logProc: "DeleteTeamMessage", logId: response.id, logAgent: s.LoginName}).Tracef("team message [%s] deleted", id)
+	return nil
+}
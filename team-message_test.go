@@ -0,0 +1,82 @@
+package finesse_api_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	api "github.com/pokornyIt/finesse-api"
+)
+
+// nextTeamMessageEvent wait for team message event with operation
+func nextTeamMessageEvent(t *testing.T, sub *api.Subscription, operation string) api.TeamMessageEvent {
+	t.Helper()
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case e := <-sub.C:
+			if me, ok := e.(api.TeamMessageEvent); ok && me.Operation == operation {
+				return me
+			}
+		case <-timeout:
+			t.Fatalf("team message event [%s] not received", operation)
+		}
+	}
+}
+
+func TestTeamMessages(t *testing.T) {
+	mock := startMock(t)
+	mock.AddSupervisor("super", "1000", "password", "2000")
+	mock.AddAgent("agent1", "1001", "password", "2001")
+	supervisor, err := api.NewSupervisor(mockAgent(t, mock, "super", "2000"))
+	if err != nil {
+		t.Fatalf("create supervisor: %s", err)
+	}
+	agent := loginAgent(t, mock, "agent1", "2001")
+	sub := agent.Subscribe(api.EventTypes(api.EventTeamMessage), api.DeliveryLossless)
+	defer sub.Close()
+
+	for _, tc := range []struct {
+		name     string
+		content  string
+		duration time.Duration
+	}{
+		{name: "empty content", content: "", duration: time.Minute},
+		{name: "short duration", content: "Meeting", duration: time.Millisecond},
+		{name: "long duration", content: "Meeting", duration: api.TeamMessageMaxDuration + time.Second},
+	} {
+		if _, err = supervisor.SendTeamMessage(tc.content, tc.duration); !errors.Is(err, api.ErrInvalidInput) {
+			t.Errorf("%s: error is [%v], expected [%s]", tc.name, err, api.ErrInvalidInput)
+		}
+	}
+	if _, err = supervisor.SendTeamMessage("Meeting", time.Minute, "6000"); !errors.Is(err, api.ErrUnauthorized) {
+		t.Errorf("message for other team: error is [%v], expected [%s]", err, api.ErrUnauthorized)
+	}
+
+	id, err := supervisor.SendTeamMessage("Meeting at 10", time.Minute)
+	if err != nil || len(id) == 0 {
+		t.Fatalf("send team message: id [%s] (%v)", id, err)
+	}
+	e := nextTeamMessageEvent(t, sub, "POST")
+	if m := e.TeamMessage; m.Id != id || m.Content != "Meeting at 10" || m.Duration != time.Minute || m.CreatedBy != "1000" {
+		t.Errorf("notified team message is %+v", m)
+	}
+	messages, err := agent.TeamMessages()
+	if err != nil || len(messages) != 1 {
+		t.Fatalf("team messages are %v (%v)", messages, err)
+	}
+	if m := messages[0]; m.Id != id || len(m.Teams) != 1 || m.Teams[0] != "5000" || m.Expired(time.Now()) {
+		t.Errorf("team message is %+v", m)
+	}
+
+	if err = supervisor.DeleteTeamMessage(id); err != nil {
+		t.Fatalf("delete team message: %s", err)
+	}
+	nextTeamMessageEvent(t, sub, "DELETE")
+	if messages, err = agent.TeamMessages(); err != nil || len(messages) != 0 {
+		t.Errorf("team messages after delete are %v (%v)", messages, err)
+	}
+	if err = supervisor.DeleteTeamMessage(id); !errors.Is(err, api.ErrNotFound) {
+		t.Errorf("delete of deleted message: error is [%v], expected [%s]", err, api.ErrNotFound)
+	}
+}
@@ -0,0 +1,68 @@
+package finesse_api
+
+import (
+	"context"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"sync/atomic"
+)
+
+const (
+	TracerName = "github.com/pokornyIt/finesse-api" // TracerName instrumentation name of library spans
+)
+
+// span attributes
+const (
+	attrAgent     = attribute.Key("finesse.agent")      // attrAgent agent login name
+	attrServer    = attribute.Key("finesse.server")     // attrServer Finesse server name
+	attrRequestId = attribute.Key("finesse.request_id") // attrRequestId request correlation ID sent as RequestId header
+	attrState     = attribute.Key("finesse.state")      // attrState requested agent state
+	attrErrorType = attribute.Key("finesse.error_type") // attrErrorType OperationError.Type
+	attrOperation = attribute.Key("finesse.operation")  // attrOperation AgentGroup operation
+	attrAgents    = attribute.Key("finesse.agents")     // attrAgents number of agents in group operation
+	attrFailed    = attribute.Key("finesse.failed")     // attrFailed number of failed agents in group operation
+)
+
+// tracerProviderHolder allows store interface in atomic.Value
+type tracerProviderHolder struct {
+	provider trace.TracerProvider
+}
+
+var tracerProvider atomic.Value
+
+// SetTracerProvider set OpenTelemetry provider for library spans, nil uses global provider (otel.SetTracerProvider)
+//
+// Trace context of caller is taken from context of ...Ctx operations and propagated to Finesse REST requests
+// by global propagator (otel.SetTextMapPropagator).
+func SetTracerProvider(provider trace.TracerProvider) {
+	tracerProvider.Store(tracerProviderHolder{provider: provider})
+}
+
+// tracer library tracer from configured or global provider
+func tracer() trace.Tracer {
+	if h, ok := tracerProvider.Load().(tracerProviderHolder); ok && h.provider != nil {
+		return h.provider.Tracer(TracerName)
+	}
+	return otel.GetTracerProvider().Tracer(TracerName)
+}
+
+// startSpan start library span as child of span in context
+func startSpan(ctx context.Context, name string, kind trace.SpanKind, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer().Start(ctx, name, trace.WithSpanKind(kind), trace.WithAttributes(attrs...))
+}
+
+// endSpan record operation error and end span
+func endSpan(span trace.Span, errOp OperationError) {
+	if errOp.Type != TypeErrorNoError {
+		span.SetAttributes(attrErrorType.Int(errOp.Type))
+		if errOp.Error != nil {
+			span.RecordError(errOp.Error)
+			span.SetStatus(codes.Error, errOp.Error.Error())
+		} else {
+			span.SetStatus(codes.Error, "")
+		}
+	}
+	span.End()
+}
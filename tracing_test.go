@@ -0,0 +1,125 @@
+package finesse_api_test
+
+import (
+	"context"
+	"testing"
+
+	api "github.com/pokornyIt/finesse-api"
+	"github.com/pokornyIt/finesse-api/finessetest"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// agentSpan span with name for agent
+func agentSpan(t *testing.T, spans tracetest.SpanStubs, name string, agent string) tracetest.SpanStub {
+	t.Helper()
+	for _, s := range spans {
+		if s.Name == name && attributeValue(s, "finesse.agent") == agent {
+			return s
+		}
+	}
+	t.Fatalf("span [%s] for agent [%s] not found", name, agent)
+	return tracetest.SpanStub{}
+}
+
+// attributeValue string value of span attribute, empty if attribute is not set
+func attributeValue(s tracetest.SpanStub, key string) string {
+	for _, a := range s.Attributes {
+		if a.Key == attribute.Key(key) {
+			return a.Value.Emit()
+		}
+	}
+	return ""
+}
+
+// checkParent span is child of parent span
+func checkParent(t *testing.T, child tracetest.SpanStub, parent tracetest.SpanStub) {
+	t.Helper()
+	if child.Parent.SpanID() != parent.SpanContext.SpanID() {
+		t.Errorf("span [%s] is not child of span [%s]", child.Name, parent.Name)
+	}
+	if child.SpanContext.TraceID() != parent.SpanContext.TraceID() {
+		t.Errorf("span [%s] is not in trace of span [%s]", child.Name, parent.Name)
+	}
+}
+
+func TestTracingGroupLogin(t *testing.T) {
+	mock, err := finessetest.NewServer()
+	if err != nil {
+		t.Fatalf("start mock server: %s", err)
+	}
+	defer mock.Close()
+	mock.AddAgent("agent1", "1001", "password", "2001")
+	mock.AddAgent("agent2", "1002", "password", "2002")
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	api.SetTracerProvider(provider)
+	defer api.SetTracerProvider(nil)
+
+	server := mock.Finesse(true)
+	server.SetLogger(api.NewNopLogger())
+	group := api.NewAgentGroup()
+	defer group.CancelFunction()
+	result := group.AddBulkAgents([]api.BulkAgent{
+		{Name: "agent1", Password: "password", Line: "2001"},
+		{Name: "agent2", Password: "password", Line: "9999"}, // invalid device
+	}, server)
+	if failed := result.Failed(); len(failed) > 0 {
+		t.Fatalf("add agents failed %v", failed)
+	}
+	add := exporter.GetSpans()
+	addGroup := agentSpan(t, add, "group "+api.GroupOperationAdd, "")
+	checkParent(t, agentSpan(t, add, "group agent "+api.GroupOperationAdd, "agent1"), addGroup)
+	exporter.Reset()
+
+	ctx, root := provider.Tracer("test").Start(context.Background(), "test")
+	result = group.LoginCtx(ctx)
+	root.End()
+	if r := result["agent1"]; r.Error.Type != api.TypeErrorNoError {
+		t.Fatalf("login agent1: %s", r.Error.Error)
+	}
+	if r := result["agent2"]; r.Error.Type != api.TypeErrorAnalyzeResponse {
+		t.Fatalf("login agent2: error type is [%d], expected [%d]", r.Error.Type, api.TypeErrorAnalyzeResponse)
+	}
+
+	spans := exporter.GetSpans()
+	groupSpan := agentSpan(t, spans, "group "+api.AgentStateLogin, "")
+	checkParent(t, groupSpan, agentSpan(t, spans, "test", ""))
+	if failed := attributeValue(groupSpan, "finesse.failed"); failed != "1" {
+		t.Errorf("group span failed is [%s], expected [1]", failed)
+	}
+	for _, agent := range []string{"agent1", "agent2"} {
+		agentOperation := agentSpan(t, spans, "group agent "+api.AgentStateLogin, agent)
+		stateChange := agentSpan(t, spans, "state change", agent)
+		rest := agentSpan(t, spans, "PUT User/{id}", agent)
+		notify := agentSpan(t, spans, "await notify", agent)
+		checkParent(t, agentOperation, groupSpan)
+		checkParent(t, stateChange, agentOperation)
+		checkParent(t, rest, stateChange)
+		checkParent(t, notify, stateChange)
+
+		if server := attributeValue(stateChange, "finesse.server"); server != mock.Host {
+			t.Errorf("%s: state change server is [%s], expected [%s]", agent, server, mock.Host)
+		}
+		if state := attributeValue(stateChange, "finesse.state"); state != api.AgentStateLogin {
+			t.Errorf("%s: state change state is [%s], expected [%s]", agent, state, api.AgentStateLogin)
+		}
+		if server := attributeValue(rest, "finesse.server"); server != mock.Host {
+			t.Errorf("%s: REST span server is [%s], expected [%s]", agent, server, mock.Host)
+		}
+	}
+
+	stateChange := agentSpan(t, spans, "state change", "agent2")
+	if errorType := attributeValue(stateChange, "finesse.error_type"); errorType != "5" {
+		t.Errorf("agent2: state change error_type is [%s], expected [5]", errorType)
+	}
+	if stateChange.Status.Code != codes.Error {
+		t.Errorf("agent2: state change status is [%s], expected [%s]", stateChange.Status.Code, codes.Error)
+	}
+	if errorType := attributeValue(agentSpan(t, spans, "state change", "agent1"), "finesse.error_type"); errorType != "" {
+		t.Errorf("agent1: state change has error_type [%s]", errorType)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"math/rand"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -16,12 +17,15 @@ const (
 
 var (
 	src           = rand.NewSource(time.Now().UnixNano()) // randomize base string
+	srcMutex      sync.Mutex                              // src is not safe for concurrent agents
 	maxRandomSize = 10                                    // required size of random string
 )
 
 func randomString() string {
 	sb := strings.Builder{}
 	sb.Grow(maxRandomSize)
+	srcMutex.Lock()
+	defer srcMutex.Unlock()
 	// A src.Int63() generates 63 random bits, enough for letterIdxMax characters!
 	for i, cache, remain := maxRandomSize-1, src.Int63(), letterIdxMax; i >= 0; {
 		if remain == 0 {
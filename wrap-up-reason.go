@@ -0,0 +1,82 @@
+package finesse_api
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// WrapUpReason one wrap-up reason defined on Finesse server
+type WrapUpReason struct {
+	Id     int    `xml:"id"`
+	Label  string `xml:"label"`
+	ForAll bool   `xml:"forAll"`
+	URI    string `xml:"uri"`
+}
+
+// WrapUpReasons list of wrap-up reasons
+type WrapUpReasons []WrapUpReason
+
+type xmppWrapUpReasons struct {
+	WrapUpReasons WrapUpReasons `xml:"WrapUpReason"`
+}
+
+// ByLabel find wrap-up reason by label, case-insensitive
+func (w WrapUpReasons) ByLabel(label string) (WrapUpReason, bool) {
+	for _, r := range w {
+		if strings.EqualFold(r.Label, label) {
+			return r, true
+		}
+	}
+	return WrapUpReason{}, false
+}
+
+// Labels all labels in list
+func (w WrapUpReasons) Labels() []string {
+	var ret []string
+	for _, r := range w {
+		ret = append(ret, r.Label)
+	}
+	return ret
+}
+
+func newWrapUpReasons(data string) (WrapUpReasons, error) {
+	var w xmppWrapUpReasons
+	err := xml.Unmarshal([]byte(data), &w)
+	if err != nil {
+		return nil, err
+	}
+	return w.WrapUpReasons, nil
+}
+
+// WrapUpReasons get wrap-up reasons configured for agent
+func (s *Server) WrapUpReasons(a *Agent) (WrapUpReasons, error) {
+	request := a.newAgentRequest()
+	response := request.doRequest("GET", request.server.urlString(request.id, "User", a.LoginId, "WrapUpReasons"), nil)
+	defer response.close()
+	msg, err := response.responseError()
+	if err != nil {
+		s.withFields(Fields{logProc: "WrapUpReasons", logId: response.id, logAgent: a.LoginName}).Error(msg)
+		return nil, err
+	}
+	reasons, err := newWrapUpReasons(response.GetResponseBody())
+	if err != nil {
+		s.withFields(Fields{logProc: "WrapUpReasons", logId: response.id, logAgent: a.LoginName}).Error(err)
+		return nil, err
+	}
+	s.withFields(Fields{logProc: "WrapUpReasons", logId: response.id, logAgent: a.LoginName}).
+		Tracef("collect [%d] wrap-up reasons", len(reasons))
+	return reasons, nil
+}
+
+// WrapUpReason resolve wrap-up reason configured for agent by label
+func (s *Server) WrapUpReason(a *Agent, label string) (WrapUpReason, error) {
+	reasons, err := s.WrapUpReasons(a)
+	if err != nil {
+		return WrapUpReason{}, err
+	}
+	if r, ok := reasons.ByLabel(label); ok {
+		return r, nil
+	}
+	return WrapUpReason{}, fmt.Errorf("wrap-up reason [%s] not exists for agent [%s]", label, a.LoginName)
+}
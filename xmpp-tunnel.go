@@ -0,0 +1,68 @@
+package finesse_api
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"strconv"
+)
+
+// wssTunnel loopback proxy of XMPP over WSS connection with TLS configuration of server
+//
+// gosrc.io/xmpp dials WSS by http.DefaultClient without own TLS configuration. XMPP client connects to plain ws://
+// address of tunnel and tunnel forwards WebSocket upgrade to Finesse with Server.TLSConfig (root CA, client
+// certificates, pinned keys) and proxy of server.
+type wssTunnel struct {
+	listener net.Listener
+	server   *http.Server
+}
+
+// newWssTunnel start tunnel to XMPP over WSS endpoint of server
+func (s *Server) newWssTunnel() (*wssTunnel, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	target := net.JoinHostPort(s.name, strconv.Itoa(s.xmppPort))
+	proxy := s.proxy
+	if proxy == nil {
+		proxy = http.ProxyFromEnvironment
+	}
+	reverseProxy := &httputil.ReverseProxy{
+		Director: func(r *http.Request) {
+			r.URL.Scheme = "https"
+			r.URL.Host = target
+			r.Host = target
+			r.Header["X-Forwarded-For"] = nil
+		},
+		Transport: &http.Transport{
+			Proxy:                 proxy,
+			TLSClientConfig:       s.TLSConfig(),
+			TLSHandshakeTimeout:   s.xmppTimeout,
+			ResponseHeaderTimeout: s.xmppTimeout,
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			s.withFields(Fields{logProc: "wssTunnel", logServer: s.name}).Warnf("XMPP over WSS connection to [%s] fails %s", target, err)
+			w.WriteHeader(http.StatusBadGateway)
+		},
+	}
+	t := &wssTunnel{
+		listener: listener,
+		server:   &http.Server{Handler: reverseProxy, ReadHeaderTimeout: s.xmppTimeout},
+	}
+	go func() {
+		_ = t.server.Serve(listener)
+	}()
+	return t, nil
+}
+
+// address ws:// address used by XMPP client instead of Finesse WSS endpoint
+func (t *wssTunnel) address() string {
+	return fmt.Sprintf("ws://%s/ws/", t.listener.Addr())
+}
+
+// close stop accept new connections, already upgraded connection stays open until XMPP client or Finesse close it
+func (t *wssTunnel) close() {
+	_ = t.server.Close()
+}